@@ -0,0 +1,192 @@
+use genco::prelude::*;
+use wit_bindgen_core::wit_parser::{Resolve, World, WorldItem};
+
+use crate::{
+    codegen::ir::AnalyzedImports,
+    go::{
+        GoIdentifier, GoType, Naming, OptionStyle, Renames, comment,
+        imports::{CONTEXT_BACKGROUND, TESTING_B},
+    },
+};
+
+/// Configuration for benchmark generation.
+pub struct BenchmarkConfig<'a> {
+    pub analyzed_imports: &'a AnalyzedImports,
+    pub world: &'a World,
+    pub resolve: &'a Resolve,
+    pub option_style: OptionStyle,
+    /// The naming strategy for type and function names.
+    pub naming: &'a Naming,
+}
+
+/// Generator for a benchmark of every function exported by a world.
+///
+/// The benchmarks are meant to be written to a `_test.go` file next to the
+/// bindings, and drive the generated methods with representative inputs,
+/// reusing one instance across iterations.
+pub struct BenchmarkGenerator<'a> {
+    config: BenchmarkConfig<'a>,
+    renames: Renames,
+}
+
+impl<'a> BenchmarkGenerator<'a> {
+    /// Create a new benchmark generator with the given config.
+    pub fn new(config: BenchmarkConfig<'a>) -> Self {
+        Self {
+            config,
+            renames: Renames::default(),
+        }
+    }
+
+    /// Set the Go identifiers overriding the generated ones.
+    pub fn with_renames(mut self, renames: Renames) -> Self {
+        self.renames = renames;
+        self
+    }
+
+    /// Generate the helper instantiating the module once for a benchmark.
+    ///
+    /// Host implementations of the imported interfaces can't be generated, so
+    /// worlds with imports must provide a `newBenchmarkFactory` function in
+    /// another test file, creating the factory with their implementations.
+    fn generate_instance_helper(&self, tokens: &mut Tokens<Go>) {
+        let AnalyzedImports {
+            instance_name,
+            constructor_name,
+            interfaces,
+            ..
+        } = self.config.analyzed_imports;
+        let new_factory = if interfaces.is_empty() {
+            quote!($constructor_name(b.Context()))
+        } else {
+            quote!(newBenchmarkFactory(b.Context()))
+        };
+        quote_in! { *tokens =>
+            $(comment(&[
+                "newBenchmarkInstance instantiates the module once for a benchmark, so every",
+                "iteration reuses the same instance.",
+            ]))
+            func newBenchmarkInstance(b *$TESTING_B) *$instance_name {
+                b.Helper()
+                fac, err := $new_factory
+                if err != nil {
+                    b.Fatal(err)
+                }
+                b.Cleanup(func() { fac.Close($CONTEXT_BACKGROUND()) })
+
+                ins, err := fac.Instantiate(b.Context())
+                if err != nil {
+                    b.Fatal(err)
+                }
+                b.Cleanup(func() { ins.Close($CONTEXT_BACKGROUND()) })
+                return ins
+            }
+        };
+    }
+}
+
+/// Returns a representative Go value of the given type, if there is a
+/// sensible one. Other types are benchmarked with their zero value.
+pub(crate) fn representative(typ: &GoType) -> Option<Tokens<Go>> {
+    match typ {
+        GoType::Bool => Some(quote!(true)),
+        GoType::Uint8
+        | GoType::Uint16
+        | GoType::Uint32
+        | GoType::Uint64
+        | GoType::Int8
+        | GoType::Int16
+        | GoType::Int32
+        | GoType::Int64 => Some(quote!($typ(42))),
+        GoType::Float32 | GoType::Float64 => Some(quote!($typ(4.2))),
+        GoType::String => Some(quote!("gravity")),
+        GoType::Slice(inner) => Some(match representative(inner) {
+            Some(value) => quote!([]$(inner.as_ref()){$value}),
+            None => quote!(make([]$(inner.as_ref()), 1)),
+        }),
+        GoType::Defined(_, underlying) => {
+            representative(underlying).map(|value| quote!($typ($value)))
+        }
+        _ => None,
+    }
+}
+
+impl FormatInto<Go> for BenchmarkGenerator<'_> {
+    fn format_into(self, tokens: &mut Tokens<Go>) {
+        self.generate_instance_helper(tokens);
+        for item in self.config.world.exports.values() {
+            let WorldItem::Function(func) = item else {
+                continue;
+            };
+            let params = func
+                .params
+                .iter()
+                .flat_map(|(name, wit_type)| {
+                    let typ = crate::resolve_type_with(
+                        wit_type,
+                        self.config.resolve,
+                        false,
+                        self.config.naming,
+                    );
+                    self.config.option_style.go_type(typ).params(name)
+                })
+                .collect::<Vec<_>>();
+            let fn_name = &self.renames.export(self.config.naming, func);
+            let bench_name = &GoIdentifier::public(format!("Benchmark{}", String::from(fn_name)));
+            quote_in! { *tokens =>
+                $['\n']
+                func $bench_name(b *$TESTING_B) {
+                    ins := newBenchmarkInstance(b)
+                    $(for (name, typ) in &params join ($['\r']) =>
+                        $(match representative(typ) {
+                            Some(value) => { $name := $value }
+                            None => { var $name $typ }
+                        })
+                    )
+                    $['\n']
+                    for b.Loop() {
+                        ins.$fn_name(
+                            $['\r']
+                            b.Context(),
+                            $(for (name, _) in &params join ($['\r']) => $name,)
+                        )
+                    }
+                }
+            };
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::go::GoType;
+
+    use super::representative;
+
+    #[test]
+    fn test_representative() {
+        let cases = [
+            (GoType::Bool, Some("true")),
+            (GoType::Uint32, Some("uint32(42)")),
+            (GoType::Float64, Some("float64(4.2)")),
+            (GoType::String, Some("\"gravity\"")),
+            (
+                GoType::Slice(Box::new(GoType::String)),
+                Some("[]string{\"gravity\"}"),
+            ),
+            (
+                GoType::Slice(Box::new(GoType::UserDefined("entry".to_string()))),
+                Some("make([]Entry, 1)"),
+            ),
+            (
+                GoType::Defined("user-id".to_string(), Box::new(GoType::Uint64)),
+                Some("UserId(uint64(42))"),
+            ),
+            (GoType::UserDefined("entry".to_string()), None),
+        ];
+        for (typ, expected) in cases {
+            let actual = representative(&typ).map(|value| value.to_string().unwrap());
+            assert_eq!(actual.as_deref(), expected, "{typ:?}");
+        }
+    }
+}
@@ -0,0 +1,406 @@
+use genco::prelude::*;
+use wit_bindgen_core::wit_parser::{FlagsRepr, Resolve, SizeAlign, Type, TypeDefKind, TypeId};
+
+use crate::{
+    field_array_len,
+    go::{
+        GoIdentifier, Naming, Renames, comment,
+        imports::{
+            BINARY_LITTLE_ENDIAN, ERRORS_NEW, MATH_FLOAT32_BITS, MATH_FLOAT32_FROM_BITS,
+            MATH_FLOAT64_BITS, MATH_FLOAT64_FROM_BITS,
+        },
+    },
+    resolve_type_with,
+};
+
+/// Generator of the `MarshalBinary` and `UnmarshalBinary` methods of a
+/// record, which encode it in its Canonical ABI layout.
+///
+/// The record is laid out as the guest lays it out in memory at offset 0,
+/// followed by the contents of its strings and lists, which it points to by
+/// their offset in the encoded bytes. Only records of numbers, `bool`,
+/// `string`, flags, lists and other such records are supported.
+pub struct BinaryGenerator<'a> {
+    resolve: &'a Resolve,
+    sizes: &'a SizeAlign,
+    naming: &'a Naming,
+    renames: &'a Renames,
+    tmp: usize,
+}
+
+impl<'a> BinaryGenerator<'a> {
+    /// Create a new binary generator.
+    pub fn new(
+        resolve: &'a Resolve,
+        sizes: &'a SizeAlign,
+        naming: &'a Naming,
+        renames: &'a Renames,
+    ) -> Self {
+        Self {
+            resolve,
+            sizes,
+            naming,
+            renames,
+            tmp: 0,
+        }
+    }
+
+    fn tmp(&mut self) -> usize {
+        let ret = self.tmp;
+        self.tmp += 1;
+        ret
+    }
+
+    /// Returns whether values of the given type can be encoded.
+    pub fn supported(&self, typ: &Type) -> bool {
+        let Type::Id(id) = typ else {
+            return !matches!(typ, Type::Char | Type::ErrorContext);
+        };
+        match &self.resolve.types[*id].kind {
+            TypeDefKind::Record(record) => record.fields.iter().all(|field| {
+                field_array_len(field, self.resolve).is_none() && self.supported(&field.ty)
+            }),
+            TypeDefKind::List(elem) | TypeDefKind::Type(elem) => self.supported(elem),
+            TypeDefKind::Flags(flags) => !matches!(flags.repr(), FlagsRepr::U32(n) if n > 2),
+            _ => false,
+        }
+    }
+
+    /// Generates the methods of the given record, unless it has fields that
+    /// can't be encoded.
+    pub fn generate(&mut self, id: TypeId, tokens: &mut Tokens<Go>) {
+        let typ = Type::Id(id);
+        if !self.supported(&typ) {
+            return;
+        }
+        let TypeDefKind::Record(record) = &self.resolve.types[id].kind else {
+            panic!("expected a record");
+        };
+        let wit_name = self.resolve.types[id]
+            .name
+            .as_ref()
+            .expect("expected record to have a name");
+        let name = &GoIdentifier::public(self.naming.name(wit_name));
+        let size = self.sizes.size(&typ).size_wasm32();
+        let offsets = self
+            .sizes
+            .field_offsets(record.fields.iter().map(|field| &field.ty));
+
+        let mut encode = Tokens::<Go>::new();
+        let mut decode = Tokens::<Go>::new();
+        for (field, (offset, ty)) in record.fields.iter().zip(offsets) {
+            let field_name = self
+                .renames
+                .field(self.naming, self.resolve, id, &field.name);
+            let pos = quote!(offset+$(offset.size_wasm32()));
+            let field_encode = self.encode(ty, quote!(r.$(&field_name)), pos.clone());
+            let field_decode = self.decode(wit_name, ty, quote!(r.$(&field_name)), pos);
+            quote_in! { encode =>
+                $['\r']
+                $field_encode
+            };
+            quote_in! { decode =>
+                $['\r']
+                $field_decode
+            };
+        }
+
+        let message = format!("{wit_name}: record out of bounds");
+        quote_in! { *tokens =>
+            $['\n']
+            $(comment(&[
+                "MarshalBinary encodes r in its Canonical ABI layout, as the guest lays it",
+                "out in memory at offset 0, followed by the contents of its strings and",
+                "lists, which it points to by their offset.",
+            ]))
+            func (r $name) MarshalBinary() ([]byte, error) {
+                return r.encodeBinary(make([]byte, $size), 0), nil
+            }
+            $['\n']
+            $(comment(&["UnmarshalBinary decodes r from the layout MarshalBinary encodes it in."]))
+            func (r *$name) UnmarshalBinary(data []byte) error {
+                if len(data) < $size {
+                    return $ERRORS_NEW($(quoted(message)))
+                }
+                return r.decodeBinary(data, 0)
+            }
+            $['\n']
+            $(comment(&[
+                "encodeBinary writes r at offset of buf, which must already be allocated,",
+                "and appends the contents of its strings and lists.",
+            ]))
+            func (r $name) encodeBinary(buf []byte, offset int) []byte {
+                $encode
+                return buf
+            }
+            $['\n']
+            $(comment(&["decodeBinary reads r from offset of data."]))
+            func (r *$name) decodeBinary(data []byte, offset int) error {
+                $decode
+                return nil
+            }
+        }
+    }
+
+    /// Returns the statements writing `value` of the given type at `pos` of
+    /// `buf`.
+    fn encode(&mut self, typ: &Type, value: Tokens<Go>, pos: Tokens<Go>) -> Tokens<Go> {
+        match typ {
+            Type::Bool => quote! {
+                if $value {
+                    buf[$pos] = 1
+                }
+            },
+            Type::U8 | Type::S8 => quote!(buf[$pos] = byte($value)),
+            Type::U16 | Type::S16 => {
+                quote!($BINARY_LITTLE_ENDIAN.PutUint16(buf[$pos:], uint16($value)))
+            }
+            Type::U32 | Type::S32 => {
+                quote!($BINARY_LITTLE_ENDIAN.PutUint32(buf[$pos:], uint32($value)))
+            }
+            Type::U64 | Type::S64 => {
+                quote!($BINARY_LITTLE_ENDIAN.PutUint64(buf[$pos:], uint64($value)))
+            }
+            Type::F32 => quote! {
+                $BINARY_LITTLE_ENDIAN.PutUint32(buf[$pos:], $MATH_FLOAT32_BITS(float32($value)))
+            },
+            Type::F64 => quote! {
+                $BINARY_LITTLE_ENDIAN.PutUint64(buf[$pos:], $MATH_FLOAT64_BITS(float64($value)))
+            },
+            Type::String => {
+                let ptr = &format!("ptr{}", self.tmp());
+                quote! {
+                    $ptr := len(buf)
+                    buf = append(buf, $(&value)...)
+                    $BINARY_LITTLE_ENDIAN.PutUint32(buf[$(&pos):], uint32($ptr))
+                    $BINARY_LITTLE_ENDIAN.PutUint32(buf[$pos+4:], uint32(len($value)))
+                }
+            }
+            Type::Char | Type::ErrorContext => unreachable!("unsupported type {typ:?}"),
+            Type::Id(id) => match &self.resolve.types[*id].kind {
+                TypeDefKind::Type(inner) => self.encode(inner, value, pos),
+                TypeDefKind::Record(_) => quote!(buf = $value.encodeBinary(buf, $pos)),
+                TypeDefKind::Flags(flags) => match flags.repr() {
+                    FlagsRepr::U8 => self.encode(&Type::U8, value, pos),
+                    FlagsRepr::U16 => self.encode(&Type::U16, value, pos),
+                    FlagsRepr::U32(1) => self.encode(&Type::U32, value, pos),
+                    // The first 32 flags are stored first, like in a `uint64`
+                    _ => self.encode(&Type::U64, value, pos),
+                },
+                TypeDefKind::List(elem) => {
+                    let tmp = self.tmp();
+                    let start = &format!("start{tmp}");
+                    let index = &format!("i{tmp}");
+                    let elem_value = &format!("e{tmp}");
+                    let size = self.sizes.size(elem).size_wasm32();
+                    let align = self.sizes.align(elem).align_wasm32();
+                    let elem_encode =
+                        self.encode(elem, quote!($elem_value), quote!($start+$index*$size));
+                    quote! {
+                        $(if align > 1 {
+                            $start := (len(buf) + $(align - 1)) &^ $(align - 1)
+                        } else {
+                            $start := len(buf)
+                        })
+                        buf = append(buf, make([]byte, $start-len(buf)+len($(&value))*$size)...)
+                        for $index, $elem_value := range $(&value) {
+                            $elem_encode
+                        }
+                        $BINARY_LITTLE_ENDIAN.PutUint32(buf[$(&pos):], uint32($start))
+                        $BINARY_LITTLE_ENDIAN.PutUint32(buf[$pos+4:], uint32(len($value)))
+                    }
+                }
+                kind => unreachable!("unsupported type {kind:?}"),
+            },
+        }
+    }
+
+    /// Returns the statements reading `target` of the given type from `pos`
+    /// of `data`.
+    fn decode(
+        &mut self,
+        record: &str,
+        typ: &Type,
+        target: Tokens<Go>,
+        pos: Tokens<Go>,
+    ) -> Tokens<Go> {
+        let go_type = &resolve_type_with(typ, self.resolve, false, self.naming);
+        let layout = self.layout(typ);
+        match &layout {
+            Type::Bool => quote!($target = data[$pos] != 0),
+            Type::U8 | Type::S8 => quote!($target = $go_type(data[$pos])),
+            Type::U16 | Type::S16 => {
+                quote!($target = $go_type($BINARY_LITTLE_ENDIAN.Uint16(data[$pos:])))
+            }
+            Type::U32 | Type::S32 => {
+                quote!($target = $go_type($BINARY_LITTLE_ENDIAN.Uint32(data[$pos:])))
+            }
+            Type::U64 | Type::S64 => {
+                quote!($target = $go_type($BINARY_LITTLE_ENDIAN.Uint64(data[$pos:])))
+            }
+            Type::F32 => quote! {
+                $target = $go_type($MATH_FLOAT32_FROM_BITS($BINARY_LITTLE_ENDIAN.Uint32(data[$pos:])))
+            },
+            Type::F64 => quote! {
+                $target = $go_type($MATH_FLOAT64_FROM_BITS($BINARY_LITTLE_ENDIAN.Uint64(data[$pos:])))
+            },
+            Type::String => {
+                let tmp = self.tmp();
+                let ptr = &format!("ptr{tmp}");
+                let len = &format!("len{tmp}");
+                let message = format!("{record}: string out of bounds");
+                quote! {
+                    $ptr, $len := int($BINARY_LITTLE_ENDIAN.Uint32(data[$(&pos):])), int($BINARY_LITTLE_ENDIAN.Uint32(data[$pos+4:]))
+                    if $ptr+$len > len(data) {
+                        return $ERRORS_NEW($(quoted(message)))
+                    }
+                    $target = $go_type(data[$ptr:$ptr+$len])
+                }
+            }
+            Type::Id(id) => match &self.resolve.types[*id].kind {
+                TypeDefKind::Record(_) => quote! {
+                    if err := $target.decodeBinary(data, $pos); err != nil {
+                        return err
+                    }
+                },
+                TypeDefKind::List(elem) => {
+                    let tmp = self.tmp();
+                    let ptr = &format!("ptr{tmp}");
+                    let len = &format!("len{tmp}");
+                    let index = &format!("i{tmp}");
+                    let size = self.sizes.size(elem).size_wasm32();
+                    let message = format!("{record}: list out of bounds");
+                    let elem_decode = self.decode(
+                        record,
+                        elem,
+                        quote!($(&target)[$index]),
+                        quote!($ptr+$index*$size),
+                    );
+                    quote! {
+                        $ptr, $len := int($BINARY_LITTLE_ENDIAN.Uint32(data[$(&pos):])), int($BINARY_LITTLE_ENDIAN.Uint32(data[$pos+4:]))
+                        if $ptr+$len*$size > len(data) {
+                            return $ERRORS_NEW($(quoted(message)))
+                        }
+                        $(&target) = make($go_type, $len)
+                        for $index := range $target {
+                            $elem_decode
+                        }
+                    }
+                }
+                kind => unreachable!("unsupported type {kind:?}"),
+            },
+            Type::Char | Type::ErrorContext => unreachable!("unsupported type {typ:?}"),
+        }
+    }
+
+    /// Resolves aliases and flags to the type they are laid out as, keeping
+    /// records and lists.
+    fn layout(&self, typ: &Type) -> Type {
+        let Type::Id(id) = typ else {
+            return *typ;
+        };
+        match &self.resolve.types[*id].kind {
+            TypeDefKind::Type(inner) => self.layout(inner),
+            TypeDefKind::Flags(flags) => match flags.repr() {
+                FlagsRepr::U8 => Type::U8,
+                FlagsRepr::U16 => Type::U16,
+                FlagsRepr::U32(1) => Type::U32,
+                _ => Type::U64,
+            },
+            _ => *typ,
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use genco::prelude::*;
+    use wit_bindgen_core::wit_parser::{Resolve, SizeAlign};
+
+    use super::BinaryGenerator;
+    use crate::go::{Naming, Renames};
+
+    fn generate(source: &str, record: &str) -> String {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str("binary.wit", source)
+            .expect("failed to parse WIT");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let (id, _) = resolve
+            .types
+            .iter()
+            .find(|(_, typ)| typ.name.as_deref() == Some(record))
+            .expect("failed to find record");
+        let naming = Naming::default();
+        let renames = Renames::default();
+        let mut tokens = Tokens::new();
+        BinaryGenerator::new(&resolve, &sizes, &naming, &renames).generate(id, &mut tokens);
+        tokens.to_string().unwrap()
+    }
+
+    #[test]
+    fn test_generate_binary() {
+        let generated = generate(
+            r#"
+            package arcjet:binary;
+
+            world binary {
+              record point {
+                x: s32,
+                y: f64,
+              }
+
+              record path {
+                name: string,
+                start: point,
+                points: list<point>,
+                visible: bool,
+              }
+            }
+            "#,
+            "path",
+        );
+        println!("Generated: {}", generated);
+
+        // The record is laid out at offset 0, with its strings and lists after it
+        assert!(generated.contains("func (r Path) MarshalBinary() ([]byte, error) {"));
+        assert!(generated.contains("return r.encodeBinary(make([]byte, 40), 0), nil"));
+        assert!(generated.contains("func (r *Path) UnmarshalBinary(data []byte) error {"));
+        assert!(generated.contains("if len(data) < 40 {"));
+        assert!(generated.contains("buf = append(buf, r.Name...)"));
+        assert!(generated.contains("buf = r.Start.encodeBinary(buf, offset+8)"));
+        assert!(generated.contains("start2 := (len(buf) + 7) &^ 7"));
+        assert!(generated.contains("buf = e2.encodeBinary(buf, start2+i2*16)"));
+        assert!(generated.contains("buf[offset+32] = 1"));
+
+        // Pointers are checked to be within the data
+        assert!(generated.contains("if ptr3+len3*16 > len(data) {"));
+        assert!(generated.contains("return errors.New(\"path: list out of bounds\")"));
+        assert!(generated.contains("r.Points = make([]Point, len3)"));
+        assert!(
+            generated
+                .contains("if err := r.Points[i3].decodeBinary(data, ptr3+i3*16); err != nil {")
+        );
+        assert!(generated.contains("r.Visible = data[offset+32] != 0"));
+    }
+
+    #[test]
+    fn test_generate_binary_unsupported() {
+        let generated = generate(
+            r#"
+            package arcjet:binary;
+
+            world binary {
+              record profile {
+                name: string,
+                age: option<u32>,
+              }
+            }
+            "#,
+            "profile",
+        );
+
+        assert!(generated.is_empty());
+    }
+}
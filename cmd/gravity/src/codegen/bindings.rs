@@ -5,14 +5,22 @@ use wit_bindgen_core::wit_parser::{Resolve, SizeAlign, World};
 
 use crate::{
     codegen::{
-        ExportGenerator, FactoryGenerator,
+        BenchmarkGenerator, ExampleGenerator, ExportGenerator, FactoryGenerator, ScaffoldGenerator,
+        ServiceGenerator,
+        benchmarks::BenchmarkConfig,
+        examples::ExampleConfig,
         exports::ExportConfig,
         factory::FactoryConfig,
         imports::{ImportAnalyzer, ImportCodeGenerator},
         ir::AnalyzedImports,
+        scaffold::ScaffoldConfig,
+        service::ServiceConfig,
         wasm::{Wasm, WasmData},
     },
-    go::GoIdentifier,
+    go::{
+        GoIdentifier, Naming, OptionStyle, Renames, comment,
+        imports::{HEX_ENCODE_TO_STRING, SHA256_SUM256},
+    },
 };
 
 /// The WIT bindings for a world.
@@ -28,6 +36,39 @@ pub struct Bindings<'a> {
 
     /// The sizes of the architecture.
     sizes: &'a SizeAlign,
+
+    /// The naming strategies for record fields, types and functions.
+    naming: Naming,
+
+    /// The Go identifiers overriding the generated ones.
+    renames: Renames,
+
+    /// Whether `list<u8>` parameters of host functions are views into guest
+    /// memory instead of copies.
+    byte_views: bool,
+
+    /// Whether `string` and `list<u8>` results of exported functions are
+    /// views into guest memory, returned with a cleanup function.
+    manual_cleanup: bool,
+
+    /// Whether host functions are registered without reflection, for TinyGo.
+    tinygo: bool,
+
+    /// How `option<T>` is represented in Go.
+    option_style: OptionStyle,
+
+    /// Whether named lists are defined types rather than inlined slices.
+    named_lists: bool,
+
+    /// Whether the included Wasm is gzip-compressed.
+    compressed_wasm: bool,
+
+    /// Whether records get `MarshalBinary` and `UnmarshalBinary` methods.
+    binary: bool,
+
+    /// Whether a gRPC-style service wrapping the exported functions is
+    /// generated.
+    grpc_adapter: bool,
 }
 
 impl<'a> Bindings<'a> {
@@ -41,9 +82,76 @@ impl<'a> Bindings<'a> {
             out: Tokens::new(),
             raw_wasm_var: wasm_var,
             sizes,
+            naming: Naming::default(),
+            renames: Renames::default(),
+            byte_views: false,
+            manual_cleanup: false,
+            tinygo: false,
+            option_style: OptionStyle::default(),
+            named_lists: false,
+            compressed_wasm: false,
+            binary: false,
+            grpc_adapter: false,
         }
     }
 
+    /// Sets the naming strategies for record fields, types and functions.
+    pub fn set_naming(&mut self, naming: Naming) {
+        self.naming = naming;
+    }
+
+    /// Sets the Go identifiers overriding the generated ones.
+    pub fn set_renames(&mut self, renames: Renames) {
+        self.renames = renames;
+    }
+
+    /// Sets whether `list<u8>` parameters of host functions are views into
+    /// guest memory instead of copies.
+    pub fn set_byte_views(&mut self, byte_views: bool) {
+        self.byte_views = byte_views;
+    }
+
+    /// Sets whether `string` and `list<u8>` results of exported functions are
+    /// views into guest memory, returned with a cleanup function.
+    pub fn set_manual_cleanup(&mut self, manual_cleanup: bool) {
+        self.manual_cleanup = manual_cleanup;
+    }
+
+    /// Sets whether host functions are registered without the reflection TinyGo
+    /// doesn't fully support. The bindings aren't verified to build with TinyGo.
+    pub fn set_tinygo(&mut self, tinygo: bool) {
+        self.tinygo = tinygo;
+    }
+
+    /// Sets how `option<T>` is represented in Go.
+    pub fn set_option_style(&mut self, option_style: OptionStyle) {
+        self.option_style = option_style;
+    }
+
+    /// Sets whether named lists, e.g. `type rows = list<row>`, are defined
+    /// types such as `Rows` rather than inlined slices.
+    pub fn set_named_lists(&mut self, named_lists: bool) {
+        self.named_lists = named_lists;
+    }
+
+    /// Sets whether records get `MarshalBinary` and `UnmarshalBinary` methods
+    /// encoding them in their Canonical ABI layout.
+    pub fn set_binary(&mut self, binary: bool) {
+        self.binary = binary;
+    }
+
+    /// Sets whether a gRPC-style service is generated, with a method calling
+    /// each exported function with the fields of a request struct.
+    pub fn set_grpc_adapter(&mut self, grpc_adapter: bool) {
+        self.grpc_adapter = grpc_adapter;
+    }
+
+    /// Sets whether the included Wasm is gzip-compressed, to be decompressed
+    /// when the first factory is created.
+    pub fn set_compressed_wasm(&mut self, compressed_wasm: bool) {
+        self.compressed_wasm = compressed_wasm;
+    }
+
     /// Adds the given Wasm to the bindings.
     pub fn include_wasm(&mut self, wasm: WasmData) {
         Wasm::new(&self.raw_wasm_var, wasm).format_into(&mut self.out)
@@ -57,14 +165,65 @@ impl<'a> Bindings<'a> {
         let (imports, chains) = self.generate_imports();
         self.generate_factory(&imports, chains);
         self.generate_exports(&imports.instance_name);
+        if self.grpc_adapter {
+            self.generate_service(&imports);
+        }
+        self.generate_build_info();
+    }
+
+    /// Generates the `Describe` function, returning how the bindings were
+    /// generated.
+    fn generate_build_info(&mut self) {
+        let version = env!("CARGO_PKG_VERSION");
+        let world = &self.world.name;
+        let wasm_var = &self.raw_wasm_var;
+        quote_in! { self.out =>
+            $['\n']
+            $(comment(&["BuildInfo describes how the bindings were generated."]))
+            type BuildInfo struct {
+                $(comment(&["GravityVersion is the version of gravity that generated the bindings."]))
+                GravityVersion string
+                $(comment(&[
+                    "WasmSHA256 is the hex-encoded SHA-256 hash of the embedded WebAssembly",
+                    "module, as compressed with --compress-wasm if it was.",
+                ]))
+                WasmSHA256 string
+                $(comment(&["World is the name of the WIT world the bindings were generated for."]))
+                World string
+            }
+            $['\n']
+            $(comment(&[
+                "Describe returns how the bindings were generated, such as to check which",
+                "version of them is deployed.",
+            ]))
+            func Describe() BuildInfo {
+                sum := $SHA256_SUM256($wasm_var)
+                return BuildInfo{
+                    GravityVersion: $(quoted(version)),
+                    WasmSHA256:     $HEX_ENCODE_TO_STRING(sum[:]),
+                    World:          $(quoted(world)),
+                }
+            }
+        }
     }
 
     /// Generates the imports for the bindings.
     fn generate_imports(&mut self) -> (AnalyzedImports, BTreeMap<String, Tokens<Go>>) {
-        let analyzer = ImportAnalyzer::new(self.resolve, self.world);
+        let analyzer = ImportAnalyzer::new(self.resolve, self.world)
+            .with_naming(self.naming.clone())
+            .with_renames(self.renames.clone())
+            .with_option_style(self.option_style)
+            .with_named_lists(self.named_lists);
         let analyzed = analyzer.analyze();
 
-        let generator = ImportCodeGenerator::new(self.resolve, &analyzed, self.sizes);
+        let generator = ImportCodeGenerator::new(self.resolve, &analyzed, self.sizes)
+            .with_naming(self.naming.clone())
+            .with_renames(self.renames.clone())
+            .with_byte_views(self.byte_views)
+            .with_tinygo(self.tinygo)
+            .with_option_style(self.option_style)
+            .with_named_lists(self.named_lists)
+            .with_binary(self.binary);
         let import_chains = generator.import_chains();
         generator.format_into(&mut self.out);
         (analyzed, import_chains)
@@ -82,7 +241,9 @@ impl<'a> Bindings<'a> {
             import_chains,
             wasm_var_name: &self.raw_wasm_var,
         };
-        FactoryGenerator::new(config).format_into(&mut self.out)
+        FactoryGenerator::new(config)
+            .with_compressed_wasm(self.compressed_wasm)
+            .format_into(&mut self.out)
     }
 
     /// Generates all exports for the world.
@@ -95,7 +256,142 @@ impl<'a> Bindings<'a> {
             world: self.world,
             resolve: self.resolve,
             sizes: self.sizes,
+            naming: &self.naming,
+            manual_cleanup: self.manual_cleanup,
+            option_style: self.option_style,
         };
-        ExportGenerator::new(config).format_into(&mut self.out)
+        ExportGenerator::new(config)
+            .with_renames(self.renames.clone())
+            .with_named_lists(self.named_lists)
+            .format_into(&mut self.out)
+    }
+
+    /// Generates the gRPC-style service wrapping the exported functions.
+    fn generate_service(&mut self, analyzed_imports: &AnalyzedImports) {
+        let config = ServiceConfig {
+            analyzed_imports,
+            world: self.world,
+            resolve: self.resolve,
+            option_style: self.option_style,
+            named_lists: self.named_lists,
+            naming: &self.naming,
+        };
+        ServiceGenerator::new(config)
+            .with_renames(self.renames.clone())
+            .format_into(&mut self.out)
+    }
+
+    /// Generates a benchmark of every function exported by the world.
+    ///
+    /// These are separate from the bindings, as they need to be written to a
+    /// `_test.go` file.
+    pub fn generate_benchmarks(&self) -> Tokens<Go> {
+        let analyzed_imports = ImportAnalyzer::new(self.resolve, self.world)
+            .with_option_style(self.option_style)
+            .with_named_lists(self.named_lists)
+            .analyze();
+        let config = BenchmarkConfig {
+            analyzed_imports: &analyzed_imports,
+            world: self.world,
+            resolve: self.resolve,
+            option_style: self.option_style,
+            naming: &self.naming,
+        };
+        let mut tokens = Tokens::new();
+        BenchmarkGenerator::new(config)
+            .with_renames(self.renames.clone())
+            .format_into(&mut tokens);
+        tokens
+    }
+
+    /// Generates a testable example of every exported function.
+    ///
+    /// Like the benchmarks, this needs to be written to a `_test.go` file.
+    pub fn generate_examples(&self) -> Tokens<Go> {
+        let analyzed_imports = ImportAnalyzer::new(self.resolve, self.world)
+            .with_option_style(self.option_style)
+            .with_named_lists(self.named_lists)
+            .analyze();
+        let config = ExampleConfig {
+            analyzed_imports: &analyzed_imports,
+            world: self.world,
+            resolve: self.resolve,
+            option_style: self.option_style,
+            naming: &self.naming,
+        };
+        let mut tokens = Tokens::new();
+        ExampleGenerator::new(config)
+            .with_renames(self.renames.clone())
+            .format_into(&mut tokens);
+        tokens
+    }
+
+    /// Generates a skeleton test for the world, stubbing its imports and
+    /// calling every exported function.
+    ///
+    /// Like the benchmarks, this needs to be written to a `_test.go` file.
+    pub fn generate_scaffold(&self) -> Tokens<Go> {
+        let analyzed_imports = ImportAnalyzer::new(self.resolve, self.world)
+            .with_naming(self.naming.clone())
+            .with_renames(self.renames.clone())
+            .with_option_style(self.option_style)
+            .with_named_lists(self.named_lists)
+            .analyze();
+        let config = ScaffoldConfig {
+            analyzed_imports: &analyzed_imports,
+            world: self.world,
+            resolve: self.resolve,
+            option_style: self.option_style,
+            naming: &self.naming,
+        };
+        let mut tokens = Tokens::new();
+        ScaffoldGenerator::new(config)
+            .with_renames(self.renames.clone())
+            .format_into(&mut tokens);
+        tokens
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use wit_bindgen_core::wit_parser::{Resolve, SizeAlign};
+
+    use crate::codegen::Bindings;
+
+    #[test]
+    fn test_generate_build_info() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "builds.wit",
+                r#"
+                package arcjet:builds;
+
+                world builds {
+                  export run: func() -> u32;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "builds")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let mut bindings = Bindings::new(&resolve, world, &sizes);
+        bindings.generate();
+
+        let generated = bindings.out.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        assert!(generated.contains("func Describe() BuildInfo {"));
+        assert!(generated.contains("sum := sha256.Sum256(wasmFileBuilds)"));
+        assert!(generated.contains(&format!(
+            "GravityVersion: \"{}\"",
+            env!("CARGO_PKG_VERSION")
+        )));
+        assert!(generated.contains("World: \"builds\""));
     }
 }
@@ -0,0 +1,219 @@
+use genco::prelude::*;
+use wit_bindgen_core::wit_parser::{Resolve, World, WorldItem};
+
+use crate::{
+    codegen::{benchmarks::representative, ir::AnalyzedImports},
+    go::{
+        Naming, OptionStyle, Renames, comment,
+        imports::{CONTEXT_BACKGROUND, FMT_PRINTLN, LOG_FATAL},
+    },
+};
+
+/// Configuration for example generation.
+pub struct ExampleConfig<'a> {
+    pub analyzed_imports: &'a AnalyzedImports,
+    pub world: &'a World,
+    pub resolve: &'a Resolve,
+    pub option_style: OptionStyle,
+    /// The naming strategy for type and function names.
+    pub naming: &'a Naming,
+}
+
+/// Generator for a testable example of every function exported by a world.
+///
+/// The examples are meant to be written to a `_test.go` file next to the
+/// bindings, so they show up in the package documentation and `go test`
+/// checks that they compile. Gravity can't know what a guest returns, so only
+/// the examples of functions without results have an `// Output:` comment,
+/// expecting nothing to be printed, and are run by `go test`.
+pub struct ExampleGenerator<'a> {
+    config: ExampleConfig<'a>,
+    renames: Renames,
+}
+
+impl<'a> ExampleGenerator<'a> {
+    /// Create a new example generator with the given config.
+    pub fn new(config: ExampleConfig<'a>) -> Self {
+        Self {
+            config,
+            renames: Renames::default(),
+        }
+    }
+
+    /// Set the Go identifiers overriding the generated ones.
+    pub fn with_renames(mut self, renames: Renames) -> Self {
+        self.renames = renames;
+        self
+    }
+}
+
+impl FormatInto<Go> for ExampleGenerator<'_> {
+    fn format_into(self, tokens: &mut Tokens<Go>) {
+        let AnalyzedImports {
+            instance_name,
+            constructor_name,
+            interfaces,
+            ..
+        } = self.config.analyzed_imports;
+        // Like the benchmarks, worlds with imports must provide a
+        // `newExampleFactory` function in another test file, creating the
+        // factory with host implementations which don't print anything.
+        let new_factory = if interfaces.is_empty() {
+            quote!($constructor_name(ctx))
+        } else {
+            quote!(newExampleFactory(ctx))
+        };
+        for item in self.config.world.exports.values() {
+            let WorldItem::Function(func) = item else {
+                continue;
+            };
+            let params = func
+                .params
+                .iter()
+                .flat_map(|(name, wit_type)| {
+                    let typ = crate::resolve_type_with(
+                        wit_type,
+                        self.config.resolve,
+                        false,
+                        self.config.naming,
+                    );
+                    self.config.option_style.go_type(typ).params(name)
+                })
+                .collect::<Vec<_>>();
+            let fn_name = &self.renames.export(self.config.naming, func);
+            let example_name = format!(
+                "Example{}_{}",
+                String::from(instance_name),
+                String::from(fn_name)
+            );
+            let call = quote! {
+                ins.$fn_name(
+                    $['\r']
+                    ctx,
+                    $(for (name, _) in &params join ($['\r']) => $name,)
+                )
+            };
+            quote_in! { *tokens =>
+                $['\n']
+                $(comment([format!(
+                    "{example_name} calls {} on a new instance.",
+                    String::from(fn_name)
+                )]))
+                func $example_name() {
+                    ctx := $CONTEXT_BACKGROUND()
+                    fac, err := $(&new_factory)
+                    if err != nil {
+                        $LOG_FATAL(err)
+                    }
+                    defer fac.Close(ctx)
+
+                    ins, err := fac.Instantiate(ctx)
+                    if err != nil {
+                        $LOG_FATAL(err)
+                    }
+                    defer ins.Close(ctx)
+                    $['\n']
+                    $(for (name, typ) in &params join ($['\r']) =>
+                        $(match representative(typ) {
+                            Some(value) => { $name := $value }
+                            None => { var $name $typ }
+                        })
+                    )
+                    $(match &func.result {
+                        Some(_) => { $FMT_PRINTLN($(&call)) }
+                        None => {
+                            $(&call)
+                            $['\r']
+                            $(comment(["Output:"]))
+                        }
+                    })
+                }
+            };
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use genco::prelude::*;
+    use wit_bindgen_core::wit_parser::Resolve;
+
+    use crate::{codegen::imports::ImportAnalyzer, go::OptionStyle};
+
+    use super::{ExampleConfig, ExampleGenerator};
+
+    fn generate(wit: &str) -> String {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str("greeter.wit", wit)
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "greeter")
+            .expect("failed to find world");
+        let analyzed_imports = ImportAnalyzer::new(&resolve, world).analyze();
+        let naming = Naming::default();
+        let generator = ExampleGenerator::new(ExampleConfig {
+            analyzed_imports: &analyzed_imports,
+            world,
+            resolve: &resolve,
+            option_style: OptionStyle::Pair,
+            naming: &naming,
+        });
+        let mut tokens = Tokens::<Go>::new();
+        generator.format_into(&mut tokens);
+        tokens.to_string().unwrap()
+    }
+
+    #[test]
+    fn test_examples() {
+        let generated = generate(
+            r#"
+            package arcjet:greeter;
+
+            world greeter {
+              export greet: func(name: string) -> string;
+              export reset: func();
+            }
+            "#,
+        );
+        println!("Generated: {}", generated);
+
+        // Every export has an example named after the instance method
+        assert!(generated.contains("func ExampleGreeterInstance_Greet() {"));
+        assert!(generated.contains("func ExampleGreeterInstance_Reset() {"));
+        assert!(generated.contains("fac, err := NewGreeterFactory(ctx)"));
+        assert!(generated.contains("name := \"gravity\""));
+
+        // Results are printed, but only examples without any are verified
+        assert!(generated.contains("fmt.Println(ins.Greet("));
+        assert_eq!(generated.matches("// Output:").count(), 1);
+        let reset = &generated[generated.find("func ExampleGreeterInstance_Reset").unwrap()..];
+        assert!(reset.contains("// Output:"));
+    }
+
+    #[test]
+    fn test_examples_with_imports() {
+        let generated = generate(
+            r#"
+            package arcjet:greeter;
+
+            interface names {
+              lookup: func(id: u32) -> string;
+            }
+
+            world greeter {
+              import names;
+
+              export greet: func(id: u32) -> string;
+            }
+            "#,
+        );
+        println!("Generated: {}", generated);
+
+        // Host implementations come from a function written by the user
+        assert!(generated.contains("fac, err := newExampleFactory(ctx)"));
+        assert!(generated.contains("id := uint32(42)"));
+    }
+}
@@ -1,22 +1,54 @@
 use genco::prelude::*;
-use wit_bindgen_core::wit_parser::{Function, Resolve, SizeAlign, World, WorldItem};
+use wit_bindgen_core::{
+    abi::{AbiVariant, guest_export_needs_post_return},
+    wit_parser::{Function, Resolve, Result_, SizeAlign, Type, TypeDefKind, World, WorldItem},
+};
 
-use crate::go::{GoIdentifier, GoResult, GoType, imports::CONTEXT_CONTEXT};
+use crate::go::{
+    GoIdentifier, GoResult, GoType, INSTANCE_METHODS, Naming, OptionStyle, Renames, comment,
+    imports::{CONTEXT_CONTEXT, ERRORS_NEW, ITER_SEQ2},
+};
+use crate::nonempty_params;
 
 pub struct ExportConfig<'a> {
     pub instance: &'a GoIdentifier,
     pub world: &'a World,
     pub resolve: &'a Resolve,
     pub sizes: &'a SizeAlign,
+    pub naming: &'a Naming,
+    /// Whether `string` and `list<u8>` results are views into guest memory,
+    /// returned with a function freeing them once the caller is done.
+    pub manual_cleanup: bool,
+    /// How `option<T>` is represented in Go.
+    pub option_style: OptionStyle,
 }
 
 pub struct ExportGenerator<'a> {
     config: ExportConfig<'a>,
+    renames: Renames,
+    named_lists: bool,
 }
 
 impl<'a> ExportGenerator<'a> {
     pub fn new(config: ExportConfig<'a>) -> Self {
-        Self { config }
+        Self {
+            config,
+            renames: Renames::default(),
+            named_lists: false,
+        }
+    }
+
+    /// Set the Go identifiers overriding the generated ones.
+    pub fn with_renames(mut self, renames: Renames) -> Self {
+        self.renames = renames;
+        self
+    }
+
+    /// Set whether named lists, e.g. `type rows = list<row>`, are defined
+    /// types such as `Rows` rather than inlined slices.
+    pub fn with_named_lists(mut self, named_lists: bool) -> Self {
+        self.named_lists = named_lists;
+        self
     }
 
     /// Generate the Go function code for the given function.
@@ -31,25 +63,24 @@ impl<'a> ExportGenerator<'a> {
     ///   `wit_bindgen_core::abi::call` function. This will call `Func::emit` lots of
     ///   times, one for each instruction in the function, and `Func::emit` will generate
     ///   Go code for each instruction
-    fn generate_function(&self, func: &Function, tokens: &mut Tokens<Go>) {
-        let params = func
-            .params
-            .iter()
-            .map(
-                |(name, wit_type)| match crate::resolve_type(wit_type, self.config.resolve) {
-                    GoType::ValueOrOk(t) => (GoIdentifier::local(name), *t),
-                    t => (GoIdentifier::local(name), t),
-                },
-            )
-            .collect::<Vec<_>>();
+    ///
+    /// Returns the method's signature, for the instance interface.
+    fn generate_function(&self, func: &Function, tokens: &mut Tokens<Go>) -> Tokens<Go> {
+        let params = self.params(func);
 
         let result = if let Some(wit_type) = &func.result {
-            GoResult::Anon(crate::resolve_type(wit_type, self.config.resolve))
+            GoResult::Anon(self.go_type(wit_type))
         } else {
             GoResult::Empty
         };
 
-        let mut f = crate::Func::export(result, self.config.sizes);
+        let mut f = crate::Func::export(result, self.config.sizes)
+            .with_naming(self.config.naming.clone())
+            .with_renames(self.renames.clone())
+            .with_manual_cleanup(self.config.manual_cleanup)
+            .with_option_style(self.config.option_style)
+            .with_named_lists(self.named_lists)
+            .with_post_return(guest_export_needs_post_return(self.config.resolve, func));
         wit_bindgen_core::abi::call(
             self.config.resolve,
             wit_bindgen_core::abi::AbiVariant::GuestExport,
@@ -60,36 +91,420 @@ impl<'a> ExportGenerator<'a> {
             false,
         );
 
-        let arg_assignments = f
-            .args()
-            .iter()
-            .zip(&params)
-            .map(|(arg, (param, _))| (arg, param))
-            .collect::<Vec<_>>();
-        let fn_name = &GoIdentifier::public(&func.name);
+        let arg_assignments = arg_assignments(f.args(), &params);
+        let fn_name = &self.renames.export(self.config.naming, func);
+        let validations = self.validations(func, fn_name, f.result());
+        let (docs, result) = if f.returns_view() {
+            (
+                vec![
+                    format!(
+                        "{} returns a view into guest memory, which is only valid until the",
+                        String::from(fn_name)
+                    ),
+                    "returned cleanup function is called. It must be called exactly once."
+                        .to_string(),
+                ],
+                quote!(($(f.result()), func())),
+            )
+        } else {
+            (Vec::new(), quote!($(f.result())))
+        };
         quote_in! { *tokens =>
             $['\n']
+            $(comment(docs))
             func (i *$(self.config.instance)) $fn_name(
                 $['\r']
                 ctx $CONTEXT_CONTEXT,
-                $(for (name, typ) in &params join ($['\r']) => $name $typ,)
-            ) $(f.result()) {
+                $(for (name, typ) in params.iter().flatten() join ($['\r']) => $name $typ,)
+            ) $(&result) {
+                i.memory.exceeded = false
+                $validations
                 $(for (arg, param) in arg_assignments join ($['\r']) => $arg := $param)
                 $(f.body())
             }
         }
+        quote!($fn_name($(signature_params(&params))) $result)
+    }
+
+    /// Generate a `{Func}ArgSize` function, returning how many bytes of guest
+    /// memory lowering the arguments of the function allocates, if it
+    /// allocates any.
+    fn generate_arg_size(&self, func: &Function, tokens: &mut Tokens<Go>) {
+        let params = self.params(func);
+        let mut body = Tokens::new();
+        // Too many parameters to pass as core Wasm values are stored in memory
+        // the host allocates
+        let sig = self
+            .config
+            .resolve
+            .wasm_signature(AbiVariant::GuestExport, func);
+        if sig.indirect_params {
+            let area = self
+                .config
+                .sizes
+                .record(func.params.iter().map(|(_, wit_type)| wit_type))
+                .size
+                .size_wasm32();
+            quote_in! { body =>
+                $['\r']
+                size += $area
+            };
+        }
+        for ((_, wit_type), params) in func.params.iter().zip(&params) {
+            let (name, _) = &params[0];
+            self.arg_size(wit_type, quote!($name), 0, &mut body);
+        }
+        if body.is_empty() {
+            return;
+        }
+
+        let fn_name = &self.renames.export(self.config.naming, func);
+        let size_name = &GoIdentifier::public(format!("{}ArgSize", String::from(fn_name)));
+        let docs = [
+            format!(
+                "{} returns how many bytes of guest memory calling {} allocates",
+                String::from(size_name),
+                String::from(fn_name),
+            ),
+            "for the given arguments, such as to reject oversized ones beforehand.".to_string(),
+        ];
+        quote_in! { *tokens =>
+            $['\n']
+            $(comment(docs))
+            func $size_name(
+                $['\r']
+                $(for (name, typ) in params.iter().flatten() join ($['\r']) => $name $typ,)
+            ) uint64 {
+                var size uint64
+                $body
+                return size
+            }
+        }
+    }
+
+    /// Appends the statements adding the bytes lowering `value` of the given
+    /// type allocates to `size`. Nested lists range over their elements with
+    /// variables named after their `depth`.
+    fn arg_size(&self, wit_type: &Type, value: Tokens<Go>, depth: usize, tokens: &mut Tokens<Go>) {
+        let Type::Id(id) = wit_type else {
+            if let Type::String = wit_type {
+                quote_in! { *tokens =>
+                    $['\r']
+                    size += uint64(len($value))
+                };
+            }
+            return;
+        };
+        match &self.config.resolve.types[*id].kind {
+            TypeDefKind::Type(inner) => self.arg_size(inner, value, depth, tokens),
+            TypeDefKind::List(elem) => {
+                let size = self.config.sizes.size(elem).size_wasm32();
+                quote_in! { *tokens =>
+                    $['\r']
+                    size += uint64(len($(&value))) * $size
+                };
+                let elem_value = &format!("e{depth}");
+                let mut elem_size = Tokens::new();
+                self.arg_size(elem, quote!($elem_value), depth + 1, &mut elem_size);
+                if !elem_size.is_empty() {
+                    quote_in! { *tokens =>
+                        $['\r']
+                        for _, $elem_value := range $value {
+                            $elem_size
+                        }
+                    };
+                }
+            }
+            TypeDefKind::Record(record) => {
+                for field in &record.fields {
+                    let name = self.renames.field(
+                        self.config.naming,
+                        self.config.resolve,
+                        *id,
+                        &field.name,
+                    );
+                    self.arg_size(&field.ty, quote!($(&value).$name), depth, tokens);
+                }
+            }
+            TypeDefKind::Tuple(tuple) => {
+                for (i, typ) in tuple.types.iter().enumerate() {
+                    self.arg_size(typ, quote!($(&value).$(format!("F{i}"))), depth, tokens);
+                }
+            }
+            // Only parameters can be results, whose error is passed next to
+            // their value
+            TypeDefKind::Result(Result_ {
+                ok,
+                err: Some(Type::String),
+            }) => {
+                let value = value.to_string().expect("failed to format value");
+                let err = &match ok {
+                    Some(_) => format!("{value}Err"),
+                    None => value.clone(),
+                };
+                let mut ok_size = Tokens::new();
+                if let Some(ok) = ok {
+                    self.arg_size(ok, quote!($value), depth, &mut ok_size);
+                }
+                if ok_size.is_empty() {
+                    quote_in! { *tokens =>
+                        $['\r']
+                        if $err != nil {
+                            size += uint64(len($err.Error()))
+                        }
+                    };
+                } else {
+                    quote_in! { *tokens =>
+                        $['\r']
+                        if $err != nil {
+                            size += uint64(len($err.Error()))
+                        } else {
+                            $ok_size
+                        }
+                    };
+                }
+            }
+            TypeDefKind::Option(inner) => match self.config.option_style {
+                OptionStyle::Pair => self.arg_size(inner, value, depth, tokens),
+                OptionStyle::Pointer => {
+                    let mut inner_size = Tokens::new();
+                    self.arg_size(inner, quote!((*$(&value))), depth, &mut inner_size);
+                    if !inner_size.is_empty() {
+                        quote_in! { *tokens =>
+                            $['\r']
+                            if $value != nil {
+                                $inner_size
+                            }
+                        };
+                    }
+                }
+            },
+            // Other types are lowered to core Wasm values without allocating
+            _ => {}
+        }
+    }
+
+    /// Generate a `{Func}Seq` variant of a function returning a `list<record>`.
+    ///
+    /// The variant returns an `iter.Seq2` which calls the function once the
+    /// caller starts ranging over it, and then lifts one record at a time from
+    /// guest memory. The post-return cleanup runs after the last record, or as
+    /// soon as the caller stops ranging early.
+    ///
+    /// Returns the method's signature, for the instance interface.
+    fn generate_seq_function(
+        &self,
+        func: &Function,
+        elem: &GoType,
+        tokens: &mut Tokens<Go>,
+    ) -> Tokens<Go> {
+        let params = self.params(func);
+
+        let mut f = crate::Func::export_seq(self.config.sizes)
+            .with_naming(self.config.naming.clone())
+            .with_renames(self.renames.clone())
+            .with_option_style(self.config.option_style)
+            .with_named_lists(self.named_lists);
+        wit_bindgen_core::abi::call(
+            self.config.resolve,
+            wit_bindgen_core::abi::AbiVariant::GuestExport,
+            wit_bindgen_core::abi::LiftLower::LowerArgsLiftResults,
+            func,
+            &mut f,
+            // async is not currently supported
+            false,
+        );
+
+        let arg_assignments = arg_assignments(f.args(), &params);
+        let fn_name = &GoIdentifier::public(format!(
+            "{}Seq",
+            String::from(self.renames.export(self.config.naming, func))
+        ));
+        quote_in! { *tokens =>
+            $['\n']
+            func (i *$(self.config.instance)) $fn_name(
+                $['\r']
+                ctx $CONTEXT_CONTEXT,
+                $(for (name, typ) in params.iter().flatten() join ($['\r']) => $name $typ,)
+            ) $ITER_SEQ2[$elem, error] {
+                return func(yield func($elem, error) bool) {
+                    err := func() error {
+                        i.memory.exceeded = false
+                        $(for (arg, param) in arg_assignments join ($['\r']) => $arg := $param)
+                        $(f.body())
+                    }()
+                    if err != nil {
+                        var zero $elem
+                        yield(zero, err)
+                    }
+                }
+            }
+        }
+        quote!($fn_name($(signature_params(&params))) $ITER_SEQ2[$elem, error])
+    }
+
+    /// Generate an interface with every method of the instance, which the
+    /// instance is asserted to implement.
+    ///
+    /// Code depending on an instance can accept the interface instead, so it
+    /// can be tested with a mock.
+    fn generate_instance_interface(&self, methods: &[Tokens<Go>], tokens: &mut Tokens<Go>) {
+        let instance = self.config.instance;
+        let interface = &GoIdentifier::public(format!("i-{}-instance", self.config.world.name));
+        let docs = [
+            format!(
+                "{} has every method of {}, so code",
+                String::from(interface),
+                String::from(instance),
+            ),
+            "depending on an instance can accept it instead, and be tested with a mock."
+                .to_string(),
+        ];
+        quote_in! { *tokens =>
+            $['\n']
+            $(comment(docs))
+            type $interface interface {
+                $(for name in INSTANCE_METHODS join ($['\r']) => $(instance_method(name)))
+                $(for method in methods join ($['\r']) => $method)
+            }
+            $['\n']
+            var _ $interface = (*$instance)(nil)
+        }
+    }
+
+    /// Resolves the Go type of a WIT type, with options in the configured
+    /// style.
+    fn go_type(&self, wit_type: &Type) -> GoType {
+        self.config.option_style.go_type(crate::resolve_type_with(
+            wit_type,
+            self.config.resolve,
+            self.named_lists,
+            self.config.naming,
+        ))
+    }
+
+    /// Resolves the Go parameters each parameter of the given function is
+    /// passed as.
+    /// Returns the checks of the parameters constrained with `@go-validate`
+    /// directives, returning an error before calling into the guest if one
+    /// fails, or panicking if the function can't return one.
+    fn validations(
+        &self,
+        func: &Function,
+        fn_name: &GoIdentifier,
+        result: &GoResult,
+    ) -> Tokens<Go> {
+        let mut tokens = Tokens::new();
+        for name in nonempty_params(func, self.config.resolve) {
+            let param = &GoIdentifier::local(name);
+            let message = format!("{}: {name} must not be empty", String::from(fn_name));
+            let err = &quote!($ERRORS_NEW($(quoted(message))));
+            quote_in! { tokens =>
+                $['\r']
+                if $param == "" {
+                    $(match result {
+                        GoResult::Anon(GoType::ValueOrError(typ)) => {
+                            var zero $(typ.as_ref())
+                            return zero, $err
+                        }
+                        GoResult::Anon(GoType::Error) => return $err,
+                        GoResult::Anon(_) | GoResult::Empty => panic($err),
+                    })
+                }
+            }
+        }
+        tokens
     }
+
+    fn params(&self, func: &Function) -> Vec<Vec<(GoIdentifier, GoType)>> {
+        func.params
+            .iter()
+            .map(|(name, wit_type)| self.go_type(wit_type).params(name))
+            .collect()
+    }
+
+    /// Returns the Go type of the records if the function returns a
+    /// `list<record>`, which is the case a `{Func}Seq` variant is generated for.
+    fn seq_element(&self, func: &Function) -> Option<GoType> {
+        let types = &self.config.resolve.types;
+        let Some(Type::Id(id)) = &func.result else {
+            return None;
+        };
+        let TypeDefKind::List(elem @ Type::Id(elem_id)) = &types[*id].kind else {
+            return None;
+        };
+        matches!(types[*elem_id].kind, TypeDefKind::Record(_))
+            .then(|| crate::resolve_type_with(elem, self.config.resolve, false, self.config.naming))
+    }
+}
+
+/// Returns the parameters of a method in the instance interface, on one line.
+fn signature_params(params: &[Vec<(GoIdentifier, GoType)>]) -> Tokens<Go> {
+    let ctx = quote!(ctx $CONTEXT_CONTEXT);
+    let params = params
+        .iter()
+        .flatten()
+        .map(|(name, typ)| quote!($name $typ));
+    quote!($(for param in std::iter::once(ctx).chain(params) join (, ) => $param))
+}
+
+/// Pairs the arguments of a `Func` with the parameters of the Go function.
+///
+/// The error of a result is assigned to the argument suffixed with `Err`,
+/// which is where lowering the result expects it.
+fn arg_assignments(
+    args: &[String],
+    params: &[Vec<(GoIdentifier, GoType)>],
+) -> Vec<(String, Tokens<Go>)> {
+    args.iter()
+        .zip(params)
+        .flat_map(|(arg, params)| {
+            params.iter().enumerate().map(move |(i, (param, typ))| {
+                let arg = if i == 0 {
+                    arg.clone()
+                } else {
+                    format!("{arg}Err")
+                };
+                match typ {
+                    // Lowering works on the underlying types of defined types
+                    GoType::Defined(_, underlying) => (arg, quote!($(underlying.as_ref())($param))),
+                    _ => (arg, quote!($param)),
+                }
+            })
+        })
+        .collect()
 }
 
 impl FormatInto<Go> for ExportGenerator<'_> {
     fn format_into(self, tokens: &mut Tokens<Go>) {
+        let mut methods = Vec::new();
+        let mut functions = Tokens::new();
         for item in self.config.world.exports.values() {
             match item {
-                WorldItem::Function(func) => self.generate_function(func, tokens),
+                WorldItem::Function(func) => {
+                    methods.push(self.generate_function(func, &mut functions));
+                    self.generate_arg_size(func, &mut functions);
+                    if let Some(elem) = self.seq_element(func) {
+                        methods.push(self.generate_seq_function(func, &elem, &mut functions));
+                    }
+                }
                 WorldItem::Interface { .. } => todo!("generate interface exports"),
                 WorldItem::Type(_) => todo!("generate type exports"),
             }
         }
+        self.generate_instance_interface(&methods, tokens);
+        functions.format_into(tokens);
+    }
+}
+
+/// Returns the signature of a method every instance has, for the interface of
+/// the instance.
+fn instance_method(name: &str) -> Tokens<Go> {
+    match name {
+        "Close" | "Reset" => quote!($name(ctx $CONTEXT_CONTEXT) error),
+        "MemorySize" => quote!(MemorySize() uint64),
+        "MemoryStats" => quote!(MemoryStats() MemoryStats),
+        _ => unreachable!("instance method `{name}` has no signature"),
     }
 }
 
@@ -97,10 +512,11 @@ impl FormatInto<Go> for ExportGenerator<'_> {
 mod tests {
     use genco::prelude::*;
     use wit_bindgen_core::wit_parser::{
-        Function, FunctionKind, Resolve, SizeAlign, Type, World, WorldItem, WorldKey,
+        Field, Flag, Flags, Function, FunctionKind, Record, Resolve, SizeAlign, Tuple, Type,
+        TypeDef, TypeDefKind, TypeOwner, World, WorldItem, WorldKey,
     };
 
-    use crate::go::GoIdentifier;
+    use crate::go::{GoIdentifier, Naming, OptionStyle};
 
     use super::{ExportConfig, ExportGenerator};
 
@@ -140,6 +556,9 @@ mod tests {
             world: &world,
             resolve: &resolve,
             sizes: &sizes,
+            naming: &Naming::default(),
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
         };
 
         let generator = ExportGenerator::new(config);
@@ -158,15 +577,1575 @@ mod tests {
         assert!(generated.contains(") uint32 {"));
 
         // Verify function body
+        assert!(generated.contains(") uint32 {\n\ti.memory.exceeded = false\n"));
         assert!(generated.contains("arg0 := value"));
-        assert!(
-            generated
-                .contains("i.module.ExportedFunction(\"add_number\").Call(ctx, uint64(result0))")
-        );
+        assert!(generated.contains("i.callExport(ctx, \"add_number\", uint64(result0))"));
         assert!(generated.contains("if err1 != nil {"));
-        assert!(generated.contains("panic(err1)"));
+        assert!(generated.contains("panic(i.callError(err1))"));
         assert!(generated.contains("results1 := raw1[0]"));
-        assert!(generated.contains("result2 := api.DecodeU32(results1)"));
+        assert!(generated.contains("result2 := uint32(results1)"));
         assert!(generated.contains("return result2"));
+
+        // The call is logged with its core arguments and result, if enabled
+        assert!(generated.contains("if i.callLogger != nil {"));
+        assert!(
+            generated
+                .contains("i.logCall(ctx, \"add_number\", []uint64{uint64(result0)}, result2)")
+        );
+    }
+
+    #[test]
+    fn test_generate_seq_function_list_of_records() {
+        let mut resolve = Resolve::new();
+        let record_id = resolve.types.alloc(TypeDef {
+            name: Some("log-entry".to_string()),
+            kind: TypeDefKind::Record(Record {
+                fields: vec![
+                    Field {
+                        name: "level".to_string(),
+                        ty: Type::U32,
+                        docs: Default::default(),
+                    },
+                    Field {
+                        name: "message".to_string(),
+                        ty: Type::String,
+                        docs: Default::default(),
+                    },
+                ],
+            }),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+        let list_id = resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::List(Type::Id(record_id)),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+
+        let func = Function {
+            name: "logs".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: vec![("count".to_string(), Type::U32)],
+            result: Some(Type::Id(list_id)),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        let world = World {
+            name: "test-world".to_string(),
+            imports: [].into(),
+            exports: [(
+                WorldKey::Name("logs".to_string()),
+                WorldItem::Function(func.clone()),
+            )]
+            .into(),
+            docs: Default::default(),
+            stability: Default::default(),
+            includes: Default::default(),
+            include_names: Default::default(),
+            package: None,
+        };
+
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let naming = Naming::default();
+        let generator = ExportGenerator::new(ExportConfig {
+            instance: &instance,
+            world: &world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &naming,
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        });
+
+        let elem = generator
+            .seq_element(&func)
+            .expect("list<record> should be streamable");
+        let mut tokens = Tokens::new();
+        generator.generate_seq_function(&func, &elem, &mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Verify the signature returns an iterator over the records
+        assert!(generated.contains("func (i *TestInstance) LogsSeq("));
+        assert!(generated.contains("count uint32"));
+        assert!(generated.contains(") iter.Seq2[LogEntry, error] {"));
+        assert!(generated.contains("return func(yield func(LogEntry, error) bool) {"));
+
+        // The guest is only called once the caller ranges over the iterator,
+        // and its memory is cleaned up whenever the closure returns
+        assert!(generated.contains("err := func() error {"));
+        assert!(generated.contains("return err1"));
+        assert!(generated.contains("cabi_post_logs"));
+
+        // Each record is lifted and yielded on its own, without a slice
+        assert!(!generated.contains("make([]LogEntry"));
+        assert!(generated.contains("base10 := ptr2"));
+        assert!(generated.contains("len10 := len3"));
+        assert!(generated.contains("base := base10 + idx10 * 12"));
+        assert!(generated.contains("value9 := LogEntry{"));
+        assert!(generated.contains("if !yield(value9, nil) {"));
+        assert!(generated.contains("return nil"));
+
+        // Errors are yielded once instead of being returned
+        assert!(generated.contains("var zero LogEntry"));
+        assert!(generated.contains("yield(zero, err)"));
+    }
+
+    #[test]
+    fn test_generate_function_empty_list_param() {
+        let mut resolve = Resolve::new();
+        let list_id = resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::List(Type::String),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+
+        let func = Function {
+            name: "count".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: vec![("items".to_string(), Type::Id(list_id))],
+            result: Some(Type::U32),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        let world = World {
+            name: "test-world".to_string(),
+            imports: [].into(),
+            exports: [(
+                WorldKey::Name("count".to_string()),
+                WorldItem::Function(func.clone()),
+            )]
+            .into(),
+            docs: Default::default(),
+            stability: Default::default(),
+            includes: Default::default(),
+            include_names: Default::default(),
+            package: None,
+        };
+
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let naming = Naming::default();
+        let generator = ExportGenerator::new(ExportConfig {
+            instance: &instance,
+            world: &world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &naming,
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        });
+        let mut tokens = Tokens::new();
+        generator.generate_function(&func, &mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Empty and nil slices get an aligned pointer without calling realloc
+        assert!(generated.contains("vec1 := arg0"));
+        assert!(generated.contains("len1 := uint64(len(vec1))"));
+        assert!(generated.contains("ptr1 := uint64(4)"));
+        assert!(generated.contains("if len1 > 0 {"));
+        assert!(
+            generated.contains(
+                "result1, err1 := i.realloc(\"cabi_realloc\").Call(ctx, 0, 0, 4, len1 * 8)"
+            )
+        );
+        assert!(generated.contains("if err1 == nil && result1[0] == 0 {"));
+        assert!(generated.contains("err1 = ErrGuestAllocFailed"));
+        assert!(generated.contains("ptr1 = result1[0]"));
+        assert!(generated.contains("Call(ctx, uint64(ptr1), uint64(len1))"));
+
+        // The arguments are allocated from the arena when enabled, and released
+        // once the call returns
+        assert!(generated.contains("realloc0 := i.realloc(\"cabi_realloc\")"));
+        assert!(generated.contains("i.arena.reset(ctx)"));
+    }
+
+    #[test]
+    fn test_generate_function_list_of_u16() {
+        let mut resolve = Resolve::new();
+        let list_id = resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::List(Type::U16),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+
+        let func = Function {
+            name: "reverse".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: vec![("values".to_string(), Type::Id(list_id))],
+            result: Some(Type::Id(list_id)),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        let world = World {
+            name: "test-world".to_string(),
+            imports: [].into(),
+            exports: [(
+                WorldKey::Name("reverse".to_string()),
+                WorldItem::Function(func.clone()),
+            )]
+            .into(),
+            docs: Default::default(),
+            stability: Default::default(),
+            includes: Default::default(),
+            include_names: Default::default(),
+            package: None,
+        };
+
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let naming = Naming::default();
+        let generator = ExportGenerator::new(ExportConfig {
+            instance: &instance,
+            world: &world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &naming,
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        });
+        let mut tokens = Tokens::new();
+        generator.generate_function(&func, &mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Elements are written 2 bytes apart, with 2-byte alignment
+        assert!(generated.contains("Call(ctx, 0, 0, 2, len1 * 2)"));
+        assert!(generated.contains("base := uint32(ptr1 + uint64(idx) * uint64(2))"));
+        assert!(generated.contains("i.guest.WriteUint16Le(base+0, uint16("));
+
+        // And read back 2 bytes apart
+        assert!(generated.contains("base := base"));
+        assert!(generated.contains(" * 2"));
+        assert!(generated.contains("i.guest.ReadUint16Le(uint32(base + 0))"));
+        assert!(generated.contains(":= make([]uint16, len"));
+    }
+
+    #[test]
+    fn test_generate_function_manual_cleanup() {
+        let func = Function {
+            name: "greet".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: vec![],
+            result: Some(Type::String),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        let world = World {
+            name: "test-world".to_string(),
+            imports: [].into(),
+            exports: [(
+                WorldKey::Name("greet".to_string()),
+                WorldItem::Function(func.clone()),
+            )]
+            .into(),
+            docs: Default::default(),
+            stability: Default::default(),
+            includes: Default::default(),
+            include_names: Default::default(),
+            package: None,
+        };
+
+        let resolve = Resolve::new();
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let naming = Naming::default();
+        let generator = ExportGenerator::new(ExportConfig {
+            instance: &instance,
+            world: &world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &naming,
+            manual_cleanup: true,
+            option_style: OptionStyle::Pair,
+        });
+        let mut tokens = Tokens::new();
+        generator.generate_function(&func, &mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // The string is a view into guest memory, freed by the caller
+        assert!(generated.contains(") (string, func()) {"));
+        assert!(generated.contains("cleanup := func() {"));
+        assert!(!generated.contains("defer func() {"));
+        assert!(generated.contains("unsafe.String(unsafe.SliceData(buf"));
+        assert!(generated.contains(", cleanup"));
+    }
+
+    #[test]
+    fn test_generate_function_spills_params_to_memory() {
+        // 20 `f64` parameters exceed the 16 flat parameters of the Canonical
+        // ABI, so they are passed through memory instead
+        let func = Function {
+            name: "sum".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: (0..20).map(|n| (format!("x{n}"), Type::F64)).collect(),
+            result: Some(Type::F64),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        let world = World {
+            name: "test-world".to_string(),
+            imports: [].into(),
+            exports: [(
+                WorldKey::Name("sum".to_string()),
+                WorldItem::Function(func.clone()),
+            )]
+            .into(),
+            docs: Default::default(),
+            stability: Default::default(),
+            includes: Default::default(),
+            include_names: Default::default(),
+            package: None,
+        };
+
+        let resolve = Resolve::new();
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let naming = Naming::default();
+        let generator = ExportGenerator::new(ExportConfig {
+            instance: &instance,
+            world: &world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &naming,
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        });
+        let mut tokens = Tokens::new();
+        generator.generate_function(&func, &mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Every parameter is still part of the Go signature
+        assert!(generated.contains("x0 float64"));
+        assert!(generated.contains("x19 float64"));
+
+        // The parameters are written to memory allocated in the guest
+        assert!(generated.contains("i.realloc(\"cabi_realloc\").Call(ctx, 0, 0, 8, 160)"));
+        assert!(generated.contains("ptr0 := uint32(result0[0])"));
+        assert!(generated.contains("i.guest.WriteUint64Le(ptr0+0, uint64("));
+        assert!(generated.contains("i.guest.WriteUint64Le(ptr0+152, uint64("));
+
+        // Only the pointer is passed to the guest
+        assert!(generated.contains("i.callExport(ctx, \"sum\", uint64(ptr0))"));
+    }
+
+    #[test]
+    fn test_generate_function_record_result_via_memory() {
+        // A record of ten fields flattens to more than the single flat result
+        // of the Canonical ABI, so the guest returns a pointer to it instead
+        let mut resolve = Resolve::new();
+        let record_id = resolve.types.alloc(TypeDef {
+            name: Some("stats".to_string()),
+            kind: TypeDefKind::Record(Record {
+                fields: vec![
+                    Field {
+                        name: "name".to_string(),
+                        ty: Type::String,
+                        docs: Default::default(),
+                    },
+                    Field {
+                        name: "tiny".to_string(),
+                        ty: Type::U8,
+                        docs: Default::default(),
+                    },
+                    Field {
+                        name: "signed-tiny".to_string(),
+                        ty: Type::S8,
+                        docs: Default::default(),
+                    },
+                    Field {
+                        name: "small".to_string(),
+                        ty: Type::U16,
+                        docs: Default::default(),
+                    },
+                    Field {
+                        name: "signed-small".to_string(),
+                        ty: Type::S16,
+                        docs: Default::default(),
+                    },
+                    Field {
+                        name: "count".to_string(),
+                        ty: Type::S32,
+                        docs: Default::default(),
+                    },
+                    Field {
+                        name: "total".to_string(),
+                        ty: Type::U64,
+                        docs: Default::default(),
+                    },
+                    Field {
+                        name: "delta".to_string(),
+                        ty: Type::S64,
+                        docs: Default::default(),
+                    },
+                    Field {
+                        name: "ratio".to_string(),
+                        ty: Type::F32,
+                        docs: Default::default(),
+                    },
+                    Field {
+                        name: "mean".to_string(),
+                        ty: Type::F64,
+                        docs: Default::default(),
+                    },
+                ],
+            }),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+
+        let func = Function {
+            name: "stats".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: vec![],
+            result: Some(Type::Id(record_id)),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        let world = World {
+            name: "test-world".to_string(),
+            imports: [].into(),
+            exports: [(
+                WorldKey::Name("stats".to_string()),
+                WorldItem::Function(func.clone()),
+            )]
+            .into(),
+            docs: Default::default(),
+            stability: Default::default(),
+            includes: Default::default(),
+            include_names: Default::default(),
+            package: None,
+        };
+
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let naming = Naming::default();
+        let generator = ExportGenerator::new(ExportConfig {
+            instance: &instance,
+            world: &world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &naming,
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        });
+        let mut tokens = Tokens::new();
+        generator.generate_function(&func, &mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // The guest returns a pointer to the record
+        assert!(generated.contains(") Stats {"));
+        assert!(generated.contains("results0 := raw0[0]"));
+
+        // Every field is read from memory at its offset
+        assert!(generated.contains("ReadByte(uint32(results0 + 8))"));
+        assert!(generated.contains("ReadByte(uint32(results0 + 9))"));
+        assert!(generated.contains("ReadUint16Le(uint32(results0 + 10))"));
+        assert!(generated.contains("ReadUint16Le(uint32(results0 + 12))"));
+        assert!(generated.contains("ReadUint32Le(uint32(results0 + 16))"));
+        assert!(generated.contains("ReadUint64Le(uint32(results0 + 24))"));
+        assert!(generated.contains("ReadUint64Le(uint32(results0 + 32))"));
+        assert!(generated.contains("ReadUint32Le(uint32(results0 + 40))"));
+        assert!(generated.contains("ReadUint64Le(uint32(results0 + 48))"));
+
+        // Narrow signed integers are sign extended
+        assert!(generated.contains(":= int32(int8(value"));
+        assert!(generated.contains(":= int32(int16(value"));
+
+        // The fields are lifted into the record, which is then cleaned up
+        assert!(generated.contains(":= Stats{"));
+        assert!(generated.contains("cabi_post_stats"));
+    }
+
+    #[test]
+    fn test_generate_instance_interface() {
+        let func = Function {
+            name: "add_number".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: vec![("value".to_string(), Type::U32)],
+            result: Some(Type::U32),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        let world = World {
+            name: "test-world".to_string(),
+            imports: [].into(),
+            exports: [(
+                WorldKey::Name("add-number".to_string()),
+                WorldItem::Function(func),
+            )]
+            .into(),
+            docs: Default::default(),
+            stability: Default::default(),
+            includes: Default::default(),
+            include_names: Default::default(),
+            package: None,
+        };
+
+        let resolve = Resolve::new();
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestWorldInstance");
+
+        let naming = Naming::default();
+        let generator = ExportGenerator::new(ExportConfig {
+            instance: &instance,
+            world: &world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &naming,
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        });
+        let mut tokens = Tokens::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // The interface has every method of the instance, which implements it
+        assert!(generated.contains("type ITestWorldInstance interface {"));
+        assert!(generated.contains("Close(ctx context.Context) error"));
+        assert!(generated.contains("Reset(ctx context.Context) error"));
+        assert!(generated.contains("MemorySize() uint64"));
+        assert!(generated.contains("MemoryStats() MemoryStats"));
+        assert!(generated.contains("AddNumber(ctx context.Context, value uint32) uint32"));
+        assert!(generated.contains("var _ ITestWorldInstance = (*TestWorldInstance)(nil)"));
+
+        // The methods follow the interface
+        let interface = generated.find("type ITestWorldInstance").unwrap();
+        let method = generated
+            .find("func (i *TestWorldInstance) AddNumber(")
+            .unwrap();
+        assert!(interface < method);
+    }
+
+    #[test]
+    fn test_generate_function_record_without_post_return() {
+        // Records of primitives don't allocate, so the guest doesn't export a
+        // `cabi_post_*` function for them
+        let mut resolve = Resolve::new();
+        let record_id = resolve.types.alloc(TypeDef {
+            name: Some("mixed".to_string()),
+            kind: TypeDefKind::Record(Record {
+                fields: [
+                    ("small", Type::U8),
+                    ("medium", Type::U16),
+                    ("large", Type::U32),
+                ]
+                .into_iter()
+                .map(|(name, ty)| Field {
+                    name: name.to_string(),
+                    ty,
+                    docs: Default::default(),
+                })
+                .collect(),
+            }),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+
+        let func = Function {
+            name: "mix".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: vec![],
+            result: Some(Type::Id(record_id)),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        let world = World {
+            name: "test-world".to_string(),
+            imports: [].into(),
+            exports: [(
+                WorldKey::Name("mix".to_string()),
+                WorldItem::Function(func.clone()),
+            )]
+            .into(),
+            docs: Default::default(),
+            stability: Default::default(),
+            includes: Default::default(),
+            include_names: Default::default(),
+            package: None,
+        };
+
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let naming = Naming::default();
+        let generator = ExportGenerator::new(ExportConfig {
+            instance: &instance,
+            world: &world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &naming,
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        });
+        let mut tokens = Tokens::new();
+        generator.generate_function(&func, &mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // The fields are read at their 2-byte aligned offsets
+        assert!(generated.contains("ReadByte(uint32(results0 + 0))"));
+        assert!(generated.contains("ReadUint16Le(uint32(results0 + 2))"));
+        assert!(generated.contains("ReadUint32Le(uint32(results0 + 4))"));
+        assert!(!generated.contains("cabi_post_mix"));
+    }
+
+    #[test]
+    fn test_generate_function_list_of_over_aligned_records() {
+        // The `u64` field makes the record 8-byte aligned, so the list must be
+        // allocated with that alignment rather than the 4 of a wasm32 pointer
+        let mut resolve = Resolve::new();
+        let record_id = resolve.types.alloc(TypeDef {
+            name: Some("sample".to_string()),
+            kind: TypeDefKind::Record(Record {
+                fields: [("weight", Type::U32), ("id", Type::U64)]
+                    .into_iter()
+                    .map(|(name, ty)| Field {
+                        name: name.to_string(),
+                        ty,
+                        docs: Default::default(),
+                    })
+                    .collect(),
+            }),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+        let list_id = resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::List(Type::Id(record_id)),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+
+        let func = Function {
+            name: "total".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: vec![("samples".to_string(), Type::Id(list_id))],
+            result: Some(Type::U64),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        let world = World {
+            name: "test-world".to_string(),
+            imports: [].into(),
+            exports: [(
+                WorldKey::Name("total".to_string()),
+                WorldItem::Function(func.clone()),
+            )]
+            .into(),
+            docs: Default::default(),
+            stability: Default::default(),
+            includes: Default::default(),
+            include_names: Default::default(),
+            package: None,
+        };
+
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let naming = Naming::default();
+        let generator = ExportGenerator::new(ExportConfig {
+            instance: &instance,
+            world: &world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &naming,
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        });
+        let mut tokens = Tokens::new();
+        generator.generate_function(&func, &mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Elements are 16 bytes with 8-byte alignment, even when empty
+        assert!(generated.contains("Call(ctx, 0, 0, 8, len"));
+        assert!(generated.contains(" * 16)"));
+        assert!(generated.contains(" := uint64(8)"));
+
+        // The `u64` is written after 4 bytes of padding
+        assert!(generated.contains("i.guest.WriteUint32Le(base+0, "));
+        assert!(generated.contains("i.guest.WriteUint64Le(base+8, uint64("));
+    }
+
+    #[test]
+    fn test_generate_function_list_of_tuples() {
+        let mut resolve = Resolve::new();
+        let tuple_id = resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::Tuple(Tuple {
+                types: vec![Type::U32, Type::String],
+            }),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+        let list_id = resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::List(Type::Id(tuple_id)),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+
+        let func = Function {
+            name: "sorted".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: vec![("pairs".to_string(), Type::Id(list_id))],
+            result: Some(Type::Id(list_id)),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        let world = World {
+            name: "test-world".to_string(),
+            imports: [].into(),
+            exports: [(
+                WorldKey::Name("sorted".to_string()),
+                WorldItem::Function(func.clone()),
+            )]
+            .into(),
+            docs: Default::default(),
+            stability: Default::default(),
+            includes: Default::default(),
+            include_names: Default::default(),
+            package: None,
+        };
+
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let naming = Naming::default();
+        let generator = ExportGenerator::new(ExportConfig {
+            instance: &instance,
+            world: &world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &naming,
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        });
+        let mut tokens = Tokens::new();
+        generator.generate_function(&func, &mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Tuples in lists are structs with a field per element
+        assert!(generated.contains("pairs []struct{ F0 uint32; F1 string },"));
+        assert!(generated.contains(") []struct{ F0 uint32; F1 string } {"));
+
+        // Elements are lowered field by field, 12 bytes apart
+        assert!(generated.contains("e.F0"));
+        assert!(generated.contains("e.F1"));
+        assert!(generated.contains(" * 12)"));
+
+        // And lifted at the offset of each field within the stride
+        assert!(generated.contains("make([]struct{ F0 uint32; F1 string }, len"));
+        assert!(generated.contains(" * 12"));
+        assert!(generated.contains("ReadUint32Le(uint32(base + 4))"));
+        assert!(generated.contains("ReadUint32Le(uint32(base + 8))"));
+        assert!(generated.contains(" := struct{ F0 uint32; F1 string }{"));
+        assert!(generated.contains("F0: "));
+        assert!(generated.contains("F1: "));
+    }
+
+    #[test]
+    fn test_generate_function_pointer_options() {
+        let mut resolve = Resolve::new();
+        let option_string = resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::Option(Type::String),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+        let option_u32 = resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::Option(Type::U32),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+
+        let func = Function {
+            name: "age".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: vec![
+                ("name".to_string(), Type::Id(option_string)),
+                ("fallback".to_string(), Type::Id(option_u32)),
+            ],
+            result: Some(Type::Id(option_u32)),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        let world = World {
+            name: "test-world".to_string(),
+            imports: [].into(),
+            exports: [(
+                WorldKey::Name("age".to_string()),
+                WorldItem::Function(func.clone()),
+            )]
+            .into(),
+            docs: Default::default(),
+            stability: Default::default(),
+            includes: Default::default(),
+            include_names: Default::default(),
+            package: None,
+        };
+
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let naming = Naming::default();
+        let generator = ExportGenerator::new(ExportConfig {
+            instance: &instance,
+            world: &world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &naming,
+            manual_cleanup: false,
+            option_style: OptionStyle::Pointer,
+        });
+        let mut tokens = Tokens::new();
+        generator.generate_function(&func, &mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Options are pointers in the signature, rather than `T, bool`
+        assert!(generated.contains("name *string,"));
+        assert!(generated.contains("fallback *uint32,"));
+        assert!(generated.contains(") *uint32 {"));
+
+        // Parameters are lowered from what they point to, unless they're nil
+        assert!(generated.contains("if arg0 != nil {"));
+        assert!(generated.contains("variantPayload := *arg0"));
+
+        // And the result is only allocated for `Some`
+        assert!(generated.contains("*uint32\n"));
+        assert!(generated.contains(" = &value"));
+        assert!(!generated.contains(", bool"));
+    }
+
+    #[test]
+    fn test_generate_function_option_tuple() {
+        let mut resolve = Resolve::new();
+        let tuple = resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::Tuple(Tuple {
+                types: vec![Type::U32, Type::String],
+            }),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+        let option_tuple = resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::Option(Type::Id(tuple)),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+
+        let func = Function {
+            name: "tag".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: vec![("label".to_string(), Type::Id(option_tuple))],
+            result: Some(Type::Id(option_tuple)),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        let world = World {
+            name: "test-world".to_string(),
+            imports: [].into(),
+            exports: [(
+                WorldKey::Name("tag".to_string()),
+                WorldItem::Function(func.clone()),
+            )]
+            .into(),
+            docs: Default::default(),
+            stability: Default::default(),
+            includes: Default::default(),
+            include_names: Default::default(),
+            package: None,
+        };
+
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let naming = Naming::default();
+        let generator = ExportGenerator::new(ExportConfig {
+            instance: &instance,
+            world: &world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &naming,
+            manual_cleanup: false,
+            option_style: OptionStyle::Pointer,
+        });
+        let mut tokens = Tokens::new();
+        generator.generate_function(&func, &mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // The tuple is a struct, both as the parameter and the result
+        assert!(generated.contains("label *struct{ F0 uint32; F1 string },"));
+        assert!(generated.contains(") *struct{ F0 uint32; F1 string } {"));
+
+        // `None` is nil, and the elements of `Some` are lowered from the struct
+        assert!(generated.contains("if arg0 != nil {"));
+        assert!(generated.contains("variantPayload := *arg0"));
+        assert!(generated.contains("variantPayload.F0"));
+        assert!(generated.contains("variantPayload.F1"));
+    }
+
+    #[test]
+    fn test_generate_function_result_tuple() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "entries.wit",
+                r#"
+                package arcjet:entries;
+
+                world entries {
+                  export split: func(entry: string) -> result<tuple<u32, string>, string>;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "entries")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let config = ExportConfig {
+            instance: &instance,
+            world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &Naming::default(),
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        };
+        let generator = ExportGenerator::new(config);
+        let mut tokens = Tokens::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // The tuple is returned as a struct along with the error
+        assert!(generated.contains(") (struct{ F0 uint32; F1 string }, error) {"));
+
+        // The ok case lifts the elements of the tuple into the struct
+        assert!(generated.contains("struct{ F0 uint32; F1 string }{"));
+        assert!(generated.contains("F0: "));
+        assert!(generated.contains("F1: "));
+
+        // And the err case the message
+        assert!(generated.contains("case 1:"));
+        assert!(generated.contains("= errors.New("));
+    }
+
+    #[test]
+    fn test_generate_function_wide_flags() {
+        let mut resolve = Resolve::new();
+        let perms = resolve.types.alloc(TypeDef {
+            name: Some("perms".to_string()),
+            kind: TypeDefKind::Flags(Flags {
+                flags: (0..40)
+                    .map(|i| Flag {
+                        name: format!("f{i}"),
+                        docs: Default::default(),
+                    })
+                    .collect(),
+            }),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+
+        let func = Function {
+            name: "echo".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: vec![("perms".to_string(), Type::Id(perms))],
+            result: Some(Type::Id(perms)),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        let world = World {
+            name: "test-world".to_string(),
+            imports: [].into(),
+            exports: [(
+                WorldKey::Name("echo".to_string()),
+                WorldItem::Function(func.clone()),
+            )]
+            .into(),
+            docs: Default::default(),
+            stability: Default::default(),
+            includes: Default::default(),
+            include_names: Default::default(),
+            package: None,
+        };
+
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let naming = Naming::default();
+        let generator = ExportGenerator::new(ExportConfig {
+            instance: &instance,
+            world: &world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &naming,
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        });
+        let mut tokens = Tokens::new();
+        generator.generate_function(&func, &mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        assert!(generated.contains("perms Perms,"));
+        assert!(generated.contains(") Perms {"));
+
+        // 40 flags are lowered to two i32s, and lifted by joining them again
+        assert!(generated.contains("_0 := uint32(perms)"));
+        assert!(generated.contains("_1 := uint32(perms >> 32)"));
+        assert!(generated.contains("Perms(uint64(uint32("));
+        assert!(generated.contains(")) | uint64(uint32("));
+        assert!(generated.contains("))<<32)"));
+    }
+
+    #[test]
+    fn test_generate_function_list_of_flags_and_enums() {
+        let flags = (0..40)
+            .map(|i| format!("f{i}"))
+            .collect::<Vec<_>>()
+            .join(", ");
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "palette.wit",
+                &format!(
+                    r#"
+                    package arcjet:palette;
+
+                    world palette {{
+                      flags perms {{ {flags} }}
+
+                      enum color {{ red, green, blue }}
+
+                      export grant: func(perms: list<perms>) -> list<perms>;
+                      export shades: func(colors: list<color>) -> list<color>;
+                    }}
+                    "#
+                ),
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "palette")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let config = ExportConfig {
+            instance: &instance,
+            world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &Naming::default(),
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        };
+        let generator = ExportGenerator::new(config);
+        let mut tokens = Tokens::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        assert!(generated.contains("perms []Perms,"));
+        assert!(generated.contains(") []Perms {"));
+        assert!(generated.contains("colors []Color,"));
+        assert!(generated.contains(") []Color {"));
+
+        // 40 flags take two i32s per element, which are joined again when lifted
+        assert!(generated.contains("* uint64(8)"));
+        assert!(
+            generated
+                .lines()
+                .any(|line| line.trim().starts_with("base := base") && line.ends_with(" * 8"))
+        );
+        assert!(generated.contains("Perms(uint64(uint32("));
+        assert!(generated.contains("))<<32)"));
+
+        // 3 cases fit in a byte per element
+        assert!(generated.contains("* uint64(1)"));
+        assert!(
+            generated
+                .lines()
+                .any(|line| line.trim().starts_with("base := base") && line.ends_with(" * 1"))
+        );
+        assert!(generated.contains("case 0:"));
+        assert!(generated.contains("= Red"));
+        assert!(generated.contains("= Blue"));
+        assert!(generated.contains(r#"panic(errors.New("invalid enum discriminant"))"#));
+    }
+
+    #[test]
+    fn test_generate_function_empty_results() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "empty.wit",
+                r#"
+                package arcjet:empty;
+
+                world empty {
+                  export name: func() -> string;
+                  export tags: func() -> list<string>;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "empty")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let config = ExportConfig {
+            instance: &instance,
+            world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &Naming::default(),
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        };
+        let generator = ExportGenerator::new(config);
+        let mut tokens = Tokens::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Strings are read through readBytes, which doesn't touch memory when
+        // they're empty, whatever their pointer, and empty lists have no
+        // elements to read
+        assert!(!generated.contains(".Read("));
+        assert!(generated.contains("for idx"));
+        assert_eq!(
+            generated
+                .lines()
+                .filter(|line| line.contains(" := readBytes(i.guest, "))
+                .count(),
+            2
+        );
+    }
+
+    #[test]
+    fn test_generate_function_builtin_names() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "builtins.wit",
+                r#"
+                package arcjet:builtins;
+
+                world builtins {
+                  export len: func(items: list<string>) -> u32;
+                  export new: func() -> string;
+                  export close: func();
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "builtins")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let config = ExportConfig {
+            instance: &instance,
+            world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &Naming::default(),
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        };
+        let generator = ExportGenerator::new(config);
+        let mut tokens = Tokens::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Methods may be named after builtins
+        assert!(generated.contains("func (i *TestInstance) Len("));
+        assert!(generated.contains("func LenArgSize("));
+        assert!(generated.contains("func (i *TestInstance) New("));
+        assert!(generated.contains(r#"i.callExport(ctx, "len", "#));
+        assert!(generated.contains(r#"i.callExport(ctx, "new", "#));
+
+        // But not after the methods every instance has
+        assert!(!generated.contains("func (i *TestInstance) Close("));
+        assert!(generated.contains("func (i *TestInstance) CallClose("));
+        assert!(generated.contains(r#"i.callExport(ctx, "close", "#));
+    }
+
+    #[test]
+    fn test_generate_arg_size() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "entries.wit",
+                r#"
+                package arcjet:entries;
+
+                world entries {
+                  record entry {
+                    name: string,
+                    tags: list<string>,
+                    id: u32,
+                  }
+
+                  export add: func(entries: list<entry>, source: string) -> u32;
+                  export double: func(n: u32) -> u32;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "entries")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let config = ExportConfig {
+            instance: &instance,
+            world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &Naming::default(),
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        };
+        let generator = ExportGenerator::new(config);
+        let mut tokens = Tokens::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Every list and string is counted, recursing into list elements
+        assert!(generated.contains("func AddArgSize("));
+        assert!(generated.contains("entries []Entry,"));
+        assert!(generated.contains(") uint64 {"));
+        assert!(generated.contains("size += uint64(len(entries)) * 20"));
+        assert!(generated.contains("for _, e0 := range entries {"));
+        assert!(generated.contains("size += uint64(len(e0.Name))"));
+        assert!(generated.contains("size += uint64(len(e0.Tags)) * 8"));
+        assert!(generated.contains("for _, e1 := range e0.Tags {"));
+        assert!(generated.contains("size += uint64(len(e1))"));
+        assert!(generated.contains("size += uint64(len(source))"));
+
+        // Functions which don't allocate don't have one
+        assert!(!generated.contains("DoubleArgSize"));
+    }
+
+    #[test]
+    fn test_generate_function_validations() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "greetings.wit",
+                r#"
+                package arcjet:greetings;
+
+                world greetings {
+                  /// Greets someone by name.
+                  ///
+                  /// @go-validate:nonempty name
+                  export greet: func(name: string, greeting: string) -> result<string, string>;
+                  /// @go-validate:nonempty name
+                  export shout: func(name: string) -> string;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "greetings")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let config = ExportConfig {
+            instance: &instance,
+            world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &Naming::default(),
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        };
+        let generator = ExportGenerator::new(config);
+        let mut tokens = Tokens::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Constrained parameters are checked before calling into the guest
+        assert!(generated.contains("if name == \"\" {"));
+        assert!(generated.contains("return zero, errors.New(\"Greet: name must not be empty\")"));
+        assert!(!generated.contains("if greeting == \"\" {"));
+
+        // Functions without an error result panic instead
+        assert!(generated.contains("panic(errors.New(\"Shout: name must not be empty\"))"));
+    }
+
+    #[test]
+    #[should_panic(expected = "only applies to `string` parameters")]
+    fn test_generate_function_validations_non_string() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "counts.wit",
+                r#"
+                package arcjet:counts;
+
+                world counts {
+                  /// @go-validate:nonempty count
+                  export double: func(count: u32) -> u32;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "counts")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let config = ExportConfig {
+            instance: &instance,
+            world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &Naming::default(),
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        };
+        let generator = ExportGenerator::new(config);
+        let mut tokens = Tokens::new();
+        generator.format_into(&mut tokens);
+    }
+
+    #[test]
+    fn test_generate_function_result_params() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "outcomes.wit",
+                r#"
+                package arcjet:outcomes;
+
+                world outcomes {
+                  export handle: func(outcome: result<u32, string>) -> u32;
+                  export check: func(outcome: result<_, string>) -> bool;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "outcomes")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let config = ExportConfig {
+            instance: &instance,
+            world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &Naming::default(),
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        };
+        let generator = ExportGenerator::new(config);
+        let mut tokens = Tokens::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Results are passed as a value and an error, like they're returned
+        assert!(
+            generated
+                .contains("Handle(ctx context.Context, outcome uint32, outcomeErr error) uint32")
+        );
+        assert!(generated.contains("arg0Err := outcomeErr"));
+        assert!(generated.contains("if arg0Err != nil {"));
+        assert!(generated.contains("variantPayload := arg0Err.Error()"));
+        assert!(generated.contains("variantPayload := arg0"));
+
+        // Both cases are lowered to the same core Wasm values
+        assert!(generated.contains("_0 uint64"));
+        assert!(generated.contains("_0 = 0"));
+        assert!(generated.contains("_0 = 1"));
+
+        // Results without a value are passed as an error
+        assert!(generated.contains("Check(ctx context.Context, outcome error) bool"));
+        assert!(generated.contains("size += uint64(len(outcomeErr.Error()))"));
+        assert!(generated.contains("size += uint64(len(outcome.Error()))"));
+    }
+
+    #[test]
+    fn test_generate_function_array_fields() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "tokens.wit",
+                r#"
+                package arcjet:tokens;
+
+                world tokens {
+                  record token {
+                    /// @go-array:16
+                    id: list<u8>,
+                    name: string,
+                  }
+
+                  export renew: func(token: token) -> token;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "tokens")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let instance = GoIdentifier::public("TestInstance");
+
+        let config = ExportConfig {
+            instance: &instance,
+            world,
+            resolve: &resolve,
+            sizes: &sizes,
+            naming: &Naming::default(),
+            manual_cleanup: false,
+            option_style: OptionStyle::Pair,
+        };
+        let generator = ExportGenerator::new(config);
+        let mut tokens = Tokens::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Arrays are lowered as slices
+        assert!(generated.contains(".Id[:]"));
+
+        // and lifted from slices of exactly their length
+        assert!(generated.contains("!= 16 {"));
+        assert!(
+            generated
+                .contains("panic(fmt.Errorf(\"expected 16 bytes for token.id, but got %d\", len(")
+        );
+        assert!(generated.contains("Id: [16]byte("));
+        assert!(generated.contains("Name: "));
     }
 }
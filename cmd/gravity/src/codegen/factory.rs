@@ -7,8 +7,19 @@ use crate::{
     go::{
         GoIdentifier, comment,
         imports::{
-            CONTEXT_CONTEXT, ERRORS_NEW, WAZERO_API_MEMORY, WAZERO_API_MODULE,
-            WAZERO_COMPILED_MODULE, WAZERO_NEW_MODULE_CONFIG, WAZERO_NEW_RUNTIME, WAZERO_RUNTIME,
+            BINARY_LITTLE_ENDIAN, BYTES_CLONE, BYTES_NEW_READER, CONTEXT_BACKGROUND,
+            CONTEXT_CONTEXT, ERRORS_NEW, FMT_ERRORF, FMT_SPRINTF, GZIP_NEW_READER,
+            HEX_ENCODE_TO_STRING, IO_READ_ALL, IO_READER, IO_WRITE_STRING, IO_WRITER,
+            RUNTIME_ADD_CLEANUP, RUNTIME_CLEANUP, SLICES_GROW, SLOG_LOGGER, STRINGS_CONTAINS,
+            SYNC_ATOMIC_BOOL, SYNC_MUTEX, SYNC_ONCE_VALUES, TIME_TIME,
+            WASI_SNAPSHOT_PREVIEW1_INSTANTIATE, WASI_SNAPSHOT_PREVIEW1_MODULE_NAME,
+            WAZERO_API_FUNCTION, WAZERO_API_MEMORY, WAZERO_API_MODULE, WAZERO_COMPILATION_CACHE,
+            WAZERO_COMPILED_MODULE, WAZERO_EXPERIMENTAL_LINEAR_MEMORY,
+            WAZERO_EXPERIMENTAL_MEMORY_ALLOCATOR_FUNC, WAZERO_EXPERIMENTAL_WITH_MEMORY_ALLOCATOR,
+            WAZERO_MODULE_CONFIG, WAZERO_NEW_MODULE_CONFIG, WAZERO_NEW_RUNTIME_CONFIG,
+            WAZERO_NEW_RUNTIME_CONFIG_COMPILER, WAZERO_NEW_RUNTIME_CONFIG_INTERPRETER,
+            WAZERO_NEW_RUNTIME_WITH_CONFIG, WAZERO_RUNTIME, WAZERO_RUNTIME_CONFIG,
+            WAZERO_SYS_CLOCK_RESOLUTION,
         },
     },
 };
@@ -23,12 +34,23 @@ pub struct FactoryConfig<'a> {
 /// Generator for factory and instance types
 pub struct FactoryGenerator<'a> {
     config: FactoryConfig<'a>,
+    compressed_wasm: bool,
 }
 
 impl<'a> FactoryGenerator<'a> {
     /// Create a new factory generator with the given config.
     pub fn new(config: FactoryConfig<'a>) -> Self {
-        Self { config }
+        Self {
+            config,
+            compressed_wasm: false,
+        }
+    }
+
+    /// Set whether the WebAssembly bytes are gzip-compressed, and decompressed
+    /// when the first factory is created.
+    pub fn with_compressed_wasm(mut self, compressed_wasm: bool) -> Self {
+        self.compressed_wasm = compressed_wasm;
+        self
     }
 
     /// Get the instance name from the analyzed imports.
@@ -36,10 +58,50 @@ impl<'a> FactoryGenerator<'a> {
         &self.config.analyzed_imports.instance_name
     }
 
+    /// Generate the `callError` helper method, wrapping traps in typed errors.
+    fn generate_call_error(&self, tokens: &mut Tokens<Go>) {
+        let instance_name = self.instance_name();
+        quote_in! { *tokens =>
+            $(comment(&[
+                "ErrStackOverflow is returned when a call into the guest exceeds the maximum",
+                "call depth of the runtime, such as with unbounded recursion. The limit is",
+                "fixed by wazero, so guests must bound their recursion to stay within it.",
+            ]))
+            var ErrStackOverflow = $ERRORS_NEW("guest stack overflow")
+            $['\n']
+            $(comment(&[
+                "ErrMemoryLimitExceeded is returned when a call into the guest fails after it",
+                "tried to grow its memory beyond the limit set with WithMaxMemoryPages.",
+            ]))
+            var ErrMemoryLimitExceeded = $ERRORS_NEW("guest memory limit exceeded")
+            $['\n']
+            $(comment(&[
+                "callError wraps the error of a failed call into the guest with the typed",
+                "error of its trap, if there is one.",
+            ]))
+            func (i *$instance_name) callError(err error) error {
+                if $STRINGS_CONTAINS(err.Error(), "wasm error: stack overflow") {
+                    return $FMT_ERRORF("%w: %w", ErrStackOverflow, err)
+                }
+                if i.memory.exceeded {
+                    return $FMT_ERRORF("%w: %w", ErrMemoryLimitExceeded, err)
+                }
+                return err
+            }
+            $['\n']
+        };
+    }
+
     /// Generate the `writeString` helper function.
     fn generate_write_string(&self, tokens: &mut Tokens<Go>) {
         // Add writeString helper function for interface string returns
         quote_in! { *tokens =>
+            $(comment(&[
+                "ErrGuestAllocFailed is returned when the guest fails to allocate memory for",
+                "a value, which its realloc function signals by returning a null pointer.",
+            ]))
+            var ErrGuestAllocFailed = $ERRORS_NEW("guest failed to allocate memory")
+            $['\n']
             $(comment(&[
                 "writeString will put a Go string into the Wasm memory following the Component",
                 "Model calling conventions, such as allocating memory with the realloc function",
@@ -59,6 +121,9 @@ impl<'a> FactoryGenerator<'a> {
                     return 1, 0, err
                 }
                 ptr := results[0]
+                if ptr == 0 {
+                    return 1, 0, ErrGuestAllocFailed
+                }
                 ok := memory.Write(uint32(ptr), []byte(s))
                 if !ok {
                     return 1, 0, $ERRORS_NEW("failed to write string to wasm memory")
@@ -69,6 +134,390 @@ impl<'a> FactoryGenerator<'a> {
         };
     }
 
+    /// Generate the `recoverHostPanic` helper function, deferred by every
+    /// host function.
+    fn generate_recover_host_panic(&self, tokens: &mut Tokens<Go>) {
+        quote_in! { *tokens =>
+            $(comment(&[
+                "ErrHostPanic is returned when a host function panics while the guest calls",
+                "it. The panic is recovered and traps the guest, so the call into the guest",
+                "fails instead of crashing the host.",
+            ]))
+            var ErrHostPanic = $ERRORS_NEW("host function panicked")
+            $['\n']
+            $(comment(&[
+                "recoverHostPanic recovers a panic of the method of the host implementation",
+                "impl, and traps the guest with ErrHostPanic instead.",
+            ]))
+            func recoverHostPanic(impl any, method string) {
+                r := recover()
+                if r == nil {
+                    return
+                }
+                if err, ok := r.(error); ok {
+                    panic($FMT_ERRORF("%w: %T.%s: %w", ErrHostPanic, impl, method, err))
+                }
+                panic($FMT_ERRORF("%w: %T.%s: %v", ErrHostPanic, impl, method, r))
+            }
+            $['\n']
+        };
+    }
+
+    /// Generate the `argArena` helper type, used by `WithArgArena`.
+    fn generate_arg_arena(&self, tokens: &mut Tokens<Go>) {
+        quote_in! { *tokens =>
+            $(comment(&[
+                "argArena is a bump allocator for the arguments of calls into the guest. It",
+                "hands out memory from a single region of guest memory which every call",
+                "reuses, so most calls don't need the guest's realloc function at all.",
+                "Allocations that don't fit in the region fall back to realloc, and the",
+                "region grows to fit them when the arena is reset after the call.",
+            ]))
+            type argArena struct {
+                $WAZERO_API_FUNCTION
+                ptr    uint64
+                size   uint64
+                offset uint64
+            }
+            $['\n']
+            $(comment(&["Call allocates memory with the signature of the guest's realloc function."]))
+            func (a *argArena) Call(ctx $CONTEXT_CONTEXT, params ...uint64) ([]uint64, error) {
+                align, size := params[2], params[3]
+                start := (a.offset + align - 1) &^ (align - 1)
+                a.offset = start + size
+                if a.offset > a.size {
+                    return a.Function.Call(ctx, params...)
+                }
+                return []uint64{a.ptr + start}, nil
+            }
+            $['\n']
+            $(comment(&[
+                "reset releases the arguments of the last call all at once, growing the",
+                "region if they didn't fit in it.",
+            ]))
+            func (a *argArena) reset(ctx $CONTEXT_CONTEXT) {
+                if a == nil {
+                    return
+                }
+                needed := a.offset
+                a.offset = 0
+                if needed <= a.size {
+                    return
+                }
+                $(comment(&[
+                    "Nothing points into the region between calls, so it can be moved. If",
+                    "growing fails, the next call falls back to realloc again.",
+                ]))
+                if results, err := a.Function.Call(ctx, a.ptr, a.size, 8, needed); err == nil && results[0] != 0 {
+                    a.ptr, a.size = results[0], needed
+                }
+            }
+            $['\n']
+        };
+    }
+
+    /// Generate the `limitedMemory` helper type, backing the memory of an
+    /// instance to enforce `WithMaxMemoryPages`.
+    fn generate_limited_memory(&self, tokens: &mut Tokens<Go>) {
+        quote_in! { *tokens =>
+            $(comment(&[
+                "limitedMemory is the linear memory of an instance, which refuses to grow",
+                "beyond its limit instead of exhausting the memory of the host.",
+            ]))
+            type limitedMemory struct {
+                buf   []byte
+                limit uint64
+                $(comment(&[
+                    "exceeded is set once the guest tries to grow beyond the limit, and is",
+                    "cleared at the start of every call into the guest.",
+                ]))
+                exceeded bool
+            }
+            $['\n']
+            $(comment(&["Reallocate grows the memory to size bytes, unless that exceeds the limit."]))
+            func (m *limitedMemory) Reallocate(size uint64) []byte {
+                if size > m.limit {
+                    m.exceeded = true
+                    return nil
+                }
+                if n := int(size) - len(m.buf); n > 0 {
+                    m.buf = $SLICES_GROW(m.buf, n)[:size]
+                }
+                return m.buf
+            }
+            $['\n']
+            $(comment(&["Free releases the memory once the instance is closed."]))
+            func (m *limitedMemory) Free() {
+                m.buf = nil
+            }
+            $['\n']
+        };
+    }
+
+    /// Generate the factory options, set with a `FactoryOption`.
+    fn generate_options(&self, tokens: &mut Tokens<Go>) {
+        quote_in! { *tokens =>
+            $(comment(&["factoryConfig is the configuration of a factory, set with a FactoryOption."]))
+            type factoryConfig struct {
+                argArena         bool
+                callLogger       *$SLOG_LOGGER
+                compilationCache $WAZERO_COMPILATION_CACHE
+                compiledModule   $WAZERO_COMPILED_MODULE
+                errorWriter      $IO_WRITER
+                leakLogger       *$SLOG_LOGGER
+                maxMemoryPages   uint32
+                moduleConfigs    []func($WAZERO_MODULE_CONFIG) $WAZERO_MODULE_CONFIG
+                newRuntimeConfig func() $WAZERO_RUNTIME_CONFIG
+                postInstantiate  []func($CONTEXT_CONTEXT, $WAZERO_API_MODULE) error
+                reset            bool
+                startFunction    string
+                wrapMemory       func(Memory) Memory
+            }
+            $['\n']
+            $(comment(&[
+                "memory returns the memory of the module which the bindings read values from",
+                "and write values to, wrapped as set with WithMemory.",
+            ]))
+            func (cfg *factoryConfig) memory(module $WAZERO_API_MODULE) $WAZERO_API_MEMORY {
+                memory := module.Memory()
+                if cfg.wrapMemory == nil || memory == nil {
+                    return memory
+                }
+                return wrappedMemory{Memory: memory, wrapped: cfg.wrapMemory(memory)}
+            }
+            $['\n']
+            $(comment(&["FactoryOption configures a factory."]))
+            type FactoryOption func(*factoryConfig)
+            $['\n']
+            $(comment(&[
+                "WithArgArena makes instances allocate the arguments of each call from a",
+                "region of guest memory which is released all at once after the call,",
+                "instead of calling the guest's realloc function for every argument.",
+                "The guest must tolerate this, as it must not free the memory of its",
+                "arguments itself.",
+            ]))
+            func WithArgArena(enabled bool) FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.argArena = enabled
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "WithInterpreter makes the factory run the module with wazero's interpreter,",
+                "which is slower than the compiler but works on every platform.",
+            ]))
+            func WithInterpreter() FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.newRuntimeConfig = $WAZERO_NEW_RUNTIME_CONFIG_INTERPRETER
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "WithCompiler makes the factory compile the module with wazero's optimizing",
+                "compiler, which isn't supported on every platform. Without this option or",
+                "WithInterpreter, the compiler is used where it's supported, falling back to",
+                "the interpreter otherwise.",
+            ]))
+            func WithCompiler() FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.newRuntimeConfig = $WAZERO_NEW_RUNTIME_CONFIG_COMPILER
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "WithCompilationCache makes the factory's runtime share compiled modules with",
+                "other runtimes using cache, such as to reuse the module compiled by another",
+                "factory, or to keep compiled modules on disk with",
+                "wazero.NewCompilationCacheWithDir.",
+            ]))
+            func WithCompilationCache(cache $WAZERO_COMPILATION_CACHE) FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.compilationCache = cache
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "WithCompiledModule makes the factory instantiate module instead of compiling",
+                "its own, such as the CompiledModule of another factory. module must have been",
+                "compiled by a runtime sharing the factory's compilation cache, set with",
+                "WithCompilationCache, and must outlive the factory.",
+            ]))
+            func WithCompiledModule(module $WAZERO_COMPILED_MODULE) FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.compiledModule = module
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "WithReset makes instances keep a copy of their memory from before their",
+                "start functions run, so they can be restored to it with Reset.",
+            ]))
+            func WithReset(enabled bool) FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.reset = enabled
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "DefaultMaxMemoryPages is the number of 64 KiB pages the memory of each",
+                "instance is limited to by default, which amounts to 256 MiB.",
+            ]))
+            const DefaultMaxMemoryPages = 4096
+            $['\n']
+            $(comment(&[
+                "WithMaxMemoryPages limits the memory of each instance to the given number of",
+                "64 KiB pages, instead of DefaultMaxMemoryPages. Calls failing because the",
+                "guest can't grow its memory any further return ErrMemoryLimitExceeded.",
+            ]))
+            func WithMaxMemoryPages(pages uint32) FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.maxMemoryPages = pages
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "Memory is the guest memory the bindings read values from and write values",
+                "to. The memory of an instance implements it, and can be wrapped with",
+                "WithMemory.",
+            ]))
+            type Memory interface {
+                $(comment(&[
+                    "Read returns a view of byteCount bytes at offset, or false if they're out",
+                    "of range.",
+                ]))
+                Read(offset, byteCount uint32) ([]byte, bool)
+                $(comment(&["Write copies v to offset, or returns false if it's out of range."]))
+                Write(offset uint32, v []byte) bool
+            }
+            $['\n']
+            $(comment(&[
+                "WithMemory makes the bindings read and write guest memory through the Memory",
+                "returned by wrap, such as to instrument every access. wrap is called with",
+                "the memory of each instance once it's instantiated, and with the memory of",
+                "the calling instance whenever a host function accesses it, so any state it",
+                "keeps must be shared between the Memory values it returns. The snapshot",
+                "taken with WithReset is read and restored directly.",
+            ]))
+            func WithMemory(wrap func(Memory) Memory) FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.wrapMemory = wrap
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "WithStartFunction sets the function called when instantiating the module.",
+                "Without this option, the module's `_initialize` function is called if it's",
+                "a reactor, or its `_start` function if it's a command.",
+            ]))
+            func WithStartFunction(name string) FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.startFunction = name
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "WithModuleConfig customizes the configuration each instance is instantiated",
+                "with, such as to set environment variables. configure is called with the",
+                "configuration Gravity would use, including its start functions, and returns",
+                "the one to use instead. Passing the option more than once applies every",
+                "configure function in order.",
+            ]))
+            func WithModuleConfig(configure func($WAZERO_MODULE_CONFIG) $WAZERO_MODULE_CONFIG) FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.moduleConfigs = append(cfg.moduleConfigs, configure)
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "WithPostInstantiate runs initialize with each module right after it's",
+                "instantiated, and its start functions have run, such as to set up the state",
+                "of a guest with a custom memory layout through its exported globals and",
+                "memory. If it fails, the module is closed and Instantiate returns its error.",
+                "Passing the option more than once runs every initialize function in order.",
+                "With WithReset, the functions run again whenever an instance is reset.",
+            ]))
+            func WithPostInstantiate(initialize func(ctx $CONTEXT_CONTEXT, module $WAZERO_API_MODULE) error) FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.postInstantiate = append(cfg.postInstantiate, initialize)
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "WithEnv sets an environment variable of the guest, for guests reading them",
+                "through WASI. Passing the option more than once sets every variable, and",
+                "it's applied in order with WithModuleConfig.",
+            ]))
+            func WithEnv(key, value string) FactoryOption {
+                return WithModuleConfig(func(config $WAZERO_MODULE_CONFIG) $WAZERO_MODULE_CONFIG {
+                    return config.WithEnv(key, value)
+                })
+            }
+            $['\n']
+            $(comment(&[
+                "WithWASIClock makes the guest's wall and monotonic clocks read fixed, for",
+                "guests reading the time through WASI, such as to make their results",
+                "reproducible in tests. It's applied in order with WithModuleConfig.",
+            ]))
+            func WithWASIClock(fixed $TIME_TIME) FactoryOption {
+                return WithModuleConfig(func(config $WAZERO_MODULE_CONFIG) $WAZERO_MODULE_CONFIG {
+                    config = config.WithWalltime(func() (int64, int32) {
+                        return fixed.Unix(), int32(fixed.Nanosecond())
+                    }, $WAZERO_SYS_CLOCK_RESOLUTION(1))
+                    return config.WithNanotime(func() int64 {
+                        return fixed.UnixNano()
+                    }, $WAZERO_SYS_CLOCK_RESOLUTION(1))
+                })
+            }
+            $['\n']
+            $(comment(&[
+                "WithWASIRandSource makes the guest read random bytes from r, for guests",
+                "reading them through WASI, such as to make their results reproducible in",
+                "tests. It's applied in order with WithModuleConfig.",
+            ]))
+            func WithWASIRandSource(r $IO_READER) FactoryOption {
+                return WithModuleConfig(func(config $WAZERO_MODULE_CONFIG) $WAZERO_MODULE_CONFIG {
+                    return config.WithRandSource(r)
+                })
+            }
+            $['\n']
+            $(comment(&[
+                "WithErrorWriter makes exported functions write the message of every error",
+                "the guest returns to w, followed by a newline, in addition to returning it,",
+                "such as to log them.",
+            ]))
+            func WithErrorWriter(w $IO_WRITER) FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.errorWriter = w
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "WithLeakCleanup closes instances which are garbage collected without being",
+                "closed, as a last resort against leaking their module, and logs a warning to",
+                "logger for each of them. It's a safety net for instances which escape by",
+                "mistake, not a substitute for calling Close.",
+            ]))
+            func WithLeakCleanup(logger *$SLOG_LOGGER) FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.leakLogger = logger
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "WithCallLogging logs every call into the guest to logger at the debug level,",
+                "with the name of the function, the little-endian bytes of its core Wasm",
+                "arguments in hex, and what it returned. It is verbose, and meant to debug",
+                "what crosses the boundary to the guest.",
+            ]))
+            func WithCallLogging(logger *$SLOG_LOGGER) FactoryOption {
+                return func(cfg *factoryConfig) {
+                    cfg.callLogger = logger
+                }
+            }
+            $['\n']
+        };
+    }
+
     /// Generate the Factory struct, constructor, and methods.
     fn generate_factory(&self, tokens: &mut Tokens<Go>) {
         let AnalyzedImports {
@@ -79,12 +528,34 @@ impl<'a> FactoryGenerator<'a> {
         } = &self.config.analyzed_imports;
         let wasm_var_name = self.config.wasm_var_name;
         // Build the parameter list
-        let params = self.build_parameters();
+        let params = &self.build_parameters();
+        let args = self.build_arguments();
+        let must_name = &format!("{}Must", String::from(constructor_name));
+        if self.compressed_wasm {
+            quote_in! { *tokens =>
+                $['\n']
+                $(comment(&[
+                    "decompressWasm decompresses the gzip-compressed WebAssembly module. It's",
+                    "only decompressed once, when the first factory is created.",
+                ]))
+                var decompressWasm = $SYNC_ONCE_VALUES(func() ([]byte, error) {
+                    r, err := $GZIP_NEW_READER($BYTES_NEW_READER($wasm_var_name))
+                    if err != nil {
+                        return nil, err
+                    }
+                    return $IO_READ_ALL(r)
+                })
+            }
+        }
         quote_in! { *tokens =>
             $['\n']
             type $factory_name struct {
                 runtime $WAZERO_RUNTIME
                 module  $WAZERO_COMPILED_MODULE
+                config  factoryConfig
+                closed  $SYNC_ATOMIC_BOOL
+                mu      $SYNC_MUTEX
+                idle    []*$instance_name
             }
             $['\n']
             func $constructor_name(
@@ -92,39 +563,249 @@ impl<'a> FactoryGenerator<'a> {
                 $params
                 $['\r']
             ) (*$factory_name, error) {
-                wazeroRuntime := $WAZERO_NEW_RUNTIME(ctx)
+                cfg := factoryConfig{
+                    maxMemoryPages:   DefaultMaxMemoryPages,
+                    newRuntimeConfig: $WAZERO_NEW_RUNTIME_CONFIG,
+                }
+                for _, opt := range opts {
+                    opt(&cfg)
+                }
+                $['\n']
+                runtimeConfig := cfg.newRuntimeConfig()
+                if cfg.compilationCache != nil {
+                    runtimeConfig = runtimeConfig.WithCompilationCache(cfg.compilationCache)
+                }
+                wazeroRuntime := $WAZERO_NEW_RUNTIME_WITH_CONFIG(ctx, runtimeConfig)
 
                 $(for chain in self.config.import_chains.values() =>
                     $chain
                     $['\r']
                 )
 
-                $(comment(&[
-                    "Compiling the module takes a LONG time, so we want to do it once and hold",
-                       "onto it with the Runtime",
-                ]))
-                module, err := wazeroRuntime.CompileModule(ctx, $wasm_var_name)
-                if err != nil {
-                    return nil, err
+                module := cfg.compiledModule
+                if module == nil {
+                    $(if self.compressed_wasm {
+                        wasm, err := decompressWasm()
+                        if err != nil {
+                            return nil, err
+                        }
+                        $['\n']
+                    })
+                    $(comment(&[
+                        "Compiling the module takes a LONG time, so we want to do it once and hold",
+                           "onto it with the Runtime",
+                    ]))
+                    compiled, err := wazeroRuntime.CompileModule(ctx, $(if self.compressed_wasm { wasm } else { $wasm_var_name }))
+                    if err != nil {
+                        return nil, err
+                    }
+                    module = compiled
+                }
+                $(comment(&["Guests built for WASI, such as for wasm32-wasip1, import its host module"]))
+                for _, fn := range module.ImportedFunctions() {
+                    if moduleName, _, _ := fn.Import(); moduleName == $WASI_SNAPSHOT_PREVIEW1_MODULE_NAME {
+                        if _, err := $WASI_SNAPSHOT_PREVIEW1_INSTANTIATE(ctx, wazeroRuntime); err != nil {
+                            return nil, err
+                        }
+                        break
+                    }
                 }
                 return &$factory_name{
                     runtime: wazeroRuntime,
                     module:  module,
+                    config:  cfg,
                 }, nil
             }
             $['\n']
+            $(comment([
+                format!("{must_name} is like {}, but panics if the factory can't be", String::from(constructor_name)),
+                "created. It's meant for tests and package-level variables, where the".to_string(),
+                "module is known to be valid.".to_string(),
+            ]))
+            func $must_name(
+                $['\r']
+                $params
+                $['\r']
+            ) *$factory_name {
+                factory, err := $constructor_name($args)
+                if err != nil {
+                    panic(err)
+                }
+                return factory
+            }
+            $['\n']
             func (f *$factory_name) Instantiate(ctx $CONTEXT_CONTEXT) (*$instance_name, error) {
-                if module, err := f.runtime.InstantiateModule(ctx, f.module, $WAZERO_NEW_MODULE_CONFIG()); err != nil {
+                $(comment(&[
+                    "wazero can't create a memory smaller than the minimum of the module, so a",
+                    "limit below it fails here rather than inside the runtime",
+                ]))
+                for _, definition := range f.module.ExportedMemories() {
+                    if pages := definition.Min(); pages > f.config.maxMemoryPages {
+                        return nil, $FMT_ERRORF("%w: the module needs %d pages of memory, over the limit of %d", ErrMemoryLimitExceeded, pages, f.config.maxMemoryPages)
+                    }
+                }
+                memory := &limitedMemory{limit: uint64(f.config.maxMemoryPages) * 65536}
+                ctx = $WAZERO_EXPERIMENTAL_WITH_MEMORY_ALLOCATOR(ctx, $WAZERO_EXPERIMENTAL_MEMORY_ALLOCATOR_FUNC(func(capacity, _ uint64) $WAZERO_EXPERIMENTAL_LINEAR_MEMORY {
+                    memory.buf = make([]byte, 0, min(capacity, memory.limit))
+                    return memory
+                }))
+                config := $WAZERO_NEW_MODULE_CONFIG().WithStartFunctions(f.startFunctions()...)
+                if f.config.reset {
+                    $(comment(&["The start functions run once the memory is copied instead, so Reset can run them again"]))
+                    config = config.WithStartFunctions()
+                }
+                for _, configure := range f.config.moduleConfigs {
+                    config = configure(config)
+                }
+                if module, err := f.runtime.InstantiateModule(ctx, f.module, config); err != nil {
                     return nil, err
                 } else {
-                    return &$instance_name{module}, nil
+                    var snapshot []byte
+                    if memory := module.Memory(); f.config.reset && memory != nil {
+                        if data, ok := memory.Read(0, memory.Size()); ok {
+                            snapshot = $BYTES_CLONE(data)
+                        }
+                    }
+                    if err := f.initialize(ctx, module); err != nil {
+                        module.Close(ctx)
+                        return nil, err
+                    }
+                    instance := &$instance_name{module: module, guest: f.config.memory(module), memory: memory, snapshot: snapshot, initialize: f.initialize, errorWriter: f.config.errorWriter, callLogger: f.config.callLogger}
+                    if f.config.argArena {
+                        instance.arena = &argArena{Function: module.ExportedFunction("cabi_realloc")}
+                    }
+                    if logger := f.config.leakLogger; logger != nil {
+                        instance.cleanup = $RUNTIME_ADD_CLEANUP(instance, func(module $WAZERO_API_MODULE) {
+                            logger.Warn("closing an instance which was garbage collected without being closed", "instance", $(quoted(String::from(instance_name))))
+                            module.Close($CONTEXT_BACKGROUND())
+                        }, module)
+                    }
+                    return instance, nil
                 }
             }
             $['\n']
+            $(comment(&[
+                "initialize runs the start functions of a module instantiated with WithReset,",
+                "which are otherwise run on instantiation, followed by the functions set with",
+                "WithPostInstantiate.",
+            ]))
+            func (f *$factory_name) initialize(ctx $CONTEXT_CONTEXT, module $WAZERO_API_MODULE) error {
+                if f.config.reset {
+                    for _, name := range f.startFunctions() {
+                        start := module.ExportedFunction(name)
+                        if start == nil {
+                            return $FMT_ERRORF("start function %q not found", name)
+                        }
+                        if _, err := start.Call(ctx); err != nil {
+                            return err
+                        }
+                    }
+                }
+                for _, initialize := range f.config.postInstantiate {
+                    if err := initialize(ctx, module); err != nil {
+                        return err
+                    }
+                }
+                return nil
+            }
+            $['\n']
+            $(comment(&[
+                "Close closes the runtime along with every instance of the factory. Closing",
+                "it again, even concurrently, does nothing.",
+            ]))
             func (f *$factory_name) Close(ctx $CONTEXT_CONTEXT) {
+                if !f.closed.CompareAndSwap(false, true) {
+                    return
+                }
                 f.runtime.Close(ctx)
             }
             $['\n']
+            $(comment(&[
+                "CompiledModule returns the module the factory instantiates, which can be",
+                "passed to WithCompiledModule to create factories without compiling it again.",
+            ]))
+            func (f *$factory_name) CompiledModule() $WAZERO_COMPILED_MODULE {
+                return f.module
+            }
+            $['\n']
+            $(comment(&[
+                "Call runs fn with an instance of the factory, so concurrent callers don't",
+                "have to manage instances themselves. Instances are pooled, and reused by",
+                "later calls once fn returns, after being reset if the factory was created",
+                "with WithReset. Without it, the guest state left by fn carries over to the",
+                "later calls reusing the instance. If fn returns an error or panics, the",
+                "instance is closed instead, since the guest may have been left in an",
+                "inconsistent state.",
+            ]))
+            func (f *$factory_name) Call(ctx $CONTEXT_CONTEXT, fn func(*$instance_name) error) error {
+                instance, err := f.acquire(ctx)
+                if err != nil {
+                    return err
+                }
+                released := false
+                defer func() {
+                    if !released {
+                        instance.Close(ctx)
+                    }
+                }()
+                if err := fn(instance); err != nil {
+                    return err
+                }
+                released = true
+                f.release(ctx, instance)
+                return nil
+            }
+            $['\n']
+            $(comment(&["acquire returns an idle instance, or a new one if there is none."]))
+            func (f *$factory_name) acquire(ctx $CONTEXT_CONTEXT) (*$instance_name, error) {
+                f.mu.Lock()
+                if n := len(f.idle); n > 0 {
+                    instance := f.idle[n-1]
+                    f.idle = f.idle[:n-1]
+                    f.mu.Unlock()
+                    return instance, nil
+                }
+                f.mu.Unlock()
+                return f.Instantiate(ctx)
+            }
+            $['\n']
+            $(comment(&[
+                "release returns an instance to the idle ones, unless it was closed or can't",
+                "be reset.",
+            ]))
+            func (f *$factory_name) release(ctx $CONTEXT_CONTEXT, instance *$instance_name) {
+                if instance.closed.Load() {
+                    return
+                }
+                if f.config.reset {
+                    if err := instance.Reset(ctx); err != nil {
+                        instance.Close(ctx)
+                        return
+                    }
+                }
+                f.mu.Lock()
+                defer f.mu.Unlock()
+                f.idle = append(f.idle, instance)
+            }
+            $['\n']
+            $(comment(&[
+                "startFunctions returns the functions called when instantiating the module,",
+                "which are the one set with WithStartFunction, or else the first of",
+                "`_initialize` and `_start` the module exports.",
+            ]))
+            func (f *$factory_name) startFunctions() []string {
+                if f.config.startFunction != "" {
+                    return []string{f.config.startFunction}
+                }
+                exports := f.module.ExportedFunctions()
+                for _, name := range []string{"_initialize", "_start"} {
+                    if _, ok := exports[name]; ok {
+                        return []string{name}
+                    }
+                }
+                return nil
+            }
+            $['\n']
         };
     }
 
@@ -133,10 +814,25 @@ impl<'a> FactoryGenerator<'a> {
         let instance_name = &self.config.analyzed_imports.instance_name;
         quote_in! { *tokens =>
             type $instance_name struct {
-                module $WAZERO_API_MODULE
+                module      $WAZERO_API_MODULE
+                guest       $WAZERO_API_MEMORY
+                memory      *limitedMemory
+                arena       *argArena
+                exports     map[string]$WAZERO_API_FUNCTION
+                snapshot    []byte
+                initialize  func($CONTEXT_CONTEXT, $WAZERO_API_MODULE) error
+                errorWriter $IO_WRITER
+                callLogger  *$SLOG_LOGGER
+                cleanup     $RUNTIME_CLEANUP
+                closed      $SYNC_ATOMIC_BOOL
             }
             $['\n']
+            $(comment(&["Close closes the instance. Closing it again, even concurrently, returns nil."]))
             func (i *$instance_name) Close(ctx $CONTEXT_CONTEXT) error {
+                if !i.closed.CompareAndSwap(false, true) {
+                    return nil
+                }
+                i.cleanup.Stop()
                 if err := i.module.Close(ctx); err != nil {
                     return err
                 }
@@ -144,35 +840,295 @@ impl<'a> FactoryGenerator<'a> {
                 return nil
             }
             $['\n']
-        };
-    }
-
-    /// Build parameter list for factory constructor
-    fn build_parameters(&self) -> Tokens<Go> {
-        let interfaces = &self.config.analyzed_imports.interfaces;
-
-        quote! {
-            ctx $CONTEXT_CONTEXT,
-            $(for interface in interfaces.iter() =>
-            $(&interface.constructor_param_name) $(&interface.go_interface_name),)
-        }
-    }
-}
-
+            $(comment(&["ErrResetUnsupported is returned by Reset when an instance can't be reset."]))
+            var ErrResetUnsupported = $ERRORS_NEW("instance does not support reset")
+            $['\n']
+            $(comment(&[
+                "Reset restores the guest memory to its state from before the start functions",
+                "ran on instantiation, and runs them again along with the functions set with",
+                "WithPostInstantiate, so the instance can be reused with a clean slate. This",
+                "requires the factory to be created with WithReset, otherwise",
+                "ErrResetUnsupported is returned. Globals are only restored as far as the",
+                "start functions set them, so guests keeping other state in mutable globals",
+                "can't be reset safely.",
+            ]))
+            func (i *$instance_name) Reset(ctx $CONTEXT_CONTEXT) error {
+                if i.snapshot == nil {
+                    return ErrResetUnsupported
+                }
+                memory := i.module.Memory()
+                if !memory.Write(0, i.snapshot) {
+                    return $ERRORS_NEW("failed to restore guest memory")
+                }
+                $(comment(&["Memory can't shrink, so memory grown since instantiation is cleared instead"]))
+                if grown, ok := memory.Read(uint32(len(i.snapshot)), memory.Size()-uint32(len(i.snapshot))); ok {
+                    clear(grown)
+                }
+                $(comment(&["The arena's region was freed along with the rest of the guest's allocations"]))
+                if i.arena != nil {
+                    *i.arena = argArena{Function: i.arena.Function}
+                }
+                return i.initialize(ctx, i.module)
+            }
+            $['\n']
+            $(comment(&[
+                "MemorySize returns the size of the guest memory in bytes, a multiple of the",
+                "64 KiB page size. The memory never shrinks, so it only grows as the guest",
+                "needs more of it.",
+            ]))
+            func (i *$instance_name) MemorySize() uint64 {
+                if memory := i.module.Memory(); memory != nil {
+                    return uint64(memory.Size())
+                }
+                return 0
+            }
+            $['\n']
+            $(comment(&["MemoryStats describes the guest memory of an instance."]))
+            type MemoryStats struct {
+                $(comment(&["Pages is the number of 64 KiB pages of guest memory."]))
+                Pages uint32
+                $(comment(&["Bytes is the size of the guest memory in bytes."]))
+                Bytes uint64
+                $(comment(&["Limit is the size in bytes the guest memory can't grow beyond."]))
+                Limit uint64
+                $(comment(&[
+                    "ArenaBytes is the size in bytes of the region reserved for the arguments",
+                    "of calls with WithArgArena.",
+                ]))
+                ArenaBytes uint64
+            }
+            $['\n']
+            $(comment(&[
+                "MemoryStats returns statistics about the guest memory. The allocator of the",
+                "guest lives inside its memory, so how much of it is allocated isn't known to",
+                "the host: the memory the guest uses is at most Bytes.",
+            ]))
+            func (i *$instance_name) MemoryStats() MemoryStats {
+                size := i.MemorySize()
+                stats := MemoryStats{Pages: uint32(size / 65536), Bytes: size, Limit: i.memory.limit}
+                if i.arena != nil {
+                    stats.ArenaBytes = i.arena.size
+                }
+                return stats
+            }
+            $['\n']
+            $(comment(&[
+                "realloc returns the function used to allocate memory in the guest for the",
+                "arguments of a call.",
+            ]))
+            func (i *$instance_name) realloc(name string) $WAZERO_API_FUNCTION {
+                if i.arena != nil {
+                    return i.arena
+                }
+                return i.exportedFunction(name)
+            }
+            $['\n']
+            $(comment(&[
+                "exportedFunction returns the function the guest exports with the given name.",
+                "Functions are cached once looked up, since looking them up again on every",
+                "call is expensive.",
+            ]))
+            func (i *$instance_name) exportedFunction(name string) $WAZERO_API_FUNCTION {
+                if function, ok := i.exports[name]; ok {
+                    return function
+                }
+                function := i.module.ExportedFunction(name)
+                if i.exports == nil {
+                    i.exports = map[string]$WAZERO_API_FUNCTION{}
+                }
+                i.exports[name] = function
+                return function
+            }
+            $['\n']
+            $(comment(&[
+                "callExport calls the function the guest exports with the given name, failing",
+                "instead of panicking if the guest doesn't export it.",
+            ]))
+            func (i *$instance_name) callExport(ctx $CONTEXT_CONTEXT, name string, params ...uint64) ([]uint64, error) {
+                function := i.exportedFunction(name)
+                if function == nil {
+                    return nil, $FMT_ERRORF("function %q not exported by the guest", name)
+                }
+                return function.Call(ctx, params...)
+            }
+            $['\n']
+            $(comment(&[
+                "writeError writes the message of an error returned by the guest to the",
+                "writer set with WithErrorWriter, if any.",
+            ]))
+            func (i *$instance_name) writeError(message string) {
+                if i.errorWriter != nil {
+                    $IO_WRITE_STRING(i.errorWriter, message+"\n")
+                }
+            }
+            $['\n']
+            $(comment(&[
+                "logCall logs a call into the guest to the logger set with WithCallLogging,",
+                "which the caller checks is set, so the arguments aren't collected otherwise.",
+            ]))
+            func (i *$instance_name) logCall(ctx $CONTEXT_CONTEXT, name string, args []uint64, results ...any) {
+                var lowered []byte
+                for _, arg := range args {
+                    lowered = $BINARY_LITTLE_ENDIAN.AppendUint64(lowered, arg)
+                }
+                i.callLogger.DebugContext(ctx, "guest call", "function", name, "args", $HEX_ENCODE_TO_STRING(lowered), "results", $FMT_SPRINTF("%+v", results))
+            }
+            $['\n']
+        };
+    }
+
+    /// Generate the `wrappedMemory` helper type, used by `WithMemory`.
+    fn generate_wrapped_memory(&self, tokens: &mut Tokens<Go>) {
+        quote_in! { *tokens =>
+            $(comment(&[
+                "wrappedMemory is the memory of an instance, whose methods used by the",
+                "bindings go through the Memory set with WithMemory.",
+            ]))
+            type wrappedMemory struct {
+                $WAZERO_API_MEMORY
+                wrapped Memory
+            }
+            $['\n']
+            func (m wrappedMemory) Read(offset, byteCount uint32) ([]byte, bool) {
+                return m.wrapped.Read(offset, byteCount)
+            }
+            $['\n']
+            func (m wrappedMemory) Write(offset uint32, v []byte) bool {
+                return m.wrapped.Write(offset, v)
+            }
+            $['\n']
+            func (m wrappedMemory) ReadUint16Le(offset uint32) (uint16, bool) {
+                buf, ok := m.wrapped.Read(offset, 2)
+                if !ok {
+                    return 0, false
+                }
+                return $BINARY_LITTLE_ENDIAN.Uint16(buf), true
+            }
+            $['\n']
+            func (m wrappedMemory) ReadUint32Le(offset uint32) (uint32, bool) {
+                buf, ok := m.wrapped.Read(offset, 4)
+                if !ok {
+                    return 0, false
+                }
+                return $BINARY_LITTLE_ENDIAN.Uint32(buf), true
+            }
+            $['\n']
+            func (m wrappedMemory) ReadUint64Le(offset uint32) (uint64, bool) {
+                buf, ok := m.wrapped.Read(offset, 8)
+                if !ok {
+                    return 0, false
+                }
+                return $BINARY_LITTLE_ENDIAN.Uint64(buf), true
+            }
+            $['\n']
+            func (m wrappedMemory) WriteUint16Le(offset uint32, v uint16) bool {
+                return m.wrapped.Write(offset, $BINARY_LITTLE_ENDIAN.AppendUint16(nil, v))
+            }
+            $['\n']
+            func (m wrappedMemory) WriteUint32Le(offset uint32, v uint32) bool {
+                return m.wrapped.Write(offset, $BINARY_LITTLE_ENDIAN.AppendUint32(nil, v))
+            }
+            $['\n']
+            func (m wrappedMemory) WriteUint64Le(offset uint32, v uint64) bool {
+                return m.wrapped.Write(offset, $BINARY_LITTLE_ENDIAN.AppendUint64(nil, v))
+            }
+            $['\n']
+            $(comment(&[
+                "readByte reads a byte from memory. wrappedMemory can't override ReadByte",
+                "of api.Memory, whose signature differs from io.ByteReader's.",
+            ]))
+            func readByte(memory $WAZERO_API_MEMORY, offset uint32) (byte, bool) {
+                if m, ok := memory.(wrappedMemory); ok {
+                    buf, ok := m.wrapped.Read(offset, 1)
+                    if !ok {
+                        return 0, false
+                    }
+                    return buf[0], true
+                }
+                return memory.ReadByte(offset)
+            }
+            $['\n']
+            $(comment(&[
+                "writeByte writes a byte to memory. wrappedMemory can't override WriteByte",
+                "of api.Memory, whose signature differs from io.ByteWriter's.",
+            ]))
+            func writeByte(memory $WAZERO_API_MEMORY, offset uint32, v byte) bool {
+                if m, ok := memory.(wrappedMemory); ok {
+                    return m.wrapped.Write(offset, []byte{v})
+                }
+                return memory.WriteByte(offset, v)
+            }
+            $['\n']
+            $(comment(&[
+                "readBytes reads the bytes of a string or list from memory. Empty ones are",
+                "read without touching memory, as guests may return them with a null or",
+                "dangling pointer.",
+            ]))
+            func readBytes(memory $WAZERO_API_MEMORY, offset, byteCount uint32) ([]byte, bool) {
+                if byteCount == 0 {
+                    return nil, true
+                }
+                return memory.Read(offset, byteCount)
+            }
+            $['\n']
+        };
+    }
+
+    /// Build parameter list for factory constructor
+    fn build_parameters(&self) -> Tokens<Go> {
+        let interfaces = &self.config.analyzed_imports.interfaces;
+
+        quote! {
+            ctx $CONTEXT_CONTEXT,
+            $(for interface in interfaces.iter() =>
+            $(&interface.constructor_param_name) $(&interface.go_interface_name),)
+            opts ...FactoryOption,
+        }
+    }
+
+    /// Build the arguments passing the parameters of the constructor on to
+    /// it.
+    fn build_arguments(&self) -> Tokens<Go> {
+        let interfaces = &self.config.analyzed_imports.interfaces;
+
+        let args = std::iter::once(quote!(ctx))
+            .chain(
+                interfaces
+                    .iter()
+                    .map(|interface| quote!($(&interface.constructor_param_name))),
+            )
+            .chain(std::iter::once(quote!(opts...)))
+            .collect::<Vec<_>>();
+        quote!($(for arg in args join (, ) => $arg))
+    }
+}
+
 impl<'a> FormatInto<Go> for &FactoryGenerator<'a> {
     fn format_into(self, tokens: &mut Tokens<Go>) {
         self.generate_factory(tokens);
         tokens.push();
+        self.generate_options(tokens);
+        tokens.push();
         self.generate_instance(tokens);
         tokens.push();
+        self.generate_call_error(tokens);
+        tokens.push();
         self.generate_write_string(tokens);
         tokens.push();
+        self.generate_recover_host_panic(tokens);
+        tokens.push();
+        self.generate_arg_arena(tokens);
+        tokens.push();
+        self.generate_limited_memory(tokens);
+        tokens.push();
+        self.generate_wrapped_memory(tokens);
+        tokens.push();
     }
 }
 
 #[cfg(test)]
 mod tests {
-    use genco::lang::go::Tokens;
+    use genco::{lang::go::Tokens, tokens::FormatInto};
 
     use crate::{
         codegen::{FactoryGenerator, factory::FactoryConfig, ir::AnalyzedImports},
@@ -198,6 +1154,756 @@ mod tests {
         let mut tokens = Tokens::new();
         generator.generate_write_string(&mut tokens);
 
-        assert!(tokens.to_string().unwrap().contains("func writeString"));
+        let generated = tokens.to_string().unwrap();
+        assert!(generated.contains("func writeString"));
+        assert!(generated.contains("return 1, 0, ErrGuestAllocFailed"));
+    }
+
+    #[test]
+    fn test_generate_call_error() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("test-constructor"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::public("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        generator.generate_call_error(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        assert!(generated.contains("var ErrStackOverflow = errors.New(\"guest stack overflow\")"));
+        assert!(generated.contains("return fmt.Errorf(\"%w: %w\", ErrStackOverflow, err)"));
+        assert!(
+            generated.contains(
+                "var ErrMemoryLimitExceeded = errors.New(\"guest memory limit exceeded\")"
+            )
+        );
+        assert!(generated.contains("return fmt.Errorf(\"%w: %w\", ErrMemoryLimitExceeded, err)"));
+    }
+
+    #[test]
+    fn test_generate_recover_host_panic() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("test-constructor"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::public("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        generator.generate_recover_host_panic(&mut tokens);
+
+        // The panic traps the guest with a typed error, keeping the error it
+        // panicked with
+        let generated = tokens.to_string().unwrap();
+        assert!(generated.contains("var ErrHostPanic = errors.New(\"host function panicked\")"));
+        assert!(generated.contains("func recoverHostPanic(impl any, method string) {"));
+        assert!(
+            generated
+                .contains("panic(fmt.Errorf(\"%w: %T.%s: %w\", ErrHostPanic, impl, method, err))")
+        );
+        assert!(
+            generated
+                .contains("panic(fmt.Errorf(\"%w: %T.%s: %v\", ErrHostPanic, impl, method, r))")
+        );
+    }
+
+    #[test]
+    fn test_generate_memory_limit() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Memory is limited to 256 MiB by default
+        assert!(generated.contains("const DefaultMaxMemoryPages = 4096"));
+        assert!(generated.contains("maxMemoryPages: DefaultMaxMemoryPages,"));
+
+        // The limit can be raised, and is enforced by the instance's memory
+        assert!(generated.contains("func WithMaxMemoryPages(pages uint32) FactoryOption {"));
+        assert!(
+            generated.contains(
+                "memory := &limitedMemory{limit: uint64(f.config.maxMemoryPages) * 65536}"
+            )
+        );
+        assert!(generated.contains("ctx = experimental.WithMemoryAllocator(ctx, "));
+        assert!(generated.contains(
+            "instance := &TestInstance{module: module, guest: f.config.memory(module), memory: memory, "
+        ));
+
+        // A limit below the minimum of the module fails instantiation
+        assert!(
+            generated.contains("if pages := definition.Min(); pages > f.config.maxMemoryPages {")
+        );
+        assert!(generated.contains(
+            "return nil, fmt.Errorf(\"%w: the module needs %d pages of memory, over the limit of %d\", ErrMemoryLimitExceeded, pages, f.config.maxMemoryPages)"
+        ));
+    }
+
+    #[test]
+    fn test_generate_memory_wrapping() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // The memory can be wrapped with an option
+        assert!(generated.contains("type Memory interface {"));
+        assert!(generated.contains("Read(offset, byteCount uint32) ([]byte, bool)"));
+        assert!(generated.contains("Write(offset uint32, v []byte) bool"));
+        assert!(generated.contains("func WithMemory(wrap func(Memory) Memory) FactoryOption {"));
+
+        // Instances keep the wrapped memory, which is the plain one by default
+        assert!(generated.contains("if cfg.wrapMemory == nil || memory == nil {"));
+        assert!(
+            generated
+                .contains("return wrappedMemory{Memory: memory, wrapped: cfg.wrapMemory(memory)}")
+        );
+        assert!(generated.contains("guest: f.config.memory(module)"));
+
+        // Typed reads and writes go through the wrapped memory's Read and Write
+        assert!(generated.contains("buf, ok := m.wrapped.Read(offset, 4)"));
+        assert!(
+            generated.contains(
+                "return m.wrapped.Write(offset, binary.LittleEndian.AppendUint32(nil, v))"
+            )
+        );
+        assert!(generated.contains("if m, ok := memory.(wrappedMemory); ok {"));
+    }
+
+    #[test]
+    fn test_generate_idempotent_close() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Closing the factory or an instance again is a no-op
+        assert!(generated.contains(
+            "func (f *TestFactory) Close(ctx context.Context) {\n\tif !f.closed.CompareAndSwap(false, true) {\n\t\treturn\n\t}"
+        ));
+        assert!(generated.contains(
+            "func (i *TestInstance) Close(ctx context.Context) error {\n\tif !i.closed.CompareAndSwap(false, true) {\n\t\treturn nil\n\t}"
+        ));
+    }
+
+    #[test]
+    fn test_generate_start_functions() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // The start function can be set, or is detected from the exports
+        assert!(generated.contains("func WithStartFunction(name string) FactoryOption {"));
+        assert!(generated.contains("func (f *TestFactory) startFunctions() []string {"));
+        assert!(generated.contains("for _, name := range []string{\"_initialize\", \"_start\"} {"));
+        assert!(generated.contains(
+            "config := wazero.NewModuleConfig().WithStartFunctions(f.startFunctions()...)"
+        ));
+    }
+
+    #[test]
+    fn test_generate_module_config() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // The module config is customized on top of the start functions,
+        // rather than replacing them
+        assert!(generated.contains(
+            "func WithModuleConfig(configure func(wazero.ModuleConfig) wazero.ModuleConfig) FactoryOption {"
+        ));
+        assert!(generated.contains("cfg.moduleConfigs = append(cfg.moduleConfigs, configure)"));
+        let start = generated
+            .find("WithStartFunctions(f.startFunctions()...)")
+            .unwrap();
+        let configure = generated.find("config = configure(config)").unwrap();
+        assert!(start < configure);
+    }
+
+    #[test]
+    fn test_generate_env() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Environment variables are set on top of the module config
+        assert!(generated.contains("func WithEnv(key, value string) FactoryOption {"));
+        assert!(generated.contains("return config.WithEnv(key, value)"));
+    }
+
+    #[test]
+    fn test_generate_exported_function() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Exported functions are only looked up once per instance
+        assert!(generated.contains("exports map[string]api.Function"));
+        assert!(
+            generated
+                .contains("func (i *TestInstance) exportedFunction(name string) api.Function {")
+        );
+        assert!(generated.contains("if function, ok := i.exports[name]; ok {"));
+        assert!(generated.contains("i.exports[name] = function"));
+        assert!(generated.contains("return i.exportedFunction(name)"));
+
+        // A missing export fails the call instead of panicking on nil
+        assert!(generated.contains("function := i.exportedFunction(name)"));
+        assert!(generated.contains("if function == nil {"));
+        assert!(
+            generated.contains(
+                "return nil, fmt.Errorf(\"function %q not exported by the guest\", name)"
+            )
+        );
+    }
+
+    #[test]
+    fn test_generate_memory_stats() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        assert!(generated.contains("func (i *TestInstance) MemorySize() uint64 {"));
+        assert!(generated.contains("return uint64(memory.Size())"));
+        assert!(generated.contains("func (i *TestInstance) MemoryStats() MemoryStats {"));
+        assert!(generated.contains("Limit: i.memory.limit"));
+        assert!(generated.contains("stats.ArenaBytes = i.arena.size"));
+    }
+
+    #[test]
+    fn test_generate_error_writer() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        assert!(generated.contains("func WithErrorWriter(w io.Writer) FactoryOption {"));
+        assert!(generated.contains("errorWriter: f.config.errorWriter"));
+        assert!(generated.contains("func (i *TestInstance) writeError(message string) {"));
+        assert!(generated.contains("io.WriteString(i.errorWriter, message+\"\\n\")"));
+    }
+
+    #[test]
+    fn test_generate_call_logging() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        assert!(generated.contains("func WithCallLogging(logger *slog.Logger) FactoryOption {"));
+        assert!(generated.contains("callLogger: f.config.callLogger"));
+        assert!(generated.contains(
+            "func (i *TestInstance) logCall(ctx context.Context, name string, args []uint64, results ...any) {"
+        ));
+        assert!(generated.contains("binary.LittleEndian.AppendUint64(lowered, arg)"));
+        assert!(generated.contains("\"args\", hex.EncodeToString(lowered)"));
+    }
+
+    #[test]
+    fn test_generate_compiled_module() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        assert!(
+            generated.contains(
+                "func WithCompilationCache(cache wazero.CompilationCache) FactoryOption {"
+            )
+        );
+        assert!(
+            generated.contains(
+                "runtimeConfig = runtimeConfig.WithCompilationCache(cfg.compilationCache)"
+            )
+        );
+        assert!(
+            generated
+                .contains("func WithCompiledModule(module wazero.CompiledModule) FactoryOption {")
+        );
+        assert!(
+            generated.contains("func (f *TestFactory) CompiledModule() wazero.CompiledModule {")
+        );
+
+        // The module is only compiled when none was given
+        assert!(generated.contains("module := cfg.compiledModule"));
+        assert!(generated.contains("if module == nil {"));
+        assert!(generated.contains("compiled, err := wazeroRuntime.CompileModule(ctx, testWasm)"));
+    }
+
+    #[test]
+    fn test_generate_leak_cleanup() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        assert!(generated.contains("func WithLeakCleanup(logger *slog.Logger) FactoryOption {"));
+        assert!(
+            generated.contains(
+                "instance.cleanup = runtime.AddCleanup(instance, func(module api.Module) {"
+            )
+        );
+        assert!(generated.contains("module.Close(context.Background())"));
+        assert!(generated.contains("\"instance\", \"TestInstance\")"));
+
+        // Closing the instance stops the cleanup
+        assert!(generated.contains("return nil\n\t}\n\ti.cleanup.Stop()"));
+    }
+
+    #[test]
+    fn test_generate_post_instantiate() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        assert!(generated.contains(
+            "func WithPostInstantiate(initialize func(ctx context.Context, module api.Module) error) FactoryOption {"
+        ));
+        assert!(
+            generated.contains("cfg.postInstantiate = append(cfg.postInstantiate, initialize)")
+        );
+
+        // Every function runs before the instance is created, closing the
+        // module if one fails
+        assert!(generated.contains("for _, initialize := range f.config.postInstantiate {"));
+        let initialize = generated
+            .find("if err := f.initialize(ctx, module); err != nil {")
+            .expect("expected the functions to run");
+        let instance = generated
+            .find("instance := &TestInstance{")
+            .expect("expected an instance");
+        assert!(initialize < instance);
+        assert!(generated.contains(
+            "if err := f.initialize(ctx, module); err != nil {\n\t\t\tmodule.Close(ctx)\n\t\t\treturn nil, err"
+        ));
+    }
+
+    #[test]
+    fn test_generate_must_constructor() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // It takes the parameters of the constructor, and panics on its error
+        assert!(generated.contains(
+            "func NewTestFactoryMust(\n\tctx context.Context,\n\topts ...FactoryOption,\n) *TestFactory {"
+        ));
+        assert!(generated.contains("factory, err := NewTestFactory(ctx, opts...)"));
+        assert!(generated.contains("if err != nil {\n\t\tpanic(err)\n\t}\n\treturn factory"));
+    }
+
+    #[test]
+    fn test_generate_wasi() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // The WASI host module is instantiated for guests importing it
+        assert!(generated.contains(
+            "if moduleName, _, _ := fn.Import(); moduleName == wasi_snapshot_preview1.ModuleName {"
+        ));
+        assert!(generated.contains(
+            "if _, err := wasi_snapshot_preview1.Instantiate(ctx, wazeroRuntime); err != nil {"
+        ));
+
+        // Its clock and randomness are set through the module config
+        assert!(generated.contains("func WithWASIClock(fixed time.Time) FactoryOption {"));
+        assert!(generated.contains("return fixed.Unix(), int32(fixed.Nanosecond())"));
+        assert!(generated.contains("}, sys.ClockResolution(1))"));
+        assert!(generated.contains("func WithWASIRandSource(r io.Reader) FactoryOption {"));
+        assert!(generated.contains("return config.WithRandSource(r)"));
+    }
+
+    #[test]
+    fn test_generate_compressed_wasm() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config).with_compressed_wasm(true);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // The module is decompressed once, before compiling it
+        assert!(
+            generated.contains("var decompressWasm = sync.OnceValues(func() ([]byte, error) {")
+        );
+        assert!(generated.contains("gzip.NewReader(bytes.NewReader(testWasm))"));
+        assert!(generated.contains("wasm, err := decompressWasm()"));
+        assert!(generated.contains("wazeroRuntime.CompileModule(ctx, wasm)"));
+    }
+
+    #[test]
+    fn test_generate_call() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        assert!(generated.contains(
+            "func (f *TestFactory) Call(ctx context.Context, fn func(*TestInstance) error) error {"
+        ));
+        assert!(generated.contains("idle []*TestInstance"));
+        assert!(generated.contains("instance, err := f.acquire(ctx)"));
+        assert!(generated.contains("f.release(ctx, instance)"));
+        assert!(generated.contains(
+            "defer func() {\n\t\tif !released {\n\t\t\tinstance.Close(ctx)\n\t\t}\n\t}()"
+        ));
+        assert!(generated.contains("if err := instance.Reset(ctx); err != nil {"));
+    }
+
+    #[test]
+    fn test_generate_reset() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("test-constructor"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::public("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // The memory is copied before the start functions run, so Reset can
+        // restore it and run them again
+        assert!(generated.contains("config = config.WithStartFunctions()"));
+        let snapshot = generated
+            .find("snapshot = bytes.Clone(data)")
+            .expect("expected a snapshot");
+        let initialize = generated
+            .find("if err := f.initialize(ctx, module); err != nil {")
+            .expect("expected the start functions to run");
+        assert!(snapshot < initialize);
+        assert!(generated.contains("for _, name := range f.startFunctions() {"));
+        assert!(generated.contains("return i.initialize(ctx, i.module)"));
+    }
+
+    #[test]
+    fn test_helpers_unexported() {
+        let analyzed_imports = &AnalyzedImports {
+            interfaces: vec![],
+            standalone_types: vec![],
+            standalone_functions: vec![],
+            factory_name: GoIdentifier::public("test-factory"),
+            instance_name: GoIdentifier::public("test-instance"),
+            constructor_name: GoIdentifier::public("new-test-factory"),
+        };
+        let config = FactoryConfig {
+            analyzed_imports,
+            import_chains: Default::default(),
+            wasm_var_name: &GoIdentifier::private("test-wasm"),
+        };
+        let generator = FactoryGenerator::new(config);
+        let mut tokens = Tokens::new();
+        (&generator).format_into(&mut tokens);
+
+        // The lifting and lowering helpers stay out of the package's API, which
+        // is only the factory, instance, options, errors and WIT types
+        let generated = tokens.to_string().unwrap();
+        for helper in [
+            "callError",
+            "writeString",
+            "recoverHostPanic",
+            "argArena",
+            "exportedFunction",
+            "callExport",
+            "realloc",
+            "limitedMemory",
+            "wrappedMemory",
+        ] {
+            assert!(generated.contains(helper), "{helper}");
+            let exported = format!("{}{}", helper[..1].to_uppercase(), &helper[1..]);
+            assert!(!generated.contains(&format!(" {exported}(")), "{exported}");
+            assert!(
+                !generated.contains(&format!("type {exported} ")),
+                "{exported}"
+            );
+        }
     }
 }
@@ -2,20 +2,22 @@ use std::mem;
 
 use genco::prelude::*;
 use wit_bindgen_core::{
-    abi::{Bindgen, Instruction},
-    wit_parser::{Alignment, ArchitectureSize, Resolve, Result_, SizeAlign, Type},
+    abi::{Bindgen, Instruction, WasmType},
+    wit_parser::{Alignment, ArchitectureSize, Resolve, Result_, SizeAlign, Type, TypeDefKind},
 };
 
 use crate::{
+    field_array_len,
     go::{
-        GoIdentifier, GoResult, GoType, Operand, comment,
+        GoIdentifier, GoResult, GoType, Naming, Operand, OptionStyle, Renames, comment,
         imports::{
-            ERRORS_NEW, WAZERO_API_DECODE_F32, WAZERO_API_DECODE_F64, WAZERO_API_DECODE_I32,
-            WAZERO_API_DECODE_U32, WAZERO_API_ENCODE_F32, WAZERO_API_ENCODE_F64,
+            ERRORS_NEW, FMT_ERRORF, MATH_FLOAT32_BITS, MATH_FLOAT32_FROM_BITS, MATH_FLOAT64_BITS,
+            MATH_FLOAT64_FROM_BITS, UNSAFE_SLICE_DATA, UNSAFE_STRING, WAZERO_API_DECODE_F32,
+            WAZERO_API_DECODE_F64, WAZERO_API_ENCODE_F32, WAZERO_API_ENCODE_F64,
             WAZERO_API_ENCODE_I32, WAZERO_API_ENCODE_U32,
         },
     },
-    resolve_type, resolve_wasm_type,
+    resolve_type_with, resolve_wasm_type,
 };
 
 /// The direction of a function.
@@ -28,6 +30,8 @@ enum Direction<'a> {
         /// The name of the parameter representing the interface instance
         /// in the generated host binding function.
         param_name: &'a GoIdentifier,
+        /// The name of the method of the interface implementing the function.
+        method_name: &'a GoIdentifier,
     },
     /// The function is exported from the world.
     #[allow(dead_code, reason = "halfway through refactor of func bindings")]
@@ -43,12 +47,40 @@ pub struct Func<'a> {
     block_storage: Vec<Tokens<Go>>,
     blocks: Vec<(Tokens<Go>, Vec<Operand>)>,
     sizes: &'a SizeAlign,
+    /// Whether the `list` result is passed element by element to `yield`
+    /// instead of being collected into a slice.
+    seq: bool,
+    /// Whether lowering the arguments allocates memory in the guest.
+    allocates: bool,
+    /// The naming strategies for record fields, types and functions.
+    naming: Naming,
+    /// The Go identifiers overriding the generated ones.
+    renames: Renames,
+    /// Whether `list<u8>` parameters of host functions are views into guest
+    /// memory instead of copies.
+    byte_views: bool,
+    /// Whether `string` and `list<u8>` results are views into guest memory,
+    /// returned with a function freeing them once the caller is done.
+    manual_cleanup: bool,
+    /// Whether the guest exports a `cabi_post_*` function freeing the result.
+    post_return: bool,
+    /// How `option<T>` is represented in Go.
+    option_style: OptionStyle,
+    /// Whether named lists are defined types rather than inlined slices.
+    named_lists: bool,
+    /// Whether the host function accesses guest memory, which it then looks
+    /// up once at the start of its body.
+    uses_memory: bool,
+    /// The name and core Wasm arguments of the guest function called, logged
+    /// with what the function returns.
+    call: Option<(String, Vec<Operand>)>,
 }
 
 impl<'a> Func<'a> {
     /// Create a new exported function.
     #[allow(dead_code, reason = "halfway through refactor of func bindings")]
     pub fn export(result: GoResult, sizes: &'a SizeAlign) -> Self {
+        let post_return = result.needs_cleanup();
         Self {
             direction: Direction::Export,
             args: Vec::new(),
@@ -58,13 +90,46 @@ impl<'a> Func<'a> {
             block_storage: Vec::new(),
             blocks: Vec::new(),
             sizes,
+            seq: false,
+            allocates: false,
+            naming: Naming::default(),
+            renames: Renames::default(),
+            byte_views: false,
+            manual_cleanup: false,
+            post_return,
+            option_style: OptionStyle::default(),
+            named_lists: false,
+            uses_memory: false,
+            call: None,
+        }
+    }
+
+    /// Create a new exported function that streams its `list` result.
+    ///
+    /// Instead of building a slice, each lifted element is passed to a
+    /// `yield` function in scope, and the body returns `nil` once every element
+    /// has been yielded or `yield` asks to stop. Errors are returned, so the
+    /// body is meant to be wrapped in a `func() error`.
+    pub fn export_seq(sizes: &'a SizeAlign) -> Self {
+        Self {
+            seq: true,
+            ..Self::export(GoResult::Anon(GoType::Error), sizes)
         }
     }
 
     /// Create a new exported function.
-    pub fn import(param_name: &'a GoIdentifier, result: GoResult, sizes: &'a SizeAlign) -> Self {
+    pub fn import(
+        param_name: &'a GoIdentifier,
+        method_name: &'a GoIdentifier,
+        result: GoResult,
+        sizes: &'a SizeAlign,
+    ) -> Self {
+        let post_return = result.needs_cleanup();
         Self {
-            direction: Direction::Import { param_name },
+            direction: Direction::Import {
+                param_name,
+                method_name,
+            },
             args: Vec::new(),
             result,
             tmp: 0,
@@ -72,9 +137,88 @@ impl<'a> Func<'a> {
             block_storage: Vec::new(),
             blocks: Vec::new(),
             sizes,
+            seq: false,
+            allocates: false,
+            naming: Naming::default(),
+            renames: Renames::default(),
+            byte_views: false,
+            manual_cleanup: false,
+            post_return,
+            option_style: OptionStyle::default(),
+            named_lists: false,
+            uses_memory: false,
+            call: None,
         }
     }
 
+    /// Set the naming strategies for record fields, types and functions.
+    pub fn with_naming(mut self, naming: Naming) -> Self {
+        self.naming = naming;
+        self
+    }
+
+    /// Set the Go identifiers overriding the generated ones.
+    pub fn with_renames(mut self, renames: Renames) -> Self {
+        self.renames = renames;
+        self
+    }
+
+    /// Set whether `list<u8>` parameters of host functions are views into
+    /// guest memory instead of copies.
+    pub fn with_byte_views(mut self, byte_views: bool) -> Self {
+        self.byte_views = byte_views;
+        self
+    }
+
+    /// Set whether `string` and `list<u8>` results are views into guest
+    /// memory, returned with a function freeing them once the caller is done.
+    pub fn with_manual_cleanup(mut self, manual_cleanup: bool) -> Self {
+        self.manual_cleanup = manual_cleanup;
+        self
+    }
+
+    /// Set whether the guest exports a `cabi_post_*` function freeing the
+    /// result, which is otherwise guessed from the Go type of the result.
+    pub fn with_post_return(mut self, post_return: bool) -> Self {
+        self.post_return = post_return;
+        self
+    }
+
+    /// Set how `option<T>` is represented in Go.
+    pub fn with_option_style(mut self, option_style: OptionStyle) -> Self {
+        self.option_style = option_style;
+        self
+    }
+
+    /// Set whether named lists, e.g. `type rows = list<row>`, are defined
+    /// types such as `Rows` rather than inlined slices.
+    pub fn with_named_lists(mut self, named_lists: bool) -> Self {
+        self.named_lists = named_lists;
+        self
+    }
+
+    /// Returns true if the function returns a view into guest memory along
+    /// with a `cleanup` function, instead of a copy.
+    pub fn returns_view(&self) -> bool {
+        self.manual_cleanup
+            && match &self.result {
+                GoResult::Anon(GoType::String) => true,
+                GoResult::Anon(GoType::Slice(inner)) => **inner == GoType::Uint8,
+                _ => false,
+            }
+    }
+
+    /// Resolves the Go type of a WIT type, with options in the configured
+    /// style.
+    fn go_type(&self, typ: &Type, resolve: &Resolve) -> GoType {
+        self.option_style.go_type(resolve_type_with(
+            typ,
+            resolve,
+            self.named_lists,
+            &self.naming,
+        ))
+    }
+
     fn tmp(&mut self) -> usize {
         let ret = self.tmp;
         self.tmp += 1;
@@ -89,8 +233,28 @@ impl<'a> Func<'a> {
         &self.result
     }
 
-    pub fn body(&self) -> &Tokens<Go> {
-        &self.body
+    pub fn body(&self) -> Tokens<Go> {
+        let mut body = Tokens::new();
+        if self.uses_memory {
+            quote_in! { body =>
+                guestMemory := cfg.memory(mod)
+                $['\r']
+            };
+        }
+        body.append(&self.body);
+        body
+    }
+
+    /// Returns the memory values are read from and written to, which is the
+    /// memory set with `WithMemory` if there is one.
+    fn memory(&mut self) -> Tokens<Go> {
+        match self.direction {
+            Direction::Export => quote!(i.guest),
+            Direction::Import { .. } => {
+                self.uses_memory = true;
+                quote!(guestMemory)
+            }
+        }
     }
 
     fn push_arg(&mut self, value: &str) {
@@ -100,6 +264,154 @@ impl<'a> Func<'a> {
     fn pop_block(&mut self) -> (Tokens<Go>, Vec<Operand>) {
         self.blocks.pop().expect("should have block to pop")
     }
+
+    /// Declares a variable for each core Wasm value a variant is lowered to,
+    /// which the block of every case assigns its results to, and pushes them
+    /// as the results. Variants written to memory aren't lowered to any.
+    ///
+    /// Arguments of exports are encoded as `uint64` values, like the call
+    /// expects them.
+    fn variant_results<const N: usize>(
+        &mut self,
+        result_types: &[WasmType],
+        cases: [(&mut Tokens<Go>, &Vec<Operand>); N],
+        results: &mut Vec<Operand>,
+    ) -> Tokens<Go> {
+        let tmp = self.tmp();
+        let mut vars = Tokens::new();
+        for (i, typ) in result_types.iter().enumerate() {
+            let variant = &format!("variant{tmp}_{i}");
+            let typ = match self.direction {
+                Direction::Export => GoType::Uint64,
+                Direction::Import { .. } => resolve_wasm_type(typ),
+            };
+            quote_in! { vars =>
+                $['\r']
+                var $variant $typ
+            };
+            for (block, block_results) in &mut cases {
+                let result = &block_results[i];
+                quote_in! { **block =>
+                    $['\r']
+                    $variant = $result
+                };
+            }
+            results.push(Operand::SingleValue(variant.into()));
+        }
+        vars
+    }
+
+    /// Returns true if the function is a host function imported by the guest.
+    fn is_import(&self) -> bool {
+        matches!(self.direction, Direction::Import { .. })
+    }
+
+    /// Emits a plain Go conversion of the operand to the given type.
+    ///
+    /// Go integer conversions truncate and sign-extend exactly like the
+    /// Canonical ABI does when moving between core Wasm and WIT integers.
+    fn convert(&mut self, typ: GoType, operand: &Operand) -> Operand {
+        let tmp = self.tmp();
+        let result = format!("result{tmp}");
+        quote_in! { self.body =>
+            $['\r']
+            $(&result) := $typ($operand)
+        };
+        Operand::SingleValue(result)
+    }
+
+    /// Emits a read of a value from guest memory with the given method of
+    /// `api.Memory`, returning the name of the variable holding it.
+    fn load(&mut self, method: &str, kind: &str, operand: &Operand, offset: usize) -> String {
+        let tmp = self.tmp();
+        let value = format!("value{tmp}");
+        let ok = &format!("ok{tmp}");
+        let default = &format!("default{tmp}");
+        let message = &format!("failed to read {kind} from memory");
+        let memory = &self.memory();
+        let read = match method {
+            // Bytes are read with a helper, as `wrappedMemory` can't override
+            // the `ReadByte` method
+            "ReadByte" => quote!(readByte($memory, uint32($operand + $offset))),
+            _ => quote!($memory.$method(uint32($operand + $offset))),
+        };
+        quote_in! { self.body =>
+            $['\r']
+            $(&value), $ok := $read
+            $(match &self.result {
+                GoResult::Anon(GoType::ValueOrError(typ)) => {
+                    if !$ok {
+                        var $default $(typ.as_ref())
+                        return $default, $ERRORS_NEW($(quoted(message)))
+                    }
+                }
+                GoResult::Anon(GoType::Error) => {
+                    if !$ok {
+                        return $ERRORS_NEW($(quoted(message)))
+                    }
+                }
+                GoResult::Anon(_) | GoResult::Empty => {
+                    $(comment(&["The return type doesn't contain an error so we panic if one is encountered"]))
+                    if !$ok {
+                        panic($ERRORS_NEW($(quoted(message))))
+                    }
+                }
+            })
+        };
+        value
+    }
+
+    /// Emits a sign extension of a narrow integer read from memory to `int32`,
+    /// the core Wasm type it is loaded as.
+    fn sign_extend(&mut self, narrow: GoType, value: &str) -> Operand {
+        let tmp = self.tmp();
+        let result = format!("result{tmp}");
+        quote_in! { self.body =>
+            $['\r']
+            $(&result) := int32($narrow($value))
+        };
+        Operand::SingleValue(result)
+    }
+}
+
+/// Converts a lifted value to its WIT type.
+///
+/// Lifting only produces values of the underlying Go types, so values of
+/// defined types (WIT type aliases) need a conversion before they are used.
+fn lifted(resolve: &Resolve, naming: &Naming, typ: &Type, operand: &Operand) -> Tokens<Go> {
+    match defined_type(typ, resolve, naming) {
+        Some(typ) => quote!($typ($operand)),
+        None => quote!($operand),
+    }
+}
+
+/// Converts a value of a defined type to the underlying Go type expected by
+/// the lowering instructions.
+fn lowered(
+    resolve: &Resolve,
+    naming: &Naming,
+    typ: &Type,
+    value: impl FormatInto<Go>,
+) -> Tokens<Go> {
+    match defined_type(typ, resolve, naming) {
+        Some(GoType::Defined(_, underlying)) => quote!($(*underlying)($value)),
+        _ => quote!($value),
+    }
+}
+
+/// Resolves the Go type of the given WIT type if it is a defined type, named
+/// with `naming`.
+fn defined_type(typ: &Type, resolve: &Resolve, naming: &Naming) -> Option<GoType> {
+    let Type::Id(id) = typ else {
+        return None;
+    };
+    if !matches!(resolve.types[*id].kind, TypeDefKind::Type(_)) {
+        return None;
+    }
+    match resolve_type_with(typ, resolve, false, naming) {
+        typ @ GoType::Defined(..) => Some(typ),
+        _ => None,
+    }
 }
 
 impl Bindgen for Func<'_> {
@@ -135,15 +447,15 @@ impl Bindgen for Func<'_> {
                 let len = &format!("len{tmp}");
                 let err = &format!("err{tmp}");
                 let default = &format!("default{tmp}");
-                let memory = &format!("memory{tmp}");
                 let realloc = &format!("realloc{tmp}");
                 let operand = &operands[0];
                 match self.direction {
                     Direction::Export => {
+                        self.allocates = true;
+                        let memory = &self.memory();
                         quote_in! { self.body =>
                             $['\r']
-                            $memory := i.module.Memory()
-                            $realloc := i.module.ExportedFunction($(quoted(*realloc_name)))
+                            $realloc := i.realloc($(quoted(*realloc_name)))
                             $ptr, $len, $err := writeString(ctx, $operand, $memory, $realloc)
                             $(match &self.result {
                                 GoResult::Anon(GoType::ValueOrError(typ)) => {
@@ -167,9 +479,9 @@ impl Bindgen for Func<'_> {
                         }
                     }
                     Direction::Import { .. } => {
+                        let memory = &self.memory();
                         quote_in! { self.body =>
                             $['\r']
-                            $memory := mod.Memory()
                             $realloc := mod.ExportedFunction($(quoted(*realloc_name)))
                             $ptr, $len, $err := writeString(ctx, $operand, $memory, $realloc)
                             if $err != nil {
@@ -182,53 +494,86 @@ impl Bindgen for Func<'_> {
                 results.push(Operand::SingleValue(len.into()));
             }
             Instruction::CallWasm { name, .. } => {
+                self.call = Some((name.to_string(), operands.clone()));
                 let tmp = self.tmp();
+                let returns_view = self.returns_view();
+                let post_return = self.post_return;
                 let raw = &format!("raw{tmp}");
                 let ret = &format!("results{tmp}");
                 let err = &format!("err{tmp}");
                 let default = &format!("default{tmp}");
+                // The arguments are no longer needed once the call returns
+                let reset = if self.allocates {
+                    quote!(i.arena.reset(ctx))
+                } else {
+                    Tokens::new()
+                };
                 // TODO(#17): Wrapping every argument in `uint64` is bad and we should instead be looking
                 // at the types and converting with proper guards in place
                 quote_in! { self.body =>
                     $['\r']
                     $(match &self.result {
                         GoResult::Anon(GoType::ValueOrError(typ)) => {
-                            $raw, $err := i.module.ExportedFunction($(quoted(*name))).Call(ctx, $(for op in operands.iter() join (, ) => uint64($op)))
+                            $raw, $err := i.callExport(ctx, $(quoted(*name)), $(for op in operands.iter() join (, ) => uint64($op)))
+                            $(&reset)
                             if $err != nil {
                                 var $default $(typ.as_ref())
-                                return $default, $err
+                                return $default, i.callError($err)
                             }
                         }
                         GoResult::Anon(GoType::Error) => {
-                            $raw, $err := i.module.ExportedFunction($(quoted(*name))).Call(ctx, $(for op in operands.iter() join (, ) => uint64($op)))
+                            $raw, $err := i.callExport(ctx, $(quoted(*name)), $(for op in operands.iter() join (, ) => uint64($op)))
+                            $(&reset)
                             if $err != nil {
-                                return $err
+                                return i.callError($err)
                             }
                         }
                         GoResult::Anon(_) => {
-                            $raw, $err := i.module.ExportedFunction($(quoted(*name))).Call(ctx, $(for op in operands.iter() join (, ) => uint64($op)))
+                            $raw, $err := i.callExport(ctx, $(quoted(*name)), $(for op in operands.iter() join (, ) => uint64($op)))
+                            $(&reset)
                             $(comment(&["The return type doesn't contain an error so we panic if one is encountered"]))
                             if $err != nil {
-                                panic($err)
+                                panic(i.callError($err))
                             }
                         }
                         GoResult::Empty => {
-                            _, $err := i.module.ExportedFunction($(quoted(*name))).Call(ctx, $(for op in operands.iter() join (, ) => uint64($op)))
+                            _, $err := i.callExport(ctx, $(quoted(*name)), $(for op in operands.iter() join (, ) => uint64($op)))
+                            $(&reset)
                             $(comment(&["The return type doesn't contain an error so we panic if one is encountered"]))
                             if $err != nil {
-                                panic($err)
+                                panic(i.callError($err))
                             }
                         }
                     })
 
-                    $(if self.result.needs_cleanup() {
+                    $(if returns_view {
                         $(comment(&[
-                            "The cleanup via `cabi_post_*` cleans up the memory in the guest. By",
-                            "deferring this, we ensure that no memory is corrupted before the function",
-                            "is done accessing it."
+                            "The result is a view into guest memory, which the caller frees with",
+                            "`cleanup` once it is done with it.",
+                        ]))
+                        cleanup := func() {
+                            post := i.exportedFunction($(quoted(format!("cabi_post_{name}"))))
+                            if post == nil {
+                                return
+                            }
+                            if _, err := post.Call(ctx, $raw...); err != nil {
+                                panic($ERRORS_NEW("failed to cleanup"))
+                            }
+                        }
+                    })
+                    $(if !returns_view && post_return {
+                        $(comment(&[
+                            "The cleanup via `cabi_post_*` cleans up the memory in the guest. It reads",
+                            "the discriminant of results and variants itself, so only the payload of",
+                            "the case that was returned is freed. By deferring this, we ensure that no",
+                            "memory is corrupted before the function is done accessing it."
                         ]))
                         defer func() {
-                            if _, err := i.module.ExportedFunction($(quoted(format!("cabi_post_{name}")))).Call(ctx, $raw...); err != nil {
+                            post := i.exportedFunction($(quoted(format!("cabi_post_{name}"))))
+                            if post == nil {
+                                return
+                            }
+                            if _, err := post.Call(ctx, $raw...); err != nil {
                                 $(comment(&[
                                     "If we get an error during cleanup, something really bad is",
                                     "going on, so we panic. Also, you can't return the error from",
@@ -257,9 +602,10 @@ impl Bindgen for Func<'_> {
                 let ok = &format!("ok{tmp}");
                 let default = &format!("default{tmp}");
                 let operand = &operands[0];
+                let memory = &self.memory();
                 quote_in! { self.body =>
                     $['\r']
-                    $value, $ok := i.module.Memory().ReadByte(uint32($operand + $offset))
+                    $value, $ok := readByte($memory, uint32($operand + $offset))
                     $(match &self.result {
                         GoResult::Anon(GoType::ValueOrError(typ)) => {
                             if !$ok {
@@ -307,6 +653,27 @@ impl Bindgen for Func<'_> {
                 }
                 results.push(Operand::SingleValue(value))
             }
+            // Host functions receive and return core values with the exact Go
+            // types of the Wasm signature, so only a conversion is needed.
+            Instruction::I32FromU32
+            | Instruction::I32FromS32
+            | Instruction::I32FromU16
+            | Instruction::I32FromS16
+            | Instruction::I32FromU8
+            | Instruction::I32FromS8
+                if self.is_import() =>
+            {
+                let result = self.convert(GoType::Uint32, &operands[0]);
+                results.push(result);
+            }
+            Instruction::CoreF32FromF32
+            | Instruction::CoreF64FromF64
+            | Instruction::F32FromCoreF32
+            | Instruction::F64FromCoreF64
+                if self.is_import() =>
+            {
+                results.push(operands[0].clone());
+            }
             Instruction::I32FromU32 => {
                 let tmp = self.tmp();
                 let result = &format!("result{tmp}");
@@ -317,16 +684,6 @@ impl Bindgen for Func<'_> {
                 };
                 results.push(Operand::SingleValue(result.into()));
             }
-            Instruction::U32FromI32 => {
-                let tmp = self.tmp();
-                let result = &format!("result{tmp}");
-                let operand = &operands[0];
-                quote_in! { self.body =>
-                    $['\r']
-                    $result := $WAZERO_API_DECODE_U32($operand)
-                };
-                results.push(Operand::SingleValue(result.into()));
-            }
             Instruction::PointerLoad { offset } => {
                 // TODO(#58): Support additional ArchitectureSize
                 let offset = offset.size_wasm32();
@@ -335,9 +692,10 @@ impl Bindgen for Func<'_> {
                 let ok = &format!("ok{tmp}");
                 let default = &format!("default{tmp}");
                 let operand = &operands[0];
+                let memory = &self.memory();
                 quote_in! { self.body =>
                     $['\r']
-                    $ptr, $ok := i.module.Memory().ReadUint32Le(uint32($operand + $offset))
+                    $ptr, $ok := $memory.ReadUint32Le(uint32($operand + $offset))
                     $(match &self.result {
                         GoResult::Anon(GoType::ValueOrError(typ)) => {
                             if !$ok {
@@ -368,9 +726,10 @@ impl Bindgen for Func<'_> {
                 let ok = &format!("ok{tmp}");
                 let default = &format!("default{tmp}");
                 let operand = &operands[0];
+                let memory = &self.memory();
                 quote_in! { self.body =>
                     $['\r']
-                    $len, $ok := i.module.Memory().ReadUint32Le(uint32($operand + $offset))
+                    $len, $ok := $memory.ReadUint32Le(uint32($operand + $offset))
                     $(match &self.result {
                         GoResult::Anon(GoType::ValueOrError(typ)) => {
                             if !$ok {
@@ -401,9 +760,10 @@ impl Bindgen for Func<'_> {
                 let ok = &format!("ok{tmp}");
                 let default = &format!("default{tmp}");
                 let operand = &operands[0];
+                let memory = &self.memory();
                 quote_in! { self.body =>
                     $['\r']
-                    $value, $ok := i.module.Memory().ReadUint32Le(uint32($operand + $offset))
+                    $value, $ok := $memory.ReadUint32Le(uint32($operand + $offset))
                     $(match &self.result {
                         GoResult::Anon(GoType::ValueOrError(typ)) => {
                             if !$ok {
@@ -428,6 +788,7 @@ impl Bindgen for Func<'_> {
             }
             Instruction::StringLift => {
                 let tmp = self.tmp();
+                let returns_view = self.returns_view();
                 let buf = &format!("buf{tmp}");
                 let ok = &format!("ok{tmp}");
                 let default = &format!("default{tmp}");
@@ -436,9 +797,10 @@ impl Bindgen for Func<'_> {
                 let len = &operands[1];
                 match self.direction {
                     Direction::Export { .. } => {
+                        let memory = &self.memory();
                         quote_in! { self.body =>
                             $['\r']
-                            $buf, $ok := i.module.Memory().Read($ptr, $len)
+                            $buf, $ok := readBytes($memory, $ptr, $len)
                             $(match &self.result {
                                 GoResult::Anon(GoType::ValueOrError(typ)) => {
                                     if !$ok {
@@ -458,13 +820,18 @@ impl Bindgen for Func<'_> {
                                     }
                                 }
                             })
-                            $str := string($buf)
+                            $(if returns_view {
+                                $str := $UNSAFE_STRING($UNSAFE_SLICE_DATA($buf), len($buf))
+                            } else {
+                                $str := string($buf)
+                            })
                         };
                     }
                     Direction::Import { .. } => {
+                        let memory = &self.memory();
                         quote_in! { self.body =>
                             $['\r']
-                            $buf, $ok := mod.Memory().Read($ptr, $len)
+                            $buf, $ok := readBytes($memory, $ptr, $len)
                             if !$ok {
                                 panic($ERRORS_NEW("failed to read bytes from memory"))
                             }
@@ -493,7 +860,13 @@ impl Bindgen for Func<'_> {
                 let tmp = self.tmp();
                 let value = &format!("value{tmp}");
                 let err = &format!("err{tmp}");
-                let typ = resolve_type(typ, resolve);
+                let is_import = self.is_import();
+                let ok_value = lifted(resolve, &self.naming, typ, ok_op);
+                // Tuples are lifted into a struct, as `TupleLift` does in blocks
+                let typ = match self.go_type(typ, resolve) {
+                    GoType::MultiReturn(typs) => GoType::Tuple(typs),
+                    typ => typ,
+                };
                 let tag = &operands[0];
                 quote_in! { self.body =>
                     $['\r']
@@ -502,10 +875,13 @@ impl Bindgen for Func<'_> {
                     switch $tag {
                     case 0:
                         $ok_block
-                        $value = $ok_op
+                        $value = $ok_value
                     case 1:
                         $err_block
                         $err = $ERRORS_NEW($err_op)
+                        $(if !is_import {
+                            i.writeError($err_op)
+                        })
                     default:
                         $err = $ERRORS_NEW("invalid variant discriminant for expected")
                     }
@@ -530,6 +906,7 @@ impl Bindgen for Func<'_> {
 
                 let tmp = self.tmp();
                 let err = &format!("err{tmp}");
+                let is_import = self.is_import();
                 let tag = &operands[0];
                 quote_in! { self.body =>
                     $['\r']
@@ -540,6 +917,9 @@ impl Bindgen for Func<'_> {
                     case 1:
                         $err_block
                         $err = $ERRORS_NEW($err_op)
+                        $(if !is_import {
+                            i.writeError($err_op)
+                        })
                     default:
                         $err = $ERRORS_NEW("invalid variant discriminant for expected")
                     }
@@ -548,34 +928,93 @@ impl Bindgen for Func<'_> {
                 results.push(Operand::SingleValue(err.into()));
             }
             Instruction::ResultLift { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::Return { amt, .. } => {
-                if *amt != 0 {
-                    let operand = &operands[0];
+            Instruction::Return { .. } if self.seq => {
+                quote_in! { self.body =>
+                    $['\r']
+                    return nil
+                };
+            }
+            Instruction::Return { amt, func } => {
+                let returns_view = self.returns_view();
+                let value = (*amt != 0).then(|| match (&self.direction, &func.result) {
+                    (Direction::Export, Some(typ)) => {
+                        lifted(resolve, &self.naming, typ, &operands[0])
+                    }
+                    _ => quote!($(&operands[0])),
+                });
+                if let Some((name, args)) = &self.call {
+                    let results = match &value {
+                        Some(value) => quote!(, $value),
+                        None => Tokens::new(),
+                    };
                     quote_in! { self.body =>
                         $['\r']
-                        return $operand
+                        if i.callLogger != nil {
+                            i.logCall(ctx, $(quoted(name)), []uint64{$(for arg in args join (, ) => uint64($arg))}$results)
+                        }
+                    };
+                }
+                if let Some(value) = value {
+                    quote_in! { self.body =>
+                        $['\r']
+                        $(if returns_view {
+                            return $value, cleanup
+                        } else {
+                            return $value
+                        })
                     };
                 }
             }
             Instruction::CallInterface { func, .. } => {
-                let ident = GoIdentifier::public(&func.name);
+                let ident = match self.direction {
+                    Direction::Import { method_name, .. } => method_name,
+                    Direction::Export => todo!("TODO(#10): handle export direction"),
+                };
                 let tmp = self.tmp();
-                let args = quote!($(for op in operands.iter() join (, ) => $op));
+                let args = operands
+                    .iter()
+                    .zip(&func.params)
+                    .map(|(op, (_, typ))| lifted(resolve, &self.naming, typ, op))
+                    .collect::<Vec<_>>();
+                let args = quote!($(for arg in args join (, ) => $arg));
                 let returns = match &func.result {
                     None => GoType::Nothing,
-                    Some(typ) => resolve_type(typ, resolve),
+                    Some(typ) => self.go_type(typ, resolve),
                 };
                 let value = &format!("value{tmp}");
                 let err = &format!("err{tmp}");
                 let ok = &format!("ok{tmp}");
+                let values = match &returns {
+                    GoType::MultiReturn(typs) => (0..typs.len())
+                        .map(|i| format!("value{tmp}_{i}"))
+                        .collect::<Vec<_>>(),
+                    _ => Vec::new(),
+                };
                 match self.direction {
                     Direction::Export { .. } => todo!("TODO(#10): handle export direction"),
                     Direction::Import { param_name, .. } => {
                         quote_in! { self.body =>
                             $['\r']
-                            $(match returns {
+                            $(match &returns {
                                 GoType::Nothing => $param_name.$ident(ctx, $args),
-                                GoType::Bool | GoType::Uint32 | GoType::Interface | GoType::String | GoType::UserDefined(_) => $value := $param_name.$ident(ctx, $args),
+                                GoType::Bool
+                                | GoType::Uint8
+                                | GoType::Uint16
+                                | GoType::Uint32
+                                | GoType::Uint64
+                                | GoType::Int8
+                                | GoType::Int16
+                                | GoType::Int32
+                                | GoType::Int64
+                                | GoType::Float32
+                                | GoType::Float64
+                                | GoType::Interface
+                                | GoType::String
+                                | GoType::Pointer(_)
+                                | GoType::UserDefined(_) => $value := $param_name.$ident(ctx, $args),
+                                GoType::Defined(_, underlying) => {
+                                    $value := $(underlying.as_ref())($param_name.$ident(ctx, $args))
+                                }
                                 GoType::Error => $err := $param_name.$ident(ctx, $args),
                                 GoType::ValueOrError(_) => {
                                     $value, $err := $param_name.$ident(ctx, $args)
@@ -583,6 +1022,9 @@ impl Bindgen for Func<'_> {
                                 GoType::ValueOrOk(_) => {
                                     $value, $ok := $param_name.$ident(ctx, $args)
                                 }
+                                GoType::MultiReturn(_) => {
+                                    $(for value in &values join (, ) => $value) := $param_name.$ident(ctx, $args)
+                                }
                                 _ => $(comment(&["TODO(#9): handle return type"]))
                             })
                         }
@@ -591,9 +1033,20 @@ impl Bindgen for Func<'_> {
                 match returns {
                     GoType::Nothing => (),
                     GoType::Bool
+                    | GoType::Uint8
+                    | GoType::Uint16
                     | GoType::Uint32
+                    | GoType::Uint64
+                    | GoType::Int8
+                    | GoType::Int16
+                    | GoType::Int32
+                    | GoType::Int64
+                    | GoType::Float32
+                    | GoType::Float64
                     | GoType::Interface
                     | GoType::UserDefined(_)
+                    | GoType::Defined(..)
+                    | GoType::Pointer(_)
                     | GoType::String => {
                         results.push(Operand::SingleValue(value.into()));
                     }
@@ -606,6 +1059,9 @@ impl Bindgen for Func<'_> {
                     GoType::ValueOrOk(_) => {
                         results.push(Operand::MultiValue((value.into(), ok.into())))
                     }
+                    GoType::MultiReturn(_) => {
+                        results.push(Operand::Tuple(values));
+                    }
                     _ => todo!("TODO(#9): handle return type - {returns:?}"),
                 }
             }
@@ -619,25 +1075,17 @@ impl Bindgen for Func<'_> {
                 let tag = &operands[0];
                 let ptr = &operands[1];
                 if let Operand::Literal(byte) = tag {
-                    match &self.direction {
-                        Direction::Export => {
-                            quote_in! { self.body =>
-                                $['\r']
-                                i.module.Memory().WriteByte($ptr+$offset, $byte)
-                            }
-                        }
-                        Direction::Import { .. } => {
-                            quote_in! { self.body =>
-                                $['\r']
-                                mod.Memory().WriteByte($ptr+$offset, $byte)
-                            }
-                        }
+                    let memory = &self.memory();
+                    quote_in! { self.body =>
+                        $['\r']
+                        writeByte($memory, $ptr+$offset, $byte)
                     }
                 } else {
                     let tmp = self.tmp();
                     let byte = format!("byte{tmp}");
                     match &self.direction {
                         Direction::Export => {
+                            let memory = &self.memory();
                             quote_in! { self.body =>
                                 $['\r']
                                 var $(&byte) uint8
@@ -650,10 +1098,11 @@ impl Bindgen for Func<'_> {
                                     $(comment(["TODO(#8): Return an error if the return type allows it"]))
                                     panic($ERRORS_NEW("invalid int8 value encountered"))
                                 }
-                                i.module.Memory().WriteByte($ptr+$offset, $byte)
+                                writeByte($memory, $ptr+$offset, $byte)
                             }
                         }
                         Direction::Import { .. } => {
+                            let memory = &self.memory();
                             quote_in! { self.body =>
                                 $['\r']
                                 var $(&byte) uint8
@@ -665,7 +1114,7 @@ impl Bindgen for Func<'_> {
                                 default:
                                     panic($ERRORS_NEW("invalid int8 value encountered"))
                                 }
-                                mod.Memory().WriteByte($ptr+$offset, $byte)
+                                writeByte($memory, $ptr+$offset, $byte)
                             }
                         }
                     }
@@ -676,19 +1125,10 @@ impl Bindgen for Func<'_> {
                 let offset = offset.size_wasm32();
                 let tag = &operands[0];
                 let ptr = &operands[1];
-                match &self.direction {
-                    Direction::Export => {
-                        quote_in! { self.body =>
-                            $['\r']
-                            i.module.Memory().WriteUint32Le($ptr+$offset, $tag)
-                        }
-                    }
-                    Direction::Import { .. } => {
-                        quote_in! { self.body =>
-                            $['\r']
-                            mod.Memory().WriteUint32Le($ptr+$offset, $tag)
-                        }
-                    }
+                let memory = &self.memory();
+                quote_in! { self.body =>
+                    $['\r']
+                    $memory.WriteUint32Le($ptr+$offset, $tag)
                 }
             }
             Instruction::LengthStore { offset } => {
@@ -696,19 +1136,10 @@ impl Bindgen for Func<'_> {
                 let offset = offset.size_wasm32();
                 let len = &operands[0];
                 let ptr = &operands[1];
-                match &self.direction {
-                    Direction::Export => {
-                        quote_in! { self.body =>
-                            $['\r']
-                            i.module.Memory().WriteUint32Le($ptr+$offset, uint32($len))
-                        }
-                    }
-                    Direction::Import { .. } => {
-                        quote_in! { self.body =>
-                            $['\r']
-                            mod.Memory().WriteUint32Le($ptr+$offset, uint32($len))
-                        }
-                    }
+                let memory = &self.memory();
+                quote_in! { self.body =>
+                    $['\r']
+                    $memory.WriteUint32Le($ptr+$offset, uint32($len))
                 }
             }
             Instruction::PointerStore { offset } => {
@@ -716,48 +1147,50 @@ impl Bindgen for Func<'_> {
                 let offset = offset.size_wasm32();
                 let value = &operands[0];
                 let ptr = &operands[1];
-                match &self.direction {
-                    Direction::Export => {
-                        quote_in! { self.body =>
-                            $['\r']
-                            i.module.Memory().WriteUint32Le($ptr+$offset, uint32($value))
-                        }
-                    }
-                    Direction::Import { .. } => {
-                        quote_in! { self.body =>
-                            $['\r']
-                            mod.Memory().WriteUint32Le($ptr+$offset, uint32($value))
-                        }
-                    }
+                let memory = &self.memory();
+                quote_in! { self.body =>
+                    $['\r']
+                    $memory.WriteUint32Le($ptr+$offset, uint32($value))
                 }
             }
             Instruction::ResultLower {
                 result:
                     Result_ {
-                        ok: Some(_),
+                        ok: Some(typ),
                         err: Some(Type::String),
                     },
+                results: result_types,
                 ..
             } => {
-                let (err_block, _) = self.pop_block();
-                let (ok_block, _) = self.pop_block();
+                let (mut err_block, err_results) = self.pop_block();
+                let (mut ok_block, ok_results) = self.pop_block();
+                let vars = self.variant_results(
+                    result_types,
+                    [(&mut ok_block, &ok_results), (&mut err_block, &err_results)],
+                    results,
+                );
                 let operand = &operands[0];
                 let (ok, err) = match operand {
                     Operand::Literal(_) => {
                         panic!("impossible: expected Operand::MultiValue but got Operand::Literal")
                     }
-                    Operand::SingleValue(_) => panic!(
-                        "impossible: expected Operand::MultiValue but got Operand::SingleValue"
-                    ),
-                    Operand::MultiValue(bindings) => bindings,
+                    // Results passed to exports are an argument with its value,
+                    // and one suffixed with `Err` with its error.
+                    Operand::SingleValue(arg) => (arg.clone(), format!("{arg}Err")),
+                    Operand::Tuple(_) => {
+                        panic!("impossible: expected Operand::MultiValue but got Operand::Tuple")
+                    }
+                    Operand::MultiValue(bindings) => bindings.clone(),
                 };
+                let (ok, err) = (&ok, &err);
                 quote_in! { self.body =>
                     $['\r']
+                    $vars
                     if $err != nil {
                         variantPayload := $err.Error()
                         $err_block
                     } else {
-                        variantPayload := $ok
+                        variantPayload := $(lowered(resolve, &self.naming, typ, ok))
                         $ok_block
                     }
                 };
@@ -768,13 +1201,20 @@ impl Bindgen for Func<'_> {
                         ok: None,
                         err: Some(Type::String),
                     },
+                results: result_types,
                 ..
             } => {
-                let (err, _) = self.pop_block();
-                let (ok, _) = self.pop_block();
+                let (mut err, err_results) = self.pop_block();
+                let (mut ok, ok_results) = self.pop_block();
+                let vars = self.variant_results(
+                    result_types,
+                    [(&mut ok, &ok_results), (&mut err, &err_results)],
+                    results,
+                );
                 let err_result = &operands[0];
                 quote_in! { self.body =>
                     $['\r']
+                    $vars
                     if $err_result != nil {
                         variantPayload := $err_result.Error()
                         $err
@@ -792,9 +1232,32 @@ impl Bindgen for Func<'_> {
                 let tmp = self.tmp();
                 let result = &format!("result{tmp}");
                 let ok = &format!("ok{tmp}");
-                let typ = resolve_type(payload, resolve);
+                let some_value = lifted(resolve, &self.naming, payload, some_result);
+                // Tuples are lifted into a struct, as `TupleLift` does in blocks
+                let typ = match self.go_type(payload, resolve) {
+                    GoType::MultiReturn(typs) => GoType::Tuple(typs),
+                    typ => typ,
+                };
                 let op = &operands[0];
 
+                // Pointers are only allocated for `Some`, and stay nil for `None`
+                if self.option_style == OptionStyle::Pointer {
+                    let value = &format!("value{tmp}");
+                    quote_in! { self.body =>
+                        $['\r']
+                        var $result *$typ
+                        if $op != 0 {
+                            $some
+                            $value := $some_value
+                            $result = &$value
+                        } else {
+                            $none
+                        }
+                    };
+                    results.push(Operand::SingleValue(result.into()));
+                    return;
+                }
+
                 quote_in! { self.body =>
                     $['\r']
                     var $result $typ
@@ -805,17 +1268,20 @@ impl Bindgen for Func<'_> {
                     } else {
                         $some
                         $ok = true
-                        $result = $some_result
+                        $result = $some_value
                     }
                 };
 
                 results.push(Operand::MultiValue((result.into(), ok.into())));
             }
             Instruction::OptionLower {
-                payload: Type::String,
+                payload,
                 results: result_types,
                 ..
-            } => {
+            } if matches!(payload, Type::String)
+                || self.option_style == OptionStyle::Pointer
+                || matches!(operands[0], Operand::MultiValue(_)) =>
+            {
                 let (mut some_block, some_results) = self.pop_block();
                 let (mut none_block, none_results) = self.pop_block();
 
@@ -849,6 +1315,18 @@ impl Bindgen for Func<'_> {
                     Operand::Literal(_) => {
                         panic!("impossible: expected Operand::MultiValue but got Operand::Literal")
                     }
+                    Operand::SingleValue(value) if self.option_style == OptionStyle::Pointer => {
+                        quote_in! { self.body =>
+                            $['\r']
+                            $vars
+                            if $value != nil {
+                                variantPayload := *$value
+                                $some_block
+                            } else {
+                                $none_block
+                            }
+                        };
+                    }
                     // TODO(#7): This is a weird hack to implement `option<string>`
                     // as arguments that currently only works for strings
                     // because it checks the empty string as the zero value to
@@ -876,34 +1354,67 @@ impl Bindgen for Func<'_> {
                             }
                         };
                     }
+                    Operand::Tuple(_) => {
+                        panic!("impossible: expected Operand::MultiValue but got Operand::Tuple")
+                    }
                 };
             }
             Instruction::OptionLower { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::RecordLower { record, .. } => {
+            Instruction::RecordLower { record, ty, .. } => {
                 let tmp = self.tmp();
                 let operand = &operands[0];
                 for field in record.fields.iter() {
-                    let struct_field = GoIdentifier::public(&field.name);
+                    let struct_field = self.renames.field(&self.naming, resolve, *ty, &field.name);
                     let var = &GoIdentifier::local(format!("{}{tmp}", &field.name));
+                    // Arrays are lowered like the slice they were lifted from
+                    let value = match field_array_len(field, resolve) {
+                        Some(_) => quote!($operand.$struct_field[:]),
+                        None => lowered(
+                            resolve,
+                            &self.naming,
+                            &field.ty,
+                            quote!($operand.$struct_field),
+                        ),
+                    };
                     quote_in! { self.body =>
                         $['\r']
-                        $var := $operand.$struct_field
+                        $var := $value
                     }
                     results.push(Operand::SingleValue(var.into()))
                 }
             }
-            Instruction::RecordLift { record, name, .. } => {
+            Instruction::RecordLift {
+                record, name, ty, ..
+            } => {
                 let tmp = self.tmp();
                 let value = &format!("value{tmp}");
-                let fields = record
-                    .fields
-                    .iter()
-                    .zip(operands)
-                    .map(|(field, op)| (GoIdentifier::public(&field.name), op));
+                let mut fields = Vec::new();
+                for (field, op) in record.fields.iter().zip(operands) {
+                    let struct_field = self.renames.field(&self.naming, resolve, *ty, &field.name);
+                    let value = match field_array_len(field, resolve) {
+                        // Converting a shorter slice to an array panics, but a
+                        // longer one would be silently truncated.
+                        Some(len) => {
+                            let message = format!(
+                                "expected {len} bytes for {name}.{}, but got %d",
+                                field.name
+                            );
+                            quote_in! { self.body =>
+                                $['\r']
+                                if len($op) != $len {
+                                    panic($FMT_ERRORF($(quoted(message)), len($op)))
+                                }
+                            };
+                            quote!([$len]byte($op))
+                        }
+                        None => lifted(resolve, &self.naming, &field.ty, op),
+                    };
+                    fields.push((struct_field, value));
+                }
 
                 quote_in! {self.body =>
                     $['\r']
-                    $value := $(GoIdentifier::public(*name)){
+                    $value := $(GoIdentifier::public(self.naming.name(name))){
                         $(for (name, op) in fields join ($['\r']) => $name: $op,)
                     }
                 };
@@ -929,34 +1440,60 @@ impl Bindgen for Func<'_> {
                 let operand = &operands[0];
                 let size = self.sizes.size(element).size_wasm32();
                 let align = self.sizes.align(element).align_wasm32();
+                // Lists returned by the host are allocated with the realloc
+                // function of the calling module, and a failure traps the guest.
+                let realloc = match self.direction {
+                    Direction::Export => {
+                        self.allocates = true;
+                        quote!(i.realloc($(quoted(*realloc_name))))
+                    }
+                    Direction::Import { .. } => {
+                        quote!(mod.ExportedFunction($(quoted(*realloc_name))))
+                    }
+                };
 
                 quote_in! { self.body =>
                     $['\r']
                     $vec := $operand
                     $len := uint64(len($vec))
-                    $result, $err := i.module.ExportedFunction($(quoted(*realloc_name))).Call(ctx, 0, 0, $align, $len * $size)
-                    $(match &self.result {
-                        GoResult::Anon(GoType::ValueOrError(typ)) => {
-                            if $err != nil {
-                                var $default $(typ.as_ref())
-                                return $default, $err
-                            }
+                    $(comment(&[
+                        "Empty (and nil) slices don't allocate any guest memory, but the",
+                        "pointer must still be non-null and aligned for the element type",
+                    ]))
+                    $ptr := uint64($align)
+                    if $len > 0 {
+                        $result, $err := $realloc.Call(ctx, 0, 0, $align, $len * $size)
+                        if $err == nil && $result[0] == 0 {
+                            $err = ErrGuestAllocFailed
                         }
-                        GoResult::Anon(GoType::Error) => {
-                            if $err != nil {
-                                return $err
+                        $(match (&self.direction, &self.result) {
+                            (Direction::Import { .. }, _) => {
+                                if $err != nil {
+                                    panic($err)
+                                }
                             }
-                        }
-                        GoResult::Anon(_) | GoResult::Empty => {
-                            $(comment(&["The return type doesn't contain an error so we panic if one is encountered"]))
-                            if $err != nil {
-                                panic($err)
+                            (_, GoResult::Anon(GoType::ValueOrError(typ))) => {
+                                if $err != nil {
+                                    var $default $(typ.as_ref())
+                                    return $default, $err
+                                }
                             }
-                        }
-                    })
-                    $ptr := $result[0]
+                            (_, GoResult::Anon(GoType::Error)) => {
+                                if $err != nil {
+                                    return $err
+                                }
+                            }
+                            (_, GoResult::Anon(_) | GoResult::Empty) => {
+                                $(comment(&["The return type doesn't contain an error so we panic if one is encountered"]))
+                                if $err != nil {
+                                    panic($err)
+                                }
+                            }
+                        })
+                        $ptr = $result[0]
+                    }
                     for idx := uint64(0); idx < $len; idx++ {
-                        $iter_element := $vec[idx]
+                        $iter_element := $(lowered(resolve, &self.naming, element, quote!($vec[idx])))
                         $iter_base := uint32($ptr + uint64(idx) * uint64($size))
                         $body
                     }
@@ -964,7 +1501,7 @@ impl Bindgen for Func<'_> {
                 results.push(Operand::SingleValue(ptr.into()));
                 results.push(Operand::SingleValue(len.into()));
             }
-            Instruction::ListLift { element, .. } => {
+            Instruction::ListLift { element, ty, .. } => {
                 let (body, body_results) = self.pop_block();
                 let tmp = self.tmp();
                 let size = self.sizes.size(element).size_wasm32();
@@ -975,15 +1512,78 @@ impl Bindgen for Func<'_> {
 
                 let base_operand = &operands[0];
                 let len_operand = &operands[1];
-                let body_result = &body_results[0];
+                let body_result = &lifted(resolve, &self.naming, element, &body_results[0]);
 
-                let typ = resolve_type(element, resolve);
+                // Bytes passed to the host are read from guest memory at once,
+                // rather than byte by byte.
+                if let (Direction::Import { .. }, Type::U8) = (&self.direction, element) {
+                    let buf = &format!("buf{tmp}");
+                    let ok = &format!("ok{tmp}");
+                    let memory = &self.memory();
+                    quote_in! { self.body =>
+                        $['\r']
+                        $buf, $ok := readBytes($memory, $base_operand, $len_operand)
+                        if !$ok {
+                            panic($ERRORS_NEW("failed to read bytes from memory"))
+                        }
+                    };
+                    if self.byte_views {
+                        results.push(Operand::SingleValue(buf.into()));
+                    } else {
+                        quote_in! { self.body =>
+                            $['\r']
+                            $(comment(&["The bytes are copied, as the memory may be reused once the host function returns"]))
+                            $result := make([]byte, len($buf))
+                            copy($result, $buf)
+                        };
+                        results.push(Operand::SingleValue(result.into()));
+                    }
+                    return;
+                }
+
+                // Bytes returned as a view are left in guest memory until the
+                // caller cleans them up.
+                if self.returns_view() {
+                    let buf = &format!("buf{tmp}");
+                    let ok = &format!("ok{tmp}");
+                    let memory = &self.memory();
+                    quote_in! { self.body =>
+                        $['\r']
+                        $buf, $ok := readBytes($memory, $base_operand, $len_operand)
+                        if !$ok {
+                            panic($ERRORS_NEW("failed to read bytes from memory"))
+                        }
+                    };
+                    results.push(Operand::SingleValue(buf.into()));
+                    return;
+                }
+
+                // Only the outermost list is streamed, lists nested in its
+                // elements are still lifted into slices.
+                if self.seq && self.block_storage.is_empty() {
+                    quote_in! { self.body =>
+                        $['\r']
+                        $base := $base_operand
+                        $len := $len_operand
+                        for $idx := uint32(0); $idx < $len; $idx++ {
+                            base := $base + $idx * $size
+                            $body
+                            if !yield($body_result, nil) {
+                                return nil
+                            }
+                        }
+                    }
+                    results.push(Operand::Literal("nil".into()));
+                    return;
+                }
+
+                let typ = self.go_type(&Type::Id(*ty), resolve);
 
                 quote_in! { self.body =>
                     $['\r']
                     $base := $base_operand
                     $len := $len_operand
-                    $result := make([]$typ, $len)
+                    $result := make($typ, $len)
                     for $idx := uint32(0); $idx < $len; $idx++ {
                         base := $base + $idx * $size
                         $body
@@ -1084,19 +1684,126 @@ impl Bindgen for Func<'_> {
                 results.push(Operand::SingleValue(enum_tmp.to_string()));
             }
             Instruction::Bitcasts { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::I32Load8S { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::I32Load16U { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::I32Load16S { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::I64Load { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::F32Load { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::F64Load { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::I32Store16 { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::I64Store { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::F32Store { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::F64Store { .. } => todo!("implement instruction: {inst:?}"),
+            // Results too large to be returned directly are read from the
+            // memory the returned pointer refers to.
+            Instruction::I32Load8S { offset } => {
+                // TODO(#58): Support additional ArchitectureSize
+                let value = self.load("ReadByte", "byte", &operands[0], offset.size_wasm32());
+                let result = self.sign_extend(GoType::Int8, &value);
+                results.push(result);
+            }
+            Instruction::I32Load16U { offset } => {
+                let value = self.load("ReadUint16Le", "i16", &operands[0], offset.size_wasm32());
+                results.push(Operand::SingleValue(value));
+            }
+            Instruction::I32Load16S { offset } => {
+                let value = self.load("ReadUint16Le", "i16", &operands[0], offset.size_wasm32());
+                let result = self.sign_extend(GoType::Int16, &value);
+                results.push(result);
+            }
+            Instruction::I64Load { offset } => {
+                let value = self.load("ReadUint64Le", "i64", &operands[0], offset.size_wasm32());
+                results.push(Operand::SingleValue(value));
+            }
+            // Host functions use core floats as Go floats, as `F32FromCoreF32`
+            // and `F64FromCoreF64` pass them through, so their bits are decoded
+            // here. The bits are read with the integer methods `wrappedMemory`
+            // overrides.
+            Instruction::F32Load { offset } if self.is_import() => {
+                let value = &self.load("ReadUint32Le", "f32", &operands[0], offset.size_wasm32());
+                let tmp = self.tmp();
+                let result = &format!("result{tmp}");
+                quote_in! { self.body =>
+                    $['\r']
+                    $result := $MATH_FLOAT32_FROM_BITS($value)
+                };
+                results.push(Operand::SingleValue(result.into()));
+            }
+            Instruction::F64Load { offset } if self.is_import() => {
+                let value = &self.load("ReadUint64Le", "f64", &operands[0], offset.size_wasm32());
+                let tmp = self.tmp();
+                let result = &format!("result{tmp}");
+                quote_in! { self.body =>
+                    $['\r']
+                    $result := $MATH_FLOAT64_FROM_BITS($value)
+                };
+                results.push(Operand::SingleValue(result.into()));
+            }
+            // Floats are read by their bits, which `F32FromCoreF32` and
+            // `F64FromCoreF64` decode from a `uint64`.
+            Instruction::F32Load { offset } => {
+                let value = self.load("ReadUint32Le", "f32", &operands[0], offset.size_wasm32());
+                let result = self.convert(GoType::Uint64, &Operand::SingleValue(value));
+                results.push(result);
+            }
+            Instruction::F64Load { offset } => {
+                let value = self.load("ReadUint64Le", "f64", &operands[0], offset.size_wasm32());
+                results.push(Operand::SingleValue(value));
+            }
+            Instruction::I32Store16 { offset } => {
+                // TODO(#58): Support additional ArchitectureSize
+                let offset = offset.size_wasm32();
+                let value = &operands[0];
+                let ptr = &operands[1];
+                let memory = &self.memory();
+                quote_in! { self.body =>
+                    $['\r']
+                    $memory.WriteUint16Le($ptr+$offset, uint16($value))
+                }
+            }
+            // Host functions store Go floats, whose bits are written with the
+            // integer methods `wrappedMemory` overrides.
+            Instruction::F32Store { offset } if self.is_import() => {
+                // TODO(#58): Support additional ArchitectureSize
+                let offset = offset.size_wasm32();
+                let value = &operands[0];
+                let ptr = &operands[1];
+                let memory = &self.memory();
+                quote_in! { self.body =>
+                    $['\r']
+                    $memory.WriteUint32Le($ptr+$offset, $MATH_FLOAT32_BITS($value))
+                }
+            }
+            Instruction::F64Store { offset } if self.is_import() => {
+                // TODO(#58): Support additional ArchitectureSize
+                let offset = offset.size_wasm32();
+                let value = &operands[0];
+                let ptr = &operands[1];
+                let memory = &self.memory();
+                quote_in! { self.body =>
+                    $['\r']
+                    $memory.WriteUint64Le($ptr+$offset, $MATH_FLOAT64_BITS($value))
+                }
+            }
+            // Floats are stored by their bits, as encoded by `CoreF32FromF32` and
+            // `CoreF64FromF64`.
+            Instruction::I64Store { offset } | Instruction::F64Store { offset } => {
+                // TODO(#58): Support additional ArchitectureSize
+                let offset = offset.size_wasm32();
+                let value = &operands[0];
+                let ptr = &operands[1];
+                let memory = &self.memory();
+                quote_in! { self.body =>
+                    $['\r']
+                    $memory.WriteUint64Le($ptr+$offset, uint64($value))
+                }
+            }
+            Instruction::F32Store { offset } => {
+                // TODO(#58): Support additional ArchitectureSize
+                let offset = offset.size_wasm32();
+                let value = &operands[0];
+                let ptr = &operands[1];
+                let memory = &self.memory();
+                quote_in! { self.body =>
+                    $['\r']
+                    $memory.WriteUint32Le($ptr+$offset, uint32($value))
+                }
+            }
             Instruction::I32FromChar => todo!("implement instruction: {inst:?}"),
-            Instruction::I64FromU64 => todo!("implement instruction: {inst:?}"),
-            Instruction::I64FromS64 => todo!("implement instruction: {inst:?}"),
+            Instruction::I64FromU64 | Instruction::I64FromS64 => {
+                let result = self.convert(GoType::Uint64, &operands[0]);
+                results.push(result);
+            }
             Instruction::I32FromS32 => {
                 let tmp = self.tmp();
                 let value = format!("value{tmp}");
@@ -1141,92 +1848,243 @@ impl Bindgen for Func<'_> {
                 };
                 results.push(Operand::SingleValue(result.into()));
             }
-            // TODO: Validate the Go cast truncates the upper bits in the I32
+            // Core values are either results of a call (`uint64`) or loaded from
+            // memory (`byte` or `uint32`), and a conversion handles all of them.
             Instruction::S8FromI32 => {
-                let tmp = self.tmp();
-                let result = &format!("result{tmp}");
-                let operand = &operands[0];
-                quote_in! { self.body =>
-                    $['\r']
-                    $result := int8($WAZERO_API_DECODE_I32($operand))
-                };
-                results.push(Operand::SingleValue(result.into()));
+                let result = self.convert(GoType::Int8, &operands[0]);
+                results.push(result);
             }
-            // TODO: Validate the Go cast truncates the upper bits in the I32
             Instruction::U8FromI32 => {
+                let result = self.convert(GoType::Uint8, &operands[0]);
+                results.push(result);
+            }
+            Instruction::S16FromI32 => {
+                let result = self.convert(GoType::Int16, &operands[0]);
+                results.push(result);
+            }
+            Instruction::U16FromI32 => {
+                let result = self.convert(GoType::Uint16, &operands[0]);
+                results.push(result);
+            }
+            Instruction::S32FromI32 => {
+                let result = self.convert(GoType::Int32, &operands[0]);
+                results.push(result);
+            }
+            Instruction::U32FromI32 => {
+                let result = self.convert(GoType::Uint32, &operands[0]);
+                results.push(result);
+            }
+            Instruction::S64FromI64 => {
+                let result = self.convert(GoType::Int64, &operands[0]);
+                results.push(result);
+            }
+            Instruction::U64FromI64 => {
+                let result = self.convert(GoType::Uint64, &operands[0]);
+                results.push(result);
+            }
+            Instruction::CharFromI32 => todo!("implement instruction: {inst:?}"),
+            Instruction::F32FromCoreF32 => {
                 let tmp = self.tmp();
                 let result = &format!("result{tmp}");
                 let operand = &operands[0];
                 quote_in! { self.body =>
                     $['\r']
-                    $result := uint8($WAZERO_API_DECODE_U32($operand))
+                    $result := $WAZERO_API_DECODE_F32($operand)
                 };
                 results.push(Operand::SingleValue(result.into()));
             }
-            // TODO: Validate the Go cast truncates the upper bits in the I32
-            Instruction::S16FromI32 => {
+            Instruction::F64FromCoreF64 => {
                 let tmp = self.tmp();
                 let result = &format!("result{tmp}");
                 let operand = &operands[0];
                 quote_in! { self.body =>
                     $['\r']
-                    $result := int16($WAZERO_API_DECODE_I32($operand))
+                    $result := $WAZERO_API_DECODE_F64($operand)
                 };
                 results.push(Operand::SingleValue(result.into()));
             }
-            // TODO: Validate the Go cast truncates the upper bits in the I32
-            Instruction::U16FromI32 => {
+            Instruction::TupleLower { tuple, .. } => {
+                // Tuples are multiple values when returned from a function, and
+                // structs with a field per element otherwise.
+                let values = match &operands[0] {
+                    Operand::Tuple(values) => values.clone(),
+                    Operand::SingleValue(value) => (0..tuple.types.len())
+                        .map(|i| format!("{value}.F{i}"))
+                        .collect(),
+                    operand => panic!("expected tuple operand, got {operand:?}"),
+                };
+                for (value, typ) in values.iter().zip(&tuple.types) {
+                    match defined_type(typ, resolve, &self.naming) {
+                        Some(GoType::Defined(_, underlying)) => {
+                            let tmp = self.tmp();
+                            let result = format!("lowered{tmp}");
+                            quote_in! { self.body =>
+                                $['\r']
+                                $(&result) := $(*underlying)($value)
+                            };
+                            results.push(Operand::SingleValue(result));
+                        }
+                        _ => results.push(Operand::SingleValue(value.clone())),
+                    }
+                }
+            }
+            Instruction::TupleLift { tuple, .. } => {
+                let values = tuple
+                    .types
+                    .iter()
+                    .zip(operands)
+                    .map(|(typ, op)| lifted(resolve, &self.naming, typ, op))
+                    .collect::<Vec<_>>();
+                // Results are returned as multiple values, but tuples nested
+                // in them, such as list elements, are structs.
+                if self.block_storage.is_empty() {
+                    results.push(Operand::Tuple(
+                        values
+                            .iter()
+                            .map(|value| value.to_string().expect("failed to format value"))
+                            .collect(),
+                    ));
+                    return;
+                }
                 let tmp = self.tmp();
-                let result = &format!("result{tmp}");
-                let operand = &operands[0];
+                let value = &format!("value{tmp}");
+                let typ = GoType::Tuple(
+                    tuple
+                        .types
+                        .iter()
+                        .map(|typ| self.go_type(typ, resolve))
+                        .collect(),
+                );
                 quote_in! { self.body =>
                     $['\r']
-                    $result := uint16($WAZERO_API_DECODE_U32($operand))
+                    $value := $typ{
+                        $(for (i, value) in values.iter().enumerate() join ($['\r']) => $(format!("F{i}")): $value,)
+                    }
                 };
-                results.push(Operand::SingleValue(result.into()));
+                results.push(Operand::SingleValue(value.into()));
             }
-            Instruction::S32FromI32 => {
+            // Flags are split into 32 bits per core Wasm value, so flags with more
+            // than 32 members are lowered to several values.
+            Instruction::FlagsLower { flags, .. } => {
                 let tmp = self.tmp();
-                let result = &format!("result{tmp}");
                 let operand = &operands[0];
+                let count = flags.repr().count();
+                for i in 0..count {
+                    let value = if count == 1 {
+                        format!("flags{tmp}")
+                    } else {
+                        format!("flags{tmp}_{i}")
+                    };
+                    let shift = 32 * i;
+                    quote_in! { self.body =>
+                        $['\r']
+                        $(if shift == 0 {
+                            $(&value) := uint32($operand)
+                        } else {
+                            $(&value) := uint32($operand >> $shift)
+                        })
+                    };
+                    results.push(Operand::SingleValue(value));
+                }
+            }
+            Instruction::FlagsLift { ty, .. } => {
+                let tmp = self.tmp();
+                let value = &format!("flags{tmp}");
+                let typ = resolve_type_with(&Type::Id(*ty), resolve, false, &self.naming);
+                let mut bits = Tokens::<Go>::new();
+                for (i, operand) in operands.iter().enumerate() {
+                    let shift = 32 * i;
+                    if shift == 0 {
+                        quote_in!(bits => uint64(uint32($operand)));
+                    } else {
+                        bits.space();
+                        quote_in!(bits => | uint64(uint32($operand))<<$shift);
+                    }
+                }
                 quote_in! { self.body =>
                     $['\r']
-                    $result := $WAZERO_API_DECODE_I32($operand)
+                    $value := $typ($bits)
                 };
-                results.push(Operand::SingleValue(result.into()));
+                results.push(Operand::SingleValue(value.into()));
             }
-            Instruction::S64FromI64 => todo!("implement instruction: {inst:?}"),
-            Instruction::U64FromI64 => todo!("implement instruction: {inst:?}"),
-            Instruction::CharFromI32 => todo!("implement instruction: {inst:?}"),
-            Instruction::F32FromCoreF32 => {
+            Instruction::VariantLift { .. } => {
+                todo!("implement instruction: {inst:?}")
+            }
+            Instruction::EnumLift { enum_, ty, .. } => {
+                let value = &operands[0];
                 let tmp = self.tmp();
-                let result = &format!("result{tmp}");
-                let operand = &operands[0];
+                let enum_tmp = &format!("enum{tmp}");
+                let typ = resolve_type_with(&Type::Id(*ty), resolve, false, &self.naming);
+
+                let mut cases: Tokens<Go> = Tokens::new();
+                for (i, case) in enum_.cases.iter().enumerate() {
+                    let case_name = GoIdentifier::public(case.name.clone());
+                    quote_in! { cases =>
+                        $['\r']
+                        case $i:
+                            $enum_tmp = $case_name
+                    };
+                }
+
                 quote_in! { self.body =>
                     $['\r']
-                    $result := $WAZERO_API_DECODE_F32($operand)
+                    var $enum_tmp $typ
+                    switch $value {
+                    $cases
+                    default:
+                        panic($ERRORS_NEW("invalid enum discriminant"))
+                    }
                 };
-                results.push(Operand::SingleValue(result.into()));
+
+                results.push(Operand::SingleValue(enum_tmp.to_string()));
             }
-            Instruction::F64FromCoreF64 => {
+            Instruction::Malloc {
+                realloc: realloc_name,
+                size,
+                align,
+            } => {
+                // Only exports spill their parameters to memory the host allocates
+                let Direction::Export = self.direction else {
+                    todo!("implement instruction: {inst:?}")
+                };
+                // TODO(#58): Support additional ArchitectureSize
+                let size = size.size_wasm32();
+                let align = align.align_wasm32();
                 let tmp = self.tmp();
                 let result = &format!("result{tmp}");
-                let operand = &operands[0];
+                let err = &format!("err{tmp}");
+                let default = &format!("default{tmp}");
+                let ptr = &format!("ptr{tmp}");
+                self.allocates = true;
                 quote_in! { self.body =>
                     $['\r']
-                    $result := $WAZERO_API_DECODE_F64($operand)
+                    $result, $err := i.realloc($(quoted(*realloc_name))).Call(ctx, 0, 0, $align, $size)
+                    if $err == nil && $result[0] == 0 {
+                        $err = ErrGuestAllocFailed
+                    }
+                    $(match &self.result {
+                        GoResult::Anon(GoType::ValueOrError(typ)) => {
+                            if $err != nil {
+                                var $default $(typ.as_ref())
+                                return $default, $err
+                            }
+                        }
+                        GoResult::Anon(GoType::Error) => {
+                            if $err != nil {
+                                return $err
+                            }
+                        }
+                        GoResult::Anon(_) | GoResult::Empty => {
+                            $(comment(&["The return type doesn't contain an error so we panic if one is encountered"]))
+                            if $err != nil {
+                                panic($err)
+                            }
+                        }
+                    })
+                    $ptr := uint32($result[0])
                 };
-                results.push(Operand::SingleValue(result.into()));
-            }
-            Instruction::TupleLower { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::TupleLift { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::FlagsLower { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::FlagsLift { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::VariantLift { .. } => {
-                todo!("implement instruction: {inst:?}")
+                results.push(Operand::SingleValue(ptr.into()));
             }
-            Instruction::EnumLift { .. } => todo!("implement instruction: {inst:?}"),
-            Instruction::Malloc { .. } => todo!("implement instruction: {inst:?}"),
             Instruction::HandleLower { .. } | Instruction::HandleLift { .. } => {
                 todo!("implement resources: {inst:?}")
             }
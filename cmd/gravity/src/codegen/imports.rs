@@ -1,37 +1,93 @@
-use std::collections::BTreeMap;
+use std::collections::{BTreeMap, BTreeSet};
 
 use genco::prelude::*;
 use wit_bindgen_core::{
-    abi::{AbiVariant, LiftLower},
+    abi::{AbiVariant, LiftLower, WasmType},
     wit_parser::{
-        Function, InterfaceId, Resolve, SizeAlign, Type, TypeDefKind, TypeId, World, WorldItem,
+        FlagsRepr, Function, InterfaceId, Resolve, SizeAlign, Type, TypeDefKind, TypeId, World,
+        WorldItem, WorldKey,
     },
 };
 
 use crate::{
     codegen::{
+        binary::BinaryGenerator,
         func::Func,
         ir::{
             AnalyzedFunction, AnalyzedImports, AnalyzedInterface, AnalyzedType, InterfaceMethod,
             Parameter, TypeDefinition, WitReturn,
         },
     },
+    field_array_len,
     go::{
-        GoIdentifier, GoResult, GoType,
-        imports::{CONTEXT_CONTEXT, WAZERO_API_MODULE},
+        GoIdentifier, GoResult, GoType, Naming, OptionStyle, Renames, comment,
+        imports::{
+            CONTEXT_CONTEXT, FMT_ERRORF, SLICES_EQUAL, SLICES_EQUAL_FUNC, WAZERO_API_DECODE_F32,
+            WAZERO_API_DECODE_F64, WAZERO_API_DECODE_U32, WAZERO_API_ENCODE_F32,
+            WAZERO_API_ENCODE_F64, WAZERO_API_ENCODE_U32, WAZERO_API_GO_MODULE_FUNC,
+            WAZERO_API_MODULE, WAZERO_API_VALUE_TYPE, WAZERO_API_VALUE_TYPE_F32,
+            WAZERO_API_VALUE_TYPE_F64, WAZERO_API_VALUE_TYPE_I32, WAZERO_API_VALUE_TYPE_I64,
+        },
     },
-    resolve_type,
+    resolve_host_wasm_type, resolve_type_with,
 };
 
 /// Analyzer for imports - only does analysis, no code generation
 pub struct ImportAnalyzer<'a> {
     resolve: &'a Resolve,
     world: &'a World,
+    naming: Naming,
+    renames: Renames,
+    option_style: OptionStyle,
+    named_lists: bool,
 }
 
 impl<'a> ImportAnalyzer<'a> {
     pub fn new(resolve: &'a Resolve, world: &'a World) -> Self {
-        Self { resolve, world }
+        Self {
+            resolve,
+            world,
+            naming: Naming::default(),
+            renames: Renames::default(),
+            option_style: OptionStyle::default(),
+            named_lists: false,
+        }
+    }
+
+    /// Set the naming strategies for record fields, types and functions.
+    pub fn with_naming(mut self, naming: Naming) -> Self {
+        self.naming = naming;
+        self
+    }
+
+    /// Set the Go identifiers overriding the generated ones.
+    pub fn with_renames(mut self, renames: Renames) -> Self {
+        self.renames = renames;
+        self
+    }
+
+    /// Set how `option<T>` is represented in Go.
+    pub fn with_option_style(mut self, option_style: OptionStyle) -> Self {
+        self.option_style = option_style;
+        self
+    }
+
+    /// Set whether named lists, e.g. `type rows = list<row>`, are defined
+    /// types such as `Rows` rather than inlined slices.
+    pub fn with_named_lists(mut self, named_lists: bool) -> Self {
+        self.named_lists = named_lists;
+        self
+    }
+
+    /// Resolves the Go type of a WIT type, with options in the configured
+    /// style.
+    fn go_type(&self, typ: &Type) -> GoType {
+        self.option_style.go_type(resolve_type_with(
+            typ,
+            self.resolve,
+            self.named_lists,
+            &self.naming,
+        ))
     }
 
     pub fn analyze(&self) -> AnalyzedImports {
@@ -40,10 +96,10 @@ impl<'a> ImportAnalyzer<'a> {
         let mut standalone_types = Vec::new();
         let mut standalone_functions = Vec::new();
 
-        for (_import_name, world_item) in world_imports.iter() {
+        for (import_name, world_item) in world_imports.iter() {
             match world_item {
                 WorldItem::Interface { id, .. } => {
-                    interfaces.push(self.analyze_interface(*id));
+                    interfaces.push(self.analyze_interface(import_name, *id));
                 }
                 WorldItem::Type(type_id) => {
                     if let Some(t) = self.analyze_type(*type_id) {
@@ -71,9 +127,21 @@ impl<'a> ImportAnalyzer<'a> {
         }
     }
 
-    fn analyze_interface(&self, interface_id: InterfaceId) -> AnalyzedInterface {
+    /// Analyzes an imported interface.
+    ///
+    /// Interfaces are keyed by their import name, so a world importing several
+    /// inline interfaces, e.g. `import app-log: interface { ... }` and
+    /// `import audit-log: interface { ... }`, gets a host interface for each.
+    fn analyze_interface(
+        &self,
+        import_name: &WorldKey,
+        interface_id: InterfaceId,
+    ) -> AnalyzedInterface {
         let interface = &self.resolve.interfaces[interface_id];
-        let interface_name = interface.name.as_ref().expect("interface missing name");
+        let interface_name = match import_name {
+            WorldKey::Name(name) => name,
+            WorldKey::Interface(_) => interface.name.as_ref().expect("interface missing name"),
+        };
 
         // Analyze methods
         let methods = interface
@@ -93,15 +161,7 @@ impl<'a> ImportAnalyzer<'a> {
         let go_interface_name =
             GoIdentifier::public(format!("i-{}-{}", self.world.name, interface_name));
 
-        let wazero_module_name = if let Some(package_id) = interface.package {
-            let package = &self.resolve.packages[package_id];
-            format!(
-                "{}:{}/{}",
-                package.name.namespace, package.name.name, interface_name
-            )
-        } else {
-            interface_name.to_string()
-        };
+        let wazero_module_name = self.resolve.name_world_key(import_name);
 
         AnalyzedInterface {
             name: interface_name.clone(),
@@ -113,25 +173,27 @@ impl<'a> ImportAnalyzer<'a> {
         }
     }
 
-    fn analyze_interface_method(&self, func: &Function, _interface_name: &str) -> InterfaceMethod {
+    fn analyze_interface_method(&self, func: &Function, interface_name: &str) -> InterfaceMethod {
         let parameters = func
             .params
             .iter()
             .map(|(name, wit_type)| Parameter {
                 name: GoIdentifier::private(name),
-                go_type: resolve_type(wit_type, self.resolve),
+                go_type: self.go_type(wit_type),
                 wit_type: *wit_type,
             })
             .collect();
 
         let return_type = func.result.as_ref().map(|wit_type| WitReturn {
-            go_type: resolve_type(wit_type, self.resolve),
+            go_type: self.go_type(wit_type),
             wit_type: *wit_type,
         });
 
         InterfaceMethod {
             name: func.name.clone(),
-            go_method_name: GoIdentifier::public(&func.name),
+            go_method_name: self
+                .renames
+                .function(&self.naming, Some(interface_name), func),
             parameters,
             return_type,
             wit_function: func.clone(),
@@ -142,10 +204,11 @@ impl<'a> ImportAnalyzer<'a> {
         let type_def = &self.resolve.types[type_id];
         let type_name = type_def.name.as_ref().expect("type missing name");
 
-        let go_type_name = GoIdentifier::public(type_name);
-        let definition = self.analyze_type_definition(&type_def.kind);
+        let go_type_name = GoIdentifier::public(self.naming.name(type_name));
+        let definition = self.analyze_type_definition(type_id);
 
         definition.map(|definition| AnalyzedType {
+            id: type_id,
             name: type_name.clone(),
             go_type_name,
             definition,
@@ -159,16 +222,20 @@ impl<'a> ImportAnalyzer<'a> {
     /// is probably a reference to an imported type that we have already analyzed.
     ///
     /// TODO: we should probably instead resolve and return type and dedup elsewhere.
-    fn analyze_type_definition(&self, kind: &TypeDefKind) -> Option<TypeDefinition> {
-        Some(match kind {
+    fn analyze_type_definition(&self, id: TypeId) -> Option<TypeDefinition> {
+        Some(match &self.resolve.types[id].kind {
             TypeDefKind::Record(record) => TypeDefinition::Record {
                 fields: record
                     .fields
                     .iter()
                     .map(|field| {
                         (
-                            GoIdentifier::public(&field.name),
-                            resolve_type(&field.ty, self.resolve),
+                            self.renames
+                                .field(&self.naming, self.resolve, id, &field.name),
+                            match field_array_len(field, self.resolve) {
+                                Some(len) => GoType::Array(len, Box::new(GoType::Uint8)),
+                                None => self.go_type(&field.ty),
+                            },
                         )
                     })
                     .collect(),
@@ -180,32 +247,29 @@ impl<'a> ImportAnalyzer<'a> {
                 cases: variant
                     .cases
                     .iter()
-                    .map(|case| {
-                        (
-                            case.name.clone(),
-                            case.ty.as_ref().map(|t| resolve_type(t, self.resolve)),
-                        )
-                    })
+                    .map(|case| (case.name.clone(), case.ty.as_ref().map(|t| self.go_type(t))))
                     .collect(),
             },
             TypeDefKind::Type(Type::Id(_)) => {
                 // TODO(#4):  Only skip this if we have already generated the type
                 return None;
             }
-            TypeDefKind::Type(Type::String) => TypeDefinition::Alias {
-                target: GoType::String,
+            TypeDefKind::Type(
+                typ @ (Type::Bool
+                | Type::U8
+                | Type::U16
+                | Type::U32
+                | Type::U64
+                | Type::S8
+                | Type::S16
+                | Type::S32
+                | Type::S64
+                | Type::F32
+                | Type::F64
+                | Type::String),
+            ) => TypeDefinition::Alias {
+                target: self.go_type(typ),
             },
-            TypeDefKind::Type(Type::Bool) => todo!("TODO(#4): generate bool type alias"),
-            TypeDefKind::Type(Type::U8) => todo!("TODO(#4): generate u8 type alias"),
-            TypeDefKind::Type(Type::U16) => todo!("TODO(#4): generate u16 type alias"),
-            TypeDefKind::Type(Type::U32) => todo!("TODO(#4): generate u32 type alias"),
-            TypeDefKind::Type(Type::U64) => todo!("TODO(#4): generate u64 type alias"),
-            TypeDefKind::Type(Type::S8) => todo!("TODO(#4): generate s8 type alias"),
-            TypeDefKind::Type(Type::S16) => todo!("TODO(#4): generate s16 type alias"),
-            TypeDefKind::Type(Type::S32) => todo!("TODO(#4): generate s32 type alias"),
-            TypeDefKind::Type(Type::S64) => todo!("TODO(#4): generate s64 type alias"),
-            TypeDefKind::Type(Type::F32) => todo!("TODO(#4): generate f32 type alias"),
-            TypeDefKind::Type(Type::F64) => todo!("TODO(#4): generate f64 type alias"),
             TypeDefKind::Type(Type::Char) => todo!("TODO(#4): generate char type alias"),
             TypeDefKind::Type(Type::ErrorContext) => {
                 todo!("TODO(#4): generate error context definition")
@@ -215,10 +279,27 @@ impl<'a> ImportAnalyzer<'a> {
             }
             TypeDefKind::Option(_) => todo!("TODO(#4): generate option type definition"),
             TypeDefKind::Result(_) => todo!("TODO(#4): generate result type definition"),
-            TypeDefKind::List(_) => todo!("TODO(#4): generate list type definition"),
+            // Named lists are only defined when they're used by name, and
+            // inlined as slices otherwise.
+            TypeDefKind::List(_) if !self.named_lists => return None,
+            TypeDefKind::List(_) => match self.go_type(&Type::Id(id)) {
+                GoType::Defined(_, target) => TypeDefinition::Alias { target: *target },
+                typ => unreachable!("named list resolved to {typ:?}"),
+            },
             TypeDefKind::Future(_) => todo!("TODO(#4): generate future type definition"),
             TypeDefKind::Stream(_) => todo!("TODO(#4): generate stream type definition"),
-            TypeDefKind::Flags(_) => todo!("TODO(#4):generate flags type definition"),
+            TypeDefKind::Flags(flags) => TypeDefinition::Flags {
+                flags: flags.flags.iter().map(|flag| flag.name.clone()).collect(),
+                repr: match flags.repr() {
+                    FlagsRepr::U8 => GoType::Uint8,
+                    FlagsRepr::U16 => GoType::Uint16,
+                    FlagsRepr::U32(1) => GoType::Uint32,
+                    FlagsRepr::U32(2) => GoType::Uint64,
+                    FlagsRepr::U32(_) => {
+                        todo!("TODO(#4): generate flags with more than 64 members")
+                    }
+                },
+            },
             TypeDefKind::Tuple(_) => todo!("TODO(#4):generate tuple type definition"),
             TypeDefKind::Resource => todo!("TODO(#5): implement resources"),
             TypeDefKind::Handle(_) => todo!("TODO(#5): implement resources"),
@@ -232,19 +313,16 @@ impl<'a> ImportAnalyzer<'a> {
             .iter()
             .map(|(name, wit_type)| Parameter {
                 name: GoIdentifier::private(name),
-                go_type: resolve_type(wit_type, self.resolve),
+                go_type: self.go_type(wit_type),
                 wit_type: *wit_type,
             })
             .collect();
 
-        let return_type = func
-            .result
-            .as_ref()
-            .map(|wit_type| resolve_type(wit_type, self.resolve));
+        let return_type = func.result.as_ref().map(|wit_type| self.go_type(wit_type));
 
         AnalyzedFunction {
             name: func.name.clone(),
-            go_name: GoIdentifier::public(&func.name),
+            go_name: self.renames.function(&self.naming, None, func),
             parameters,
             return_type,
         }
@@ -256,6 +334,13 @@ pub struct ImportCodeGenerator<'a> {
     resolve: &'a Resolve,
     analyzed: &'a AnalyzedImports,
     sizes: &'a SizeAlign,
+    naming: Naming,
+    renames: Renames,
+    byte_views: bool,
+    tinygo: bool,
+    option_style: OptionStyle,
+    named_lists: bool,
+    binary: bool,
 }
 
 impl<'a> ImportCodeGenerator<'a> {
@@ -265,9 +350,62 @@ impl<'a> ImportCodeGenerator<'a> {
             resolve,
             analyzed,
             sizes,
+            naming: Naming::default(),
+            renames: Renames::default(),
+            byte_views: false,
+            tinygo: false,
+            option_style: OptionStyle::default(),
+            named_lists: false,
+            binary: false,
         }
     }
 
+    /// Set the naming strategies for record fields, types and functions.
+    pub fn with_naming(mut self, naming: Naming) -> Self {
+        self.naming = naming;
+        self
+    }
+
+    /// Set the Go identifiers overriding the generated ones.
+    pub fn with_renames(mut self, renames: Renames) -> Self {
+        self.renames = renames;
+        self
+    }
+
+    /// Set whether `list<u8>` parameters of host functions are views into
+    /// guest memory instead of copies.
+    pub fn with_byte_views(mut self, byte_views: bool) -> Self {
+        self.byte_views = byte_views;
+        self
+    }
+
+    /// Set whether host functions are registered without reflection, which
+    /// TinyGo doesn't fully support.
+    pub fn with_tinygo(mut self, tinygo: bool) -> Self {
+        self.tinygo = tinygo;
+        self
+    }
+
+    /// Set how `option<T>` is represented in Go.
+    pub fn with_option_style(mut self, option_style: OptionStyle) -> Self {
+        self.option_style = option_style;
+        self
+    }
+
+    /// Set whether named lists, e.g. `type rows = list<row>`, are defined
+    /// types such as `Rows` rather than inlined slices.
+    pub fn with_named_lists(mut self, named_lists: bool) -> Self {
+        self.named_lists = named_lists;
+        self
+    }
+
+    /// Set whether records get `MarshalBinary` and `UnmarshalBinary` methods
+    /// encoding them in their Canonical ABI layout.
+    pub fn with_binary(mut self, binary: bool) -> Self {
+        self.binary = binary;
+        self
+    }
+
     /// Extract import chains for host module builders
     pub fn import_chains(&self) -> BTreeMap<String, Tokens<Go>> {
         let mut chains = BTreeMap::new();
@@ -302,6 +440,14 @@ impl<'a> ImportCodeGenerator<'a> {
     }
 }
 
+/// Returns whether the given WIT type is a `list<u8>`.
+fn is_byte_list(resolve: &Resolve, typ: &Type) -> bool {
+    match typ {
+        Type::Id(id) => matches!(resolve.types[*id].kind, TypeDefKind::List(Type::U8)),
+        _ => false,
+    }
+}
+
 impl FormatInto<Go> for ImportCodeGenerator<'_> {
     fn format_into(self, tokens: &mut Tokens<Go>) {
         // Generate interface type definitions
@@ -326,9 +472,21 @@ impl<'a> ImportCodeGenerator<'a> {
             .methods
             .iter()
             .map(|method| self.generate_method_signature(method));
+        let name = String::from(&interface.go_interface_name);
+        let docs = [
+            format!(
+                "{name} is implemented by the host for the {} import, and passed to {}.",
+                interface.wazero_module_name,
+                String::from(&self.analyzed.constructor_name),
+            ),
+            "Its methods receive the context passed to the instance method that called".to_string(),
+            "into the guest, so values, deadlines and cancellation propagate to them.".to_string(),
+            format!("Check that a type implements it with `var _ {name} = (*T)(nil)`."),
+        ];
 
         quote_in! { *tokens =>
             $['\n']
+            $(comment(docs))
             type $(&interface.go_interface_name) interface {
                 $(for method in methods join ($['\r']) => $method)
             }
@@ -341,8 +499,22 @@ impl<'a> ImportCodeGenerator<'a> {
             .clone()
             .map(|t| GoResult::Anon(t.go_type))
             .unwrap_or(GoResult::Empty);
+        // Views into guest memory must not outlive the call
+        let docs = method
+            .parameters
+            .iter()
+            .filter(|param| self.byte_views && is_byte_list(self.resolve, &param.wit_type))
+            .map(|param| {
+                format!(
+                    "{} is a view into guest memory, which is only valid until {} returns.",
+                    String::from(&param.name),
+                    String::from(&method.go_method_name),
+                )
+            })
+            .collect::<Vec<_>>();
 
         quote! {
+            $(comment(docs))
             $(&method.go_method_name)(
                 ctx $CONTEXT_CONTEXT,
                 $(for param in &method.parameters join ($['\r']) => $(&param.name) $(&param.go_type),)
@@ -350,22 +522,168 @@ impl<'a> ImportCodeGenerator<'a> {
         }
     }
 
+    /// Returns the names of all records, as in Go types, which are compared
+    /// with their generated `Equal` method.
+    fn record_names(&self) -> BTreeSet<String> {
+        self.analyzed
+            .interfaces
+            .iter()
+            .flat_map(|interface| &interface.types)
+            .chain(&self.analyzed.standalone_types)
+            .filter(|typ| matches!(typ.definition, TypeDefinition::Record { .. }))
+            .map(|typ| self.naming.name(&typ.name))
+            .collect()
+    }
+
+    /// Returns the definition of the type with the given name, the WIT name
+    /// named with the field case as in Go types.
+    fn type_definition(&self, name: &str) -> Option<&TypeDefinition> {
+        self.analyzed
+            .interfaces
+            .iter()
+            .flat_map(|interface| &interface.types)
+            .chain(&self.analyzed.standalone_types)
+            .find(|typ| self.naming.name(&typ.name) == name)
+            .map(|typ| &typ.definition)
+    }
+
+    /// Returns the statements returning an error if the given value holds
+    /// something its type can't, such as an enum which isn't one of its cases,
+    /// or `None` if every value is valid.
+    fn validation(&self, typ: &GoType, value: Tokens<Go>, path: &str) -> Option<Tokens<Go>> {
+        match typ {
+            GoType::UserDefined(name) => match self.type_definition(name)? {
+                TypeDefinition::Enum { cases } => {
+                    let cases = cases.iter().map(GoIdentifier::public);
+                    Some(quote! {
+                        switch $(&value) {
+                        case $(for case in cases join (, ) => $case):
+                        default:
+                            return $FMT_ERRORF($(quoted(format!("invalid {path}: %v"))), $(&value))
+                        }
+                    })
+                }
+                TypeDefinition::Flags { flags, repr } => {
+                    let bits = match repr {
+                        GoType::Uint8 => 8,
+                        GoType::Uint16 => 16,
+                        GoType::Uint32 => 32,
+                        _ => 64,
+                    };
+                    (flags.len() < bits).then(|| {
+                        quote! {
+                            if $(&value)>>$(flags.len().to_string()) != 0 {
+                                return $FMT_ERRORF($(quoted(format!("invalid {path}: %#x"))), $(&value))
+                            }
+                        }
+                    })
+                }
+                TypeDefinition::Record { fields } => fields
+                    .iter()
+                    .any(|(_, field_type)| self.validation(field_type, quote!(v), path).is_some())
+                    .then(|| {
+                        quote! {
+                            if err := $value.Validate(); err != nil {
+                                return $FMT_ERRORF($(quoted(format!("invalid {path}: %w"))), err)
+                            }
+                        }
+                    }),
+                _ => None,
+            },
+            GoType::Pointer(inner) => {
+                let validation = self.validation(inner, quote!((*$(&value))), path)?;
+                Some(quote! {
+                    if $value != nil {
+                        $validation
+                    }
+                })
+            }
+            GoType::Slice(inner) => {
+                let validation = self.validation(inner, quote!(v), &format!("{path} element"))?;
+                Some(quote! {
+                    for _, v := range $value {
+                        $validation
+                    }
+                })
+            }
+            _ => None,
+        }
+    }
+
     fn generate_type_definition(&self, typ: &AnalyzedType, tokens: &mut Tokens<Go>) {
         match &typ.definition {
             TypeDefinition::Record { fields } => {
+                let name = &typ.go_type_name;
+                let records = self.record_names();
+                let mut comparisons = Tokens::<Go>::new();
+                for (i, (field_name, field_type)) in fields.iter().enumerate() {
+                    if i > 0 {
+                        comparisons.space();
+                        comparisons.append("&&");
+                        if i == 1 {
+                            comparisons.indent();
+                        }
+                        comparisons.push();
+                    }
+                    comparisons.append(equal(
+                        field_type,
+                        quote!(r.$field_name),
+                        quote!(other.$field_name),
+                        &records,
+                    ));
+                }
+                if fields.len() > 1 {
+                    comparisons.unindent();
+                }
+                let validations = fields
+                    .iter()
+                    .filter_map(|(field_name, field_type)| {
+                        let path = format!("{}.{}", String::from(name), String::from(field_name));
+                        self.validation(field_type, quote!(r.$field_name), &path)
+                    })
+                    .collect::<Vec<_>>();
                 quote_in! { *tokens =>
                     $['\n']
-                    type $(&typ.go_type_name) struct {
+                    type $name struct {
                         $(for (field_name, field_type) in fields join ($['\n']) =>
                             $field_name $field_type
                         )
                     }
+                    $['\n']
+                    $(comment(&[
+                        "Equal reports whether r and other have equal fields, comparing slices,",
+                        "pointers and nested records by their contents.",
+                    ]))
+                    func (r $name) Equal(other $name) bool {
+                        $(if fields.is_empty() {
+                            return true
+                        } else {
+                            return $comparisons
+                        })
+                    }
+                    $(if !validations.is_empty() {
+                        $['\n']
+                        $(comment(&[
+                            "Validate returns an error if a field of r holds a value its WIT type",
+                            "can't, such as an enum which isn't one of its cases or flags outside of",
+                            "its set, so values built by hand are checked before they're passed on.",
+                        ]))
+                        func (r $name) Validate() error {
+                            $(for validation in validations join ($['\r']) => $validation)
+                            return nil
+                        }
+                    })
+                }
+                if self.binary {
+                    BinaryGenerator::new(self.resolve, self.sizes, &self.naming, &self.renames)
+                        .generate(typ.id, tokens);
                 }
             }
             TypeDefinition::Enum { cases } => {
-                let enum_type = &GoIdentifier::private(&typ.name);
+                let enum_type = &GoIdentifier::private(self.naming.name(&typ.name));
                 let enum_interface = &typ.go_type_name;
-                let enum_function = &GoIdentifier::private(format!("is-{}", &typ.name));
+                let enum_function =
+                    &GoIdentifier::private(format!("is-{}", self.naming.name(&typ.name)));
                 let variants = cases.iter().map(GoIdentifier::public);
                 quote_in! { *tokens =>
                     $['\n']
@@ -383,11 +701,35 @@ impl<'a> ImportCodeGenerator<'a> {
                     $['\n']
                 }
             }
+            TypeDefinition::Flags { flags, repr } => {
+                let name = &typ.go_type_name;
+                let flags = flags
+                    .iter()
+                    .map(|flag| {
+                        GoIdentifier::public(self.naming.name(&format!("{}-{flag}", typ.name)))
+                    })
+                    .collect::<Vec<_>>();
+                quote_in! { *tokens =>
+                    $['\n']
+                    type $name $repr
+                    $['\n']
+                    const (
+                        $(for (i, flag) in flags.iter().enumerate() join ($['\r']) =>
+                            $(if i == 0 {
+                                $flag $name = 1 << iota
+                            } else {
+                                $flag
+                            })
+                        )
+                    )
+                }
+            }
             TypeDefinition::Alias { target } => {
-                // TODO(#4): We might want a Type Definition (newtype) instead of Type Alias here
+                // A defined type rather than a Go alias, so the type system keeps
+                // e.g. IDs apart from other integers.
                 quote_in! { *tokens =>
                     $['\n']
-                    type $(&typ.go_type_name) = $target
+                    type $(&typ.go_type_name) $target
                 }
             }
             TypeDefinition::Primitive => {
@@ -413,6 +755,10 @@ impl<'a> ImportCodeGenerator<'a> {
         param_name: &GoIdentifier,
     ) -> Tokens<Go> {
         let func_name = &method.name;
+        // Panics of the host implementation trap the guest with a typed error.
+        let recover = quote! {
+            defer recoverHostPanic($param_name, $(quoted(String::from(&method.go_method_name))))
+        };
 
         // Generate Wasm function parameters based on WIT types.
         let wasm_params = vec![
@@ -423,12 +769,25 @@ impl<'a> ImportCodeGenerator<'a> {
         let wasm_sig = self
             .resolve
             .wasm_signature(AbiVariant::GuestImport, &method.wit_function);
-        let result = if wasm_sig.results.is_empty() {
-            GoResult::Empty
-        } else {
-            todo!("implement handling of wasm signatures with results");
+        // The Go types of the host function must match the core Wasm signature
+        // exactly, otherwise wazero will fail to link the import.
+        let core_params = wasm_sig
+            .params
+            .iter()
+            .enumerate()
+            .map(|(i, typ)| (format!("arg{i}"), resolve_host_wasm_type(typ)))
+            .collect::<Vec<_>>();
+        let result = match wasm_sig.results.as_slice() {
+            [] => GoResult::Empty,
+            [typ] => GoResult::Anon(resolve_host_wasm_type(typ)),
+            _ => unreachable!("imported functions return at most one flat value"),
         };
-        let mut f = Func::import(param_name, result, self.sizes);
+        let mut f = Func::import(param_name, &method.go_method_name, result, self.sizes)
+            .with_naming(self.naming.clone())
+            .with_renames(self.renames.clone())
+            .with_byte_views(self.byte_views)
+            .with_option_style(self.option_style)
+            .with_named_lists(self.named_lists);
 
         // Magic
         wit_bindgen_core::abi::call(
@@ -441,12 +800,43 @@ impl<'a> ImportCodeGenerator<'a> {
             false,
         );
 
+        if self.tinygo {
+            // Host functions are called with their core Wasm values on a stack,
+            // which replaces the reflection `WithFunc` uses on the signature.
+            let args = core_params
+                .iter()
+                .enumerate()
+                .map(|(i, (_, typ))| decode_stack(typ, quote!($(format!("stack[{i}]")))))
+                .collect::<Vec<_>>();
+            let call = quote!(host(ctx, mod, $(for arg in args join (, ) => $arg)));
+            return quote! {
+                NewFunctionBuilder().
+                WithGoModuleFunction($WAZERO_API_GO_MODULE_FUNC(func(ctx $CONTEXT_CONTEXT, mod $WAZERO_API_MODULE, stack []uint64) {
+                    $(&recover)
+                    host := func(
+                        $(for param in wasm_params join (,$['\r']) => $param),
+                        $(for (name, typ) in &core_params join (,$['\r']) => $name $typ),
+                    ) $(f.result()) {
+                        $(f.body())
+                    }
+                    $(match f.result() {
+                        GoResult::Empty => $call,
+                        GoResult::Anon(typ) => { stack[0] = $(encode_stack(typ, call)) },
+                    })
+                }),
+                $(value_types(&wasm_sig.params)),
+                $(value_types(&wasm_sig.results))).
+                Export($(quoted(func_name))).
+            };
+        }
+
         quote! {
             NewFunctionBuilder().
             WithFunc(func(
                 $(for param in wasm_params join (,$['\r']) => $param),
-                $(for param in f.args() join (,$['\r']) => $param uint32),
-            ) $(f.result()){
+                $(for (name, typ) in &core_params join (,$['\r']) => $name $typ),
+            ) $(f.result()) {
+                $recover
                 $(f.body())
             }).
             Export($(quoted(func_name))).
@@ -454,6 +844,66 @@ impl<'a> ImportCodeGenerator<'a> {
     }
 }
 
+/// Returns an expression reporting whether the values of the given type are
+/// equal, which compares slices, pointers and records by their contents.
+fn equal(typ: &GoType, a: Tokens<Go>, b: Tokens<Go>, records: &BTreeSet<String>) -> Tokens<Go> {
+    match typ {
+        GoType::UserDefined(name) if records.contains(name.as_str()) => quote!($a.Equal($b)),
+        GoType::Slice(inner) if comparable(inner, records) => quote!($SLICES_EQUAL($a, $b)),
+        GoType::Slice(inner) => {
+            let elements = equal(inner, quote!(x), quote!(y), records);
+            quote!($SLICES_EQUAL_FUNC($a, $b, func(x, y $(inner.as_ref())) bool { return $elements }))
+        }
+        GoType::Pointer(inner) => {
+            let values = equal(inner, quote!((*$(&a))), quote!((*$(&b))), records);
+            quote!(($(&a) == nil && $(&b) == nil || $(&a) != nil && $(&b) != nil && $values))
+        }
+        _ => quote!($a == $b),
+    }
+}
+
+/// Returns whether the values of the given type can be compared with `==`.
+fn comparable(typ: &GoType, records: &BTreeSet<String>) -> bool {
+    match typ {
+        GoType::Slice(_) | GoType::Pointer(_) => false,
+        GoType::UserDefined(name) => !records.contains(name.as_str()),
+        _ => true,
+    }
+}
+
+/// Returns the `api.ValueType` slice of the given core Wasm types.
+fn value_types(types: &[WasmType]) -> Tokens<Go> {
+    let types = types.iter().map(|typ| match typ {
+        WasmType::I32 | WasmType::Pointer | WasmType::Length => quote!($WAZERO_API_VALUE_TYPE_I32),
+        WasmType::I64 | WasmType::PointerOrI64 => quote!($WAZERO_API_VALUE_TYPE_I64),
+        WasmType::F32 => quote!($WAZERO_API_VALUE_TYPE_F32),
+        WasmType::F64 => quote!($WAZERO_API_VALUE_TYPE_F64),
+    });
+    quote!([]$WAZERO_API_VALUE_TYPE{$(for typ in types join (, ) => $typ)})
+}
+
+/// Decodes a value on the stack of a host function to its Go type, as
+/// resolved by `resolve_host_wasm_type`.
+fn decode_stack(typ: &GoType, value: Tokens<Go>) -> Tokens<Go> {
+    match typ {
+        GoType::Uint32 => quote!($WAZERO_API_DECODE_U32($value)),
+        GoType::Float32 => quote!($WAZERO_API_DECODE_F32($value)),
+        GoType::Float64 => quote!($WAZERO_API_DECODE_F64($value)),
+        _ => value,
+    }
+}
+
+/// Encodes a Go value, as resolved by `resolve_host_wasm_type`, to a value on
+/// the stack of a host function.
+fn encode_stack(typ: &GoType, value: Tokens<Go>) -> Tokens<Go> {
+    match typ {
+        GoType::Uint32 => quote!($WAZERO_API_ENCODE_U32($value)),
+        GoType::Float32 => quote!($WAZERO_API_ENCODE_F32($value)),
+        GoType::Float64 => quote!($WAZERO_API_ENCODE_F64($value)),
+        _ => value,
+    }
+}
+
 #[cfg(test)]
 mod tests {
     use genco::prelude::*;
@@ -467,7 +917,7 @@ mod tests {
             imports::{ImportAnalyzer, ImportCodeGenerator},
             ir::{AnalyzedImports, InterfaceMethod, Parameter, WitReturn},
         },
-        go::{GoIdentifier, GoType},
+        go::{GoIdentifier, GoType, Naming},
     };
 
     #[test]
@@ -517,7 +967,8 @@ mod tests {
         // The result should contain the WIT type-driven generation
         let code_str = result.to_string().unwrap();
         assert!(code_str.contains("NewFunctionBuilder"));
-        assert!(code_str.contains("mod.Memory().Read"));
+        assert!(code_str.contains("guestMemory := cfg.memory(mod)"));
+        assert!(code_str.contains("guestMemory.Read"));
         assert!(code_str.contains("writeString"));
 
         println!("Generated code:\n{}", code_str);
@@ -566,11 +1017,528 @@ mod tests {
         let code_str = result.to_string().unwrap();
         assert!(code_str.contains("arg0 uint32"));
         assert!(!code_str.contains("arg1 uint32"));
-        assert!(!code_str.contains("mod.Memory().Read")); // No string reading
+        assert!(!code_str.contains("guestMemory.Read")); // No string reading
+        assert!(!code_str.contains("guestMemory := ")); // Nor a memory to read from
 
         println!("U32 generated code:\n{}", code_str);
     }
 
+    #[test]
+    fn test_exact_integer_types() {
+        let analyzed = AnalyzedImports {
+            instance_name: GoIdentifier::public("TestInstance"),
+            interfaces: vec![],
+            standalone_functions: vec![],
+            standalone_types: vec![],
+            factory_name: GoIdentifier::public("TestFactory"),
+            constructor_name: GoIdentifier::public("NewTestFactory"),
+        };
+        let resolve = Resolve::new();
+        let sizes = SizeAlign::default();
+
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+
+        let method = InterfaceMethod {
+            name: "scale".to_string(),
+            go_method_name: GoIdentifier::public("Scale"),
+            parameters: vec![
+                Parameter {
+                    name: GoIdentifier::private("value"),
+                    go_type: GoType::Int64,
+                    wit_type: Type::S64,
+                },
+                Parameter {
+                    name: GoIdentifier::private("factor"),
+                    go_type: GoType::Uint8,
+                    wit_type: Type::U8,
+                },
+            ],
+            return_type: Some(WitReturn {
+                go_type: GoType::Uint32,
+                wit_type: Type::U32,
+            }),
+            wit_function: Function {
+                name: "scale".to_string(),
+                kind: FunctionKind::Freestanding,
+                params: vec![
+                    ("value".to_string(), Type::S64),
+                    ("factor".to_string(), Type::U8),
+                ],
+                result: Some(Type::U32),
+                docs: Default::default(),
+                stability: Default::default(),
+            },
+        };
+
+        // The interface method must use the exact WIT width and signedness
+        let signature = generator
+            .generate_method_signature(&method)
+            .to_string()
+            .unwrap();
+        assert!(signature.contains("value int64"));
+        assert!(signature.contains("factor uint8"));
+        assert!(signature.contains(") uint32"));
+
+        // The host function must use the exact core Wasm types
+        let param_name = GoIdentifier::private("handler");
+        let code_str = generator
+            .generate_host_function_builder(&method, &param_name)
+            .to_string()
+            .unwrap();
+        assert!(code_str.contains("arg0 uint64"));
+        assert!(code_str.contains("arg1 uint32"));
+        assert!(code_str.contains(") uint32 {"));
+        assert!(code_str.contains("result0 := int64(arg0)"));
+        assert!(code_str.contains("result1 := uint8(arg1)"));
+        assert!(code_str.contains("value2 := handler.Scale(ctx, result0, result1)"));
+        assert!(code_str.contains("result3 := uint32(value2)"));
+        assert!(code_str.contains("return result3"));
+    }
+
+    #[test]
+    fn test_tinygo() {
+        let analyzed = AnalyzedImports {
+            instance_name: GoIdentifier::public("TestInstance"),
+            interfaces: vec![],
+            standalone_functions: vec![],
+            standalone_types: vec![],
+            factory_name: GoIdentifier::public("TestFactory"),
+            constructor_name: GoIdentifier::public("NewTestFactory"),
+        };
+        let resolve = Resolve::new();
+        let sizes = SizeAlign::default();
+
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes).with_tinygo(true);
+
+        let method = InterfaceMethod {
+            name: "scale".to_string(),
+            go_method_name: GoIdentifier::public("Scale"),
+            parameters: vec![
+                Parameter {
+                    name: GoIdentifier::private("value"),
+                    go_type: GoType::Int64,
+                    wit_type: Type::S64,
+                },
+                Parameter {
+                    name: GoIdentifier::private("factor"),
+                    go_type: GoType::Float32,
+                    wit_type: Type::F32,
+                },
+            ],
+            return_type: Some(WitReturn {
+                go_type: GoType::Uint32,
+                wit_type: Type::U32,
+            }),
+            wit_function: Function {
+                name: "scale".to_string(),
+                kind: FunctionKind::Freestanding,
+                params: vec![
+                    ("value".to_string(), Type::S64),
+                    ("factor".to_string(), Type::F32),
+                ],
+                result: Some(Type::U32),
+                docs: Default::default(),
+                stability: Default::default(),
+            },
+        };
+
+        let param_name = GoIdentifier::private("handler");
+        let code_str = generator
+            .generate_host_function_builder(&method, &param_name)
+            .to_string()
+            .unwrap();
+        println!("Generated: {}", code_str);
+
+        // The host function isn't registered with reflection
+        assert!(!code_str.contains("WithFunc("));
+        assert!(code_str.contains("defer recoverHostPanic(handler, \"Scale\")"));
+        assert!(code_str.contains(
+            "WithGoModuleFunction(api.GoModuleFunc(func(ctx context.Context, mod api.Module, stack []uint64) {"
+        ));
+        assert!(code_str.contains("[]api.ValueType{api.ValueTypeI64, api.ValueTypeF32}"));
+        assert!(code_str.contains("[]api.ValueType{api.ValueTypeI32}"));
+
+        // Its arguments and result are moved through the stack
+        assert!(code_str.contains("host := func("));
+        assert!(code_str.contains(
+            "stack[0] = api.EncodeU32(host(ctx, mod, stack[0], api.DecodeF32(stack[1])))"
+        ));
+    }
+
+    #[test]
+    fn test_byte_views() {
+        use wit_bindgen_core::wit_parser::{TypeDef, TypeDefKind, TypeOwner};
+
+        let mut resolve = Resolve::new();
+        let bytes = Type::Id(resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::List(Type::U8),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        }));
+        let analyzed = AnalyzedImports {
+            instance_name: GoIdentifier::public("TestInstance"),
+            interfaces: vec![],
+            standalone_functions: vec![],
+            standalone_types: vec![],
+            factory_name: GoIdentifier::public("TestFactory"),
+            constructor_name: GoIdentifier::public("NewTestFactory"),
+        };
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let method = InterfaceMethod {
+            name: "sum".to_string(),
+            go_method_name: GoIdentifier::public("Sum"),
+            parameters: vec![Parameter {
+                name: GoIdentifier::local("data"),
+                go_type: GoType::Slice(Box::new(GoType::Uint8)),
+                wit_type: bytes,
+            }],
+            return_type: Some(WitReturn {
+                go_type: GoType::Uint32,
+                wit_type: Type::U32,
+            }),
+            wit_function: Function {
+                name: "sum".to_string(),
+                kind: FunctionKind::Freestanding,
+                params: vec![("data".to_string(), bytes)],
+                result: Some(Type::U32),
+                docs: Default::default(),
+                stability: Default::default(),
+            },
+        };
+        let param_name = GoIdentifier::private("handler");
+
+        // By default, the bytes are read at once and copied
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+        let code_str = generator
+            .generate_host_function_builder(&method, &param_name)
+            .to_string()
+            .unwrap();
+        assert!(code_str.contains("readBytes(guestMemory, arg0, arg1)"));
+        assert!(code_str.contains("copy("));
+        assert!(code_str.contains("defer recoverHostPanic(handler, \"Sum\")"));
+        let signature = generator
+            .generate_method_signature(&method)
+            .to_string()
+            .unwrap();
+        assert!(!signature.contains("view"));
+
+        // With views, the host gets the bytes in guest memory
+        let generator = generator.with_byte_views(true);
+        let code_str = generator
+            .generate_host_function_builder(&method, &param_name)
+            .to_string()
+            .unwrap();
+        assert!(code_str.contains("readBytes(guestMemory, arg0, arg1)"));
+        assert!(!code_str.contains("copy("));
+        let signature = generator
+            .generate_method_signature(&method)
+            .to_string()
+            .unwrap();
+        assert!(signature.contains(
+            "// data is a view into guest memory, which is only valid until Sum returns."
+        ));
+    }
+
+    #[test]
+    fn test_tuple_return() {
+        use wit_bindgen_core::wit_parser::{Tuple, TypeDef, TypeDefKind, TypeOwner};
+
+        let mut resolve = Resolve::new();
+        let pair = Type::Id(resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::Tuple(Tuple {
+                types: vec![Type::U32, Type::String],
+            }),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        }));
+        let analyzed = AnalyzedImports {
+            instance_name: GoIdentifier::public("TestInstance"),
+            interfaces: vec![],
+            standalone_functions: vec![],
+            standalone_types: vec![],
+            factory_name: GoIdentifier::public("TestFactory"),
+            constructor_name: GoIdentifier::public("NewTestFactory"),
+        };
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let method = InterfaceMethod {
+            name: "pair".to_string(),
+            go_method_name: GoIdentifier::public("Pair"),
+            parameters: vec![],
+            return_type: Some(WitReturn {
+                go_type: GoType::MultiReturn(vec![GoType::Uint32, GoType::String]),
+                wit_type: pair,
+            }),
+            wit_function: Function {
+                name: "pair".to_string(),
+                kind: FunctionKind::Freestanding,
+                params: vec![],
+                result: Some(pair),
+                docs: Default::default(),
+                stability: Default::default(),
+            },
+        };
+        let param_name = GoIdentifier::private("handler");
+
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+        let signature = generator
+            .generate_method_signature(&method)
+            .to_string()
+            .unwrap();
+        assert!(signature.contains(") (uint32, string)"));
+
+        // Both values are lowered into the return area
+        let code_str = generator
+            .generate_host_function_builder(&method, &param_name)
+            .to_string()
+            .unwrap();
+        assert!(code_str.contains("value0_0, value0_1 := handler.Pair(ctx)"));
+        assert!(code_str.contains("guestMemory.WriteUint32Le("));
+        assert!(code_str.contains("writeString("));
+    }
+
+    #[test]
+    fn test_list_of_tuples_param() {
+        use wit_bindgen_core::wit_parser::{Tuple, TypeDef, TypeDefKind, TypeOwner};
+
+        let mut resolve = Resolve::new();
+        let field = Type::Id(resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::Tuple(Tuple {
+                types: vec![Type::String, Type::String],
+            }),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        }));
+        let fields = Type::Id(resolve.types.alloc(TypeDef {
+            name: None,
+            kind: TypeDefKind::List(field),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        }));
+        let analyzed = AnalyzedImports {
+            instance_name: GoIdentifier::public("TestInstance"),
+            interfaces: vec![],
+            standalone_functions: vec![],
+            standalone_types: vec![],
+            factory_name: GoIdentifier::public("TestFactory"),
+            constructor_name: GoIdentifier::public("NewTestFactory"),
+        };
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let method = InterfaceMethod {
+            name: "log".to_string(),
+            go_method_name: GoIdentifier::public("Log"),
+            parameters: vec![Parameter {
+                name: GoIdentifier::local("fields"),
+                go_type: crate::resolve_type(&fields, &resolve),
+                wit_type: fields,
+            }],
+            return_type: None,
+            wit_function: Function {
+                name: "log".to_string(),
+                kind: FunctionKind::Freestanding,
+                params: vec![("fields".to_string(), fields)],
+                result: None,
+                docs: Default::default(),
+                stability: Default::default(),
+            },
+        };
+        let param_name = GoIdentifier::private("handler");
+
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+        let signature = generator
+            .generate_method_signature(&method)
+            .to_string()
+            .unwrap();
+        assert!(signature.contains("fields []struct{ F0 string; F1 string },"));
+
+        // Every pair is lifted from guest memory into a struct before the
+        // host function is called
+        let code_str = generator
+            .generate_host_function_builder(&method, &param_name)
+            .to_string()
+            .unwrap();
+        println!("Generated: {}", code_str);
+        assert!(code_str.contains(":= make([]struct{ F0 string; F1 string }, "));
+        assert!(code_str.contains(":= struct{ F0 string; F1 string }{"));
+        assert!(code_str.contains("readBytes(guestMemory, "));
+        assert!(code_str.contains("handler.Log(ctx, "));
+    }
+
+    #[test]
+    fn test_primitive_type_alias() {
+        use crate::codegen::ir::{AnalyzedType, TypeDefinition};
+        use wit_bindgen_core::wit_parser::{TypeDef, TypeDefKind, TypeOwner};
+
+        let mut resolve = Resolve::new();
+        let alias_id = resolve.types.alloc(TypeDef {
+            name: Some("user-id".to_string()),
+            kind: TypeDefKind::Type(Type::U64),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+        let user_id = Type::Id(alias_id);
+        let world = World {
+            name: "test-world".to_string(),
+            imports: Default::default(),
+            exports: Default::default(),
+            docs: Default::default(),
+            stability: Default::default(),
+            package: None,
+            includes: Default::default(),
+            include_names: Default::default(),
+        };
+        let analyzed = AnalyzedImports {
+            instance_name: GoIdentifier::public("TestInstance"),
+            interfaces: vec![],
+            standalone_functions: vec![],
+            standalone_types: vec![],
+            factory_name: GoIdentifier::public("TestFactory"),
+            constructor_name: GoIdentifier::public("NewTestFactory"),
+        };
+        let sizes = SizeAlign::default();
+
+        // The alias is generated as a defined type, not a Go type alias
+        let analyzer = ImportAnalyzer::new(&resolve, &world);
+        let definition = analyzer.analyze_type_definition(alias_id).unwrap();
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+        let mut tokens = Tokens::new();
+        generator.generate_type_definition(
+            &AnalyzedType {
+                id: alias_id,
+                name: "user-id".to_string(),
+                go_type_name: GoIdentifier::public("user-id"),
+                definition,
+            },
+            &mut tokens,
+        );
+        let type_str = tokens.to_string().unwrap();
+        assert!(type_str.contains("type UserId uint64"));
+        assert!(!type_str.contains("="));
+
+        let user_id_type = GoType::Defined("user-id".to_string(), Box::new(GoType::Uint64));
+        let method = InterfaceMethod {
+            name: "next-id".to_string(),
+            go_method_name: GoIdentifier::public("next-id"),
+            parameters: vec![Parameter {
+                name: GoIdentifier::private("id"),
+                go_type: user_id_type.clone(),
+                wit_type: user_id,
+            }],
+            return_type: Some(WitReturn {
+                go_type: user_id_type,
+                wit_type: user_id,
+            }),
+            wit_function: Function {
+                name: "next-id".to_string(),
+                kind: FunctionKind::Freestanding,
+                params: vec![("id".to_string(), user_id)],
+                result: Some(user_id),
+                docs: Default::default(),
+                stability: Default::default(),
+            },
+        };
+
+        // Signatures use the named type
+        let signature = generator
+            .generate_method_signature(&method)
+            .to_string()
+            .unwrap();
+        assert!(signature.contains("id UserId"));
+        assert!(signature.contains(") UserId"));
+
+        // The host function converts between the named type and its core type
+        let param_name = GoIdentifier::private("handler");
+        let code_str = generator
+            .generate_host_function_builder(&method, &param_name)
+            .to_string()
+            .unwrap();
+        assert!(code_str.contains("result0 := uint64(arg0)"));
+        assert!(code_str.contains("value1 := uint64(handler.NextId(ctx, UserId(result0)))"));
+        assert!(code_str.contains("result2 := uint64(value1)"));
+        assert!(code_str.contains("return result2"));
+    }
+
+    #[test]
+    fn test_type_alias_initialisms() {
+        use wit_bindgen_core::wit_parser::{TypeDef, TypeDefKind, TypeOwner};
+
+        let mut resolve = Resolve::new();
+        let alias_id = resolve.types.alloc(TypeDef {
+            name: Some("user-id".to_string()),
+            kind: TypeDefKind::Type(Type::U64),
+            owner: TypeOwner::None,
+            docs: Default::default(),
+            stability: Default::default(),
+        });
+        let user_id = Type::Id(alias_id);
+        let world = World {
+            name: "test-world".to_string(),
+            imports: Default::default(),
+            exports: Default::default(),
+            docs: Default::default(),
+            stability: Default::default(),
+            package: None,
+            includes: Default::default(),
+            include_names: Default::default(),
+        };
+        let analyzed = AnalyzedImports {
+            instance_name: GoIdentifier::public("TestInstance"),
+            interfaces: vec![],
+            standalone_functions: vec![],
+            standalone_types: vec![],
+            factory_name: GoIdentifier::public("TestFactory"),
+            constructor_name: GoIdentifier::public("NewTestFactory"),
+        };
+        let sizes = SizeAlign::default();
+        let naming = Naming::default();
+        let function = Function {
+            name: "next-id".to_string(),
+            kind: FunctionKind::Freestanding,
+            params: vec![("id".to_string(), user_id)],
+            result: Some(user_id),
+            docs: Default::default(),
+            stability: Default::default(),
+        };
+
+        // Initialisms are kept in type and method names by default
+        let analyzer = ImportAnalyzer::new(&resolve, &world).with_naming(naming.clone());
+        let typ = analyzer.analyze_type(alias_id).unwrap();
+        assert_eq!(String::from(&typ.go_type_name), "UserID");
+        let method = analyzer.analyze_interface_method(&function, "users");
+        assert_eq!(String::from(&method.go_method_name), "NextID");
+
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes).with_naming(naming);
+        let mut tokens = Tokens::new();
+        generator.generate_type_definition(&typ, &mut tokens);
+        let type_str = tokens.to_string().unwrap();
+        assert!(type_str.contains("type UserID uint64"));
+
+        let signature = generator
+            .generate_method_signature(&method)
+            .to_string()
+            .unwrap();
+        assert!(signature.contains("NextID("));
+        assert!(signature.contains("id UserID"));
+        assert!(signature.contains(") UserID"));
+
+        let param_name = GoIdentifier::private("handler");
+        let code_str = generator
+            .generate_host_function_builder(&method, &param_name)
+            .to_string()
+            .unwrap();
+        assert!(code_str.contains("value1 := uint64(handler.NextID(ctx, UserID(result0)))"));
+    }
+
     fn create_test_world_with_interface() -> (Resolve, WorldId) {
         let mut resolve = Resolve::default();
 
@@ -674,6 +1642,308 @@ mod tests {
         assert!(output.contains("Log("));
     }
 
+    #[test]
+    fn test_import_same_interface_under_different_names() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "logs.wit",
+                r#"
+                package arcjet:logs;
+
+                world logs {
+                  import app-log: interface {
+                    log: func(msg: string);
+                  }
+                  import audit-log: interface {
+                    log: func(msg: string);
+                  }
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "logs")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+
+        let analyzed = ImportAnalyzer::new(&resolve, world).analyze();
+
+        // Each import gets its own host interface and implementation
+        let names = analyzed
+            .interfaces
+            .iter()
+            .map(|interface| {
+                (
+                    String::from(&interface.go_interface_name),
+                    String::from(&interface.constructor_param_name),
+                    interface.wazero_module_name.as_str(),
+                )
+            })
+            .collect::<Vec<_>>();
+        assert_eq!(
+            names,
+            [
+                ("ILogsAppLog".to_string(), "appLog".to_string(), "app-log"),
+                (
+                    "ILogsAuditLog".to_string(),
+                    "auditLog".to_string(),
+                    "audit-log"
+                ),
+            ]
+        );
+
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+        let chains = generator.import_chains();
+        let app_log = chains["app-log"].to_string().unwrap();
+        assert!(app_log.contains("appLog.Log(ctx, "));
+        let audit_log = chains["audit-log"].to_string().unwrap();
+        assert!(audit_log.contains("auditLog.Log(ctx, "));
+    }
+
+    #[test]
+    fn test_option_record_return() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "store.wit",
+                r#"
+                package arcjet:store;
+
+                interface store {
+                  record entry {
+                    key: string,
+                    hits: u32,
+                  }
+
+                  lookup: func(key: string) -> option<entry>;
+                }
+
+                world lookups {
+                  import store;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "lookups")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+
+        let analyzed = ImportAnalyzer::new(&resolve, world).analyze();
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+        let chains = generator.import_chains();
+        let store = chains["arcjet:store/store"].to_string().unwrap();
+        println!("Generated: {}", store);
+
+        // The record is lowered into the return area only if there is one
+        assert!(store.contains(", ok"));
+        assert!(store.contains(":= store.Lookup(ctx, "));
+        assert!(store.contains("if ok"));
+        assert!(store.contains("variantPayload := value"));
+        assert!(store.contains("variantPayload.Key"));
+        assert!(store.contains("variantPayload.Hits"));
+        assert!(store.contains("writeString("));
+    }
+
+    #[test]
+    fn test_float_record() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "samples.wit",
+                r#"
+                package arcjet:samples;
+
+                interface samples {
+                  record sample {
+                    a: f32,
+                    b: f64,
+                    c: f32,
+                    d: f64,
+                    e: f32,
+                    f: f32,
+                    g: f64,
+                    h: f64,
+                  }
+
+                  scale: func(sample: sample) -> sample;
+                }
+
+                world sampling {
+                  import samples;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "sampling")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+
+        let analyzed = ImportAnalyzer::new(&resolve, world).analyze();
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+        let chains = generator.import_chains();
+        let samples = chains["arcjet:samples/samples"].to_string().unwrap();
+        println!("Generated: {}", samples);
+
+        // The flattened fields keep their float types
+        assert!(samples.contains("arg0 float32,"));
+        assert!(samples.contains("arg1 float64,"));
+        assert!(samples.contains("arg6 float64,"));
+        assert!(samples.contains("arg7 float64,"));
+        assert!(samples.contains("arg8 uint32,"));
+
+        // The returned fields are written by their bits
+        assert!(samples.contains("WriteUint32Le(arg8+0, math.Float32bits("));
+        assert!(samples.contains("WriteUint64Le(arg8+8, math.Float64bits("));
+        assert!(samples.contains("WriteUint64Le(arg8+48, math.Float64bits("));
+        assert!(!samples.contains("uint32(value"));
+    }
+
+    #[test]
+    fn test_list_of_records_result() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "rows.wit",
+                r#"
+                package arcjet:rows;
+
+                interface db {
+                  record row {
+                    id: u64,
+                    name: string,
+                  }
+
+                  query: func() -> list<row>;
+                }
+
+                world rows {
+                  import db;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "rows")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+
+        let analyzed = ImportAnalyzer::new(&resolve, world).analyze();
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+        let chains = generator.import_chains();
+        let db = chains["arcjet:rows/db"].to_string().unwrap();
+        println!("Generated: {}", db);
+
+        // The list is allocated with the realloc function of the calling
+        // module, as the host function has no instance
+        assert!(!db.contains("i.realloc("));
+        assert!(db.contains(r#"mod.ExportedFunction("cabi_realloc").Call(ctx, 0, 0, 8, len"#));
+        assert!(db.contains(" * 16)"));
+
+        // Each row is written at a stride of its size, followed by the list
+        assert!(db.contains(" * uint64(16))"));
+        assert!(db.contains("WriteUint64Le(base+0, "));
+        assert!(db.contains("WriteUint32Le(base+12, uint32("));
+        assert!(db.contains("WriteUint32Le(arg0+4, uint32("));
+    }
+
+    #[test]
+    fn test_named_lists() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "rows.wit",
+                r#"
+                package arcjet:rows;
+
+                interface db {
+                  record row {
+                    id: u64,
+                  }
+
+                  type rows = list<row>;
+
+                  query: func(filter: rows) -> rows;
+                }
+
+                world rows {
+                  import db;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "rows")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+
+        let generate = |named_lists: bool| {
+            let analyzed = ImportAnalyzer::new(&resolve, world)
+                .with_named_lists(named_lists)
+                .analyze();
+            let generator =
+                ImportCodeGenerator::new(&resolve, &analyzed, &sizes).with_named_lists(named_lists);
+            let db = generator.import_chains()["arcjet:rows/db"]
+                .to_string()
+                .unwrap();
+            let mut tokens = Tokens::<Go>::new();
+            generator.format_into(&mut tokens);
+            (tokens.to_string().unwrap(), db)
+        };
+
+        let (generated, db) = generate(true);
+        println!("Generated: {}\n{}", generated, db);
+        assert!(generated.contains("type Rows []Row"));
+        assert!(generated.contains("filter Rows,\n\t) Rows"));
+        assert!(db.contains(":= make(Rows, "));
+
+        // Otherwise the list is inlined
+        let (generated, db) = generate(false);
+        println!("Generated: {}\n{}", generated, db);
+        assert!(!generated.contains("Rows"));
+        assert!(generated.contains("filter []Row,\n\t) []Row"));
+        assert!(db.contains(":= make([]Row, "));
+    }
+
+    #[test]
+    fn test_interface_type_exported() {
+        let (resolve, world_id) = create_test_world_with_interface();
+        let world = &resolve.worlds[world_id];
+        let sizes = SizeAlign::default();
+
+        let analyzed = ImportAnalyzer::new(&resolve, &world).analyze();
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+        let mut tokens = Tokens::<Go>::new();
+        generator.format_into(&mut tokens);
+
+        // The interface must be exported for host implementations to assert
+        // that they satisfy it
+        let interface = &analyzed.interfaces[0];
+        let name = String::from(&interface.go_interface_name);
+        assert!(name.starts_with(|c: char| c.is_ascii_uppercase()));
+        let output = tokens.to_string().unwrap();
+        assert!(output.contains(
+            "// Check that a type implements it with `var _ ITestWorldLogger = (*T)(nil)`.\ntype ITestWorldLogger interface {"
+        ));
+    }
+
     #[test]
     fn test_record_type_generation() {
         use crate::codegen::ir::TypeDefinition;
@@ -777,8 +2047,7 @@ mod tests {
         let analyzer = ImportAnalyzer::new(&resolve, &world);
 
         // Test analyze_type_definition directly with the record kind
-        let type_def = &resolve.types[type_id];
-        let analyzed_definition = analyzer.analyze_type_definition(&type_def.kind).unwrap();
+        let analyzed_definition = analyzer.analyze_type_definition(type_id).unwrap();
 
         println!(
             "Direct analysis of type definition: {:?}",
@@ -999,8 +2268,7 @@ mod tests {
         let analyzer = ImportAnalyzer::new(&resolve, &world);
 
         // Test record analysis
-        let record_def = &resolve.types[record_type_id];
-        let record_analysis = analyzer.analyze_type_definition(&record_def.kind).unwrap();
+        let record_analysis = analyzer.analyze_type_definition(record_type_id).unwrap();
 
         match record_analysis {
             TypeDefinition::Record { .. } => {
@@ -1012,8 +2280,7 @@ mod tests {
         }
 
         // Test alias analysis
-        let alias_def = &resolve.types[alias_type_id];
-        let alias_analysis = analyzer.analyze_type_definition(&alias_def.kind).unwrap();
+        let alias_analysis = analyzer.analyze_type_definition(alias_type_id).unwrap();
 
         match alias_analysis {
             TypeDefinition::Alias { .. } => {
@@ -1026,4 +2293,234 @@ mod tests {
 
         println!("✓ Both record and alias types analyzed correctly");
     }
+
+    #[test]
+    fn test_record_equal() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "shapes.wit",
+                r#"
+                package arcjet:shapes;
+
+                interface types {
+                  record point {
+                    x: s32,
+                    y: s32,
+                  }
+
+                  record shape {
+                    name: string,
+                    origin: point,
+                    points: list<point>,
+                    tags: list<string>,
+                    grid: list<list<u8>>,
+                  }
+                }
+
+                world shapes {
+                  import types;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "shapes")
+            .expect("failed to find world");
+        let analyzed = ImportAnalyzer::new(&resolve, world).analyze();
+        let sizes = SizeAlign::default();
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+        let mut tokens = Tokens::<Go>::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        assert!(generated.contains("func (r Point) Equal(other Point) bool {"));
+        assert!(generated.contains("return r.X == other.X &&\n"));
+        assert!(generated.contains("func (r Shape) Equal(other Shape) bool {"));
+        assert!(generated.contains("r.Name == other.Name &&"));
+        assert!(generated.contains("r.Origin.Equal(other.Origin) &&"));
+        assert!(generated.contains(
+            "slices.EqualFunc(r.Points, other.Points, func(x, y Point) bool { return x.Equal(y) }) &&"
+        ));
+        assert!(generated.contains("slices.Equal(r.Tags, other.Tags) &&"));
+        assert!(generated.contains(
+            "slices.EqualFunc(r.Grid, other.Grid, func(x, y []uint8) bool { return slices.Equal(x, y) })"
+        ));
+    }
+
+    #[test]
+    fn test_array_field() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "tokens.wit",
+                r#"
+                package arcjet:tokens;
+
+                interface types {
+                  record token {
+                    /// The UUID of the token.
+                    /// @go-array:16
+                    id: list<u8>,
+                    data: list<u8>,
+                  }
+                }
+
+                world tokens {
+                  import types;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "tokens")
+            .expect("failed to find world");
+        let analyzed = ImportAnalyzer::new(&resolve, world).analyze();
+        let sizes = SizeAlign::default();
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+        let mut tokens = Tokens::<Go>::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Only the tagged field is an array, which can be compared with `==`
+        assert!(generated.contains("Id [16]uint8"));
+        assert!(generated.contains("Data []uint8"));
+        assert!(generated.contains("r.Id == other.Id &&"));
+        assert!(generated.contains("slices.Equal(r.Data, other.Data)"));
+    }
+
+    #[test]
+    fn test_wide_flags() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "files.wit",
+                r#"
+                package arcjet:files;
+
+                interface types {
+                  flags mode {
+                    read,
+                    write,
+                  }
+
+                  flags perms {
+                    f0, f1, f2, f3, f4, f5, f6, f7, f8, f9,
+                    f10, f11, f12, f13, f14, f15, f16, f17, f18, f19,
+                    f20, f21, f22, f23, f24, f25, f26, f27, f28, f29,
+                    f30, f31, f32, f33, f34, f35, f36, f37, f38, f39,
+                  }
+                }
+
+                world files {
+                  import types;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "files")
+            .expect("failed to find world");
+        let analyzed = ImportAnalyzer::new(&resolve, world).analyze();
+        let sizes = SizeAlign::default();
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+        let mut tokens = Tokens::<Go>::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Flags are sized to their member count
+        assert!(generated.contains("type Mode uint8"));
+        assert!(generated.contains("ModeRead Mode = 1 << iota"));
+        assert!(generated.contains("ModeWrite\n"));
+        assert!(generated.contains("type Perms uint64"));
+        assert!(generated.contains("PermsF0 Perms = 1 << iota"));
+        assert!(generated.contains("PermsF39\n"));
+    }
+
+    #[test]
+    fn test_record_validate() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "paints.wit",
+                r#"
+                package arcjet:paints;
+
+                interface types {
+                  enum color {
+                    red,
+                    green,
+                  }
+
+                  flags finish {
+                    matte,
+                    gloss,
+                  }
+
+                  record paint {
+                    name: string,
+                    color: color,
+                    finish: finish,
+                  }
+
+                  record palette {
+                    base: paint,
+                    accents: list<color>,
+                  }
+
+                  record swatch {
+                    name: string,
+                  }
+                }
+
+                world paints {
+                  import types;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "paints")
+            .expect("failed to find world");
+        let analyzed = ImportAnalyzer::new(&resolve, world).analyze();
+        let sizes = SizeAlign::default();
+        let generator = ImportCodeGenerator::new(&resolve, &analyzed, &sizes);
+        let mut tokens = Tokens::<Go>::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        assert!(generated.contains("func (r Paint) Validate() error {"));
+        assert!(generated.contains("switch r.Color {\n\tcase Red, Green:\n\tdefault:"));
+        assert!(generated.contains(r#"return fmt.Errorf("invalid Paint.Color: %v", r.Color)"#));
+        assert!(generated.contains("if r.Finish>>2 != 0 {"));
+        assert!(generated.contains(r#"return fmt.Errorf("invalid Paint.Finish: %#x", r.Finish)"#));
+
+        // Nested records and lists are validated too
+        assert!(generated.contains("func (r Palette) Validate() error {"));
+        assert!(generated.contains("if err := r.Base.Validate(); err != nil {"));
+        assert!(generated.contains(r#"return fmt.Errorf("invalid Palette.Base: %w", err)"#));
+        assert!(generated.contains("for _, v := range r.Accents {"));
+        assert!(
+            generated.contains(r#"return fmt.Errorf("invalid Palette.Accents element: %v", v)"#)
+        );
+
+        // Records without constrained fields have nothing to validate
+        assert!(!generated.contains("func (r Swatch) Validate() error {"));
+    }
 }
@@ -1,4 +1,4 @@
-use wit_bindgen_core::wit_parser::{Function, Type};
+use wit_bindgen_core::wit_parser::{Function, Type, TypeId};
 
 use crate::go::{GoIdentifier, GoType};
 
@@ -100,6 +100,8 @@ pub struct WitReturn {
 /// An analyzed WIT type definition.
 #[derive(Debug, Clone)]
 pub struct AnalyzedType {
+    /// The ID of the type in the WIT world.
+    pub id: TypeId,
     /// The name of the type in the WIT world.
     pub name: String,
     /// The Go identifier of the type.
@@ -119,7 +121,10 @@ pub enum TypeDefinition {
     },
     /// A simple enumeration with named constants
     Enum { cases: Vec<String> },
-    /// A type alias that wraps another type
+    /// A set of named bits, generated as an unsigned integer wide enough for
+    /// all of them
+    Flags { flags: Vec<String>, repr: GoType },
+    /// A type alias of a primitive type, generated as a Go defined type
     Alias { target: GoType },
     /// A primitive type that doesn't need special handling
     Primitive,
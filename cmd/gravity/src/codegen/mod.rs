@@ -1,13 +1,22 @@
+mod benchmarks;
+mod binary;
 mod bindings;
+mod examples;
 mod exports;
 mod factory;
 mod func;
 mod imports;
 mod ir;
+mod scaffold;
+mod service;
 mod wasm;
 
+pub use benchmarks::BenchmarkGenerator;
 pub use bindings::*;
+pub use examples::ExampleGenerator;
 pub use exports::ExportGenerator;
 pub use factory::FactoryGenerator;
 pub use func::Func;
-pub use wasm::WasmData;
+pub use scaffold::ScaffoldGenerator;
+pub use service::ServiceGenerator;
+pub use wasm::{WasmData, compress_wasm};
@@ -0,0 +1,264 @@
+use genco::prelude::*;
+use wit_bindgen_core::wit_parser::{Resolve, World, WorldItem};
+
+use crate::{
+    codegen::ir::{AnalyzedImports, AnalyzedInterface},
+    go::{
+        GoIdentifier, GoType, Naming, OptionStyle, Renames, comment,
+        imports::{CONTEXT_CONTEXT, TESTING_T},
+    },
+};
+
+/// Configuration for test scaffold generation.
+pub struct ScaffoldConfig<'a> {
+    pub analyzed_imports: &'a AnalyzedImports,
+    pub world: &'a World,
+    pub resolve: &'a Resolve,
+    pub option_style: OptionStyle,
+    /// The naming strategy for type and function names.
+    pub naming: &'a Naming,
+}
+
+/// Generator for a skeleton `_test.go` file for a world.
+///
+/// The scaffold is a starting point meant to be edited: it stubs every
+/// imported interface with methods returning zero values, and has a
+/// `TestSmoke` calling every exported function with zero values.
+pub struct ScaffoldGenerator<'a> {
+    config: ScaffoldConfig<'a>,
+    renames: Renames,
+}
+
+impl<'a> ScaffoldGenerator<'a> {
+    /// Create a new scaffold generator with the given config.
+    pub fn new(config: ScaffoldConfig<'a>) -> Self {
+        Self {
+            config,
+            renames: Renames::default(),
+        }
+    }
+
+    /// Set the Go identifiers overriding the generated ones.
+    pub fn with_renames(mut self, renames: Renames) -> Self {
+        self.renames = renames;
+        self
+    }
+
+    /// The name of the stub implementing the given interface.
+    fn stub_name(&self, interface: &AnalyzedInterface) -> GoIdentifier {
+        GoIdentifier::private(format!(
+            "stub-{}-{}",
+            self.config.world.name, interface.name
+        ))
+    }
+
+    /// Generate a stub implementation of an imported interface, whose
+    /// methods return zero values.
+    fn generate_stub(&self, interface: &AnalyzedInterface, tokens: &mut Tokens<Go>) {
+        let stub_name = &self.stub_name(interface);
+        let docs = [format!(
+            "{} is a stub implementation of {}.",
+            String::from(stub_name),
+            String::from(&interface.go_interface_name),
+        )];
+        quote_in! { *tokens =>
+            $['\n']
+            $(comment(docs))
+            type $stub_name struct{}
+            $(for method in &interface.methods =>
+                $['\n']
+                func ($stub_name) $(&method.go_method_name)(
+                    $['\r']
+                    ctx $CONTEXT_CONTEXT,
+                    $(for param in &method.parameters join ($['\r']) => $(&param.name) $(&param.go_type),)
+                ) $(zero_results(method.return_type.as_ref().map(|ret| &ret.go_type))) {
+                    return
+                }
+            )
+        };
+    }
+
+    /// Generate the smoke test, calling every exported function once.
+    fn generate_smoke_test(&self, tokens: &mut Tokens<Go>) {
+        let AnalyzedImports {
+            constructor_name,
+            interfaces,
+            ..
+        } = self.config.analyzed_imports;
+        let stubs = interfaces
+            .iter()
+            .map(|interface| self.stub_name(interface))
+            .collect::<Vec<_>>();
+        let functions = self
+            .config
+            .world
+            .exports
+            .values()
+            .filter_map(|item| match item {
+                WorldItem::Function(func) => Some(func),
+                _ => None,
+            })
+            .map(|func| {
+                let params = func
+                    .params
+                    .iter()
+                    .flat_map(|(name, wit_type)| {
+                        let typ = crate::resolve_type_with(
+                            wit_type,
+                            self.config.resolve,
+                            false,
+                            self.config.naming,
+                        );
+                        self.config.option_style.go_type(typ).params(name)
+                    })
+                    .collect::<Vec<_>>();
+                (self.renames.export(self.config.naming, func), params)
+            })
+            .collect::<Vec<_>>();
+        quote_in! { *tokens =>
+            $['\n']
+            $(comment(&[
+                "TestSmoke instantiates the module and calls every exported function with",
+                "zero values.",
+            ]))
+            func TestSmoke(t *$TESTING_T) {
+                fac, err := $constructor_name(
+                    $['\r']
+                    t.Context(),
+                    $(for stub in &stubs join ($['\r']) => $stub{},)
+                )
+                if err != nil {
+                    t.Fatal(err)
+                }
+                defer fac.Close(t.Context())
+
+                ins, err := fac.Instantiate(t.Context())
+                if err != nil {
+                    t.Fatal(err)
+                }
+                defer ins.Close(t.Context())
+                $(for (fn_name, params) in &functions =>
+                    $['\n']
+                    t.Run($(quoted(String::from(fn_name))), func(t *$TESTING_T) {
+                        $(for (name, typ) in params join ($['\r']) => var $name $typ)
+                        ins.$fn_name(
+                            $['\r']
+                            t.Context(),
+                            $(for (name, _) in params join ($['\r']) => $name,)
+                        )
+                    })
+                )
+            }
+        };
+    }
+}
+
+/// Returns the named results of a stub method, so it can return zero values
+/// with a bare `return`.
+fn zero_results(typ: Option<&GoType>) -> Tokens<Go> {
+    match typ {
+        None | Some(GoType::Nothing) => Tokens::new(),
+        Some(GoType::ValueOrError(typ)) => quote!((_ $(typ.as_ref()), _ error)),
+        Some(GoType::ValueOrOk(typ)) => quote!((_ $(typ.as_ref()), _ bool)),
+        Some(GoType::MultiReturn(typs)) => quote!(($(for typ in typs join (, ) => _ $typ))),
+        Some(typ) => quote!((_ $typ)),
+    }
+}
+
+impl FormatInto<Go> for ScaffoldGenerator<'_> {
+    fn format_into(self, tokens: &mut Tokens<Go>) {
+        for interface in &self.config.analyzed_imports.interfaces {
+            self.generate_stub(interface, tokens);
+        }
+        self.generate_smoke_test(tokens);
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use genco::prelude::*;
+    use wit_bindgen_core::wit_parser::Resolve;
+
+    use crate::{
+        codegen::imports::ImportAnalyzer,
+        go::{GoType, Naming, OptionStyle},
+    };
+
+    use super::{ScaffoldConfig, ScaffoldGenerator, zero_results};
+
+    #[test]
+    fn test_zero_results() {
+        let cases = [
+            (None, ""),
+            (Some(GoType::Uint32), "(_ uint32)"),
+            (Some(GoType::Error), "(_ error)"),
+            (
+                Some(GoType::ValueOrError(Box::new(GoType::String))),
+                "(_ string, _ error)",
+            ),
+            (
+                Some(GoType::MultiReturn(vec![GoType::Uint32, GoType::String])),
+                "(_ uint32, _ string)",
+            ),
+        ];
+        for (typ, expected) in cases {
+            let actual = zero_results(typ.as_ref()).to_string().unwrap();
+            assert_eq!(actual, expected, "{typ:?}");
+        }
+    }
+
+    #[test]
+    fn test_scaffold() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "greeter.wit",
+                r#"
+                package arcjet:greeter;
+
+                interface names {
+                  lookup: func(id: u32) -> result<string, string>;
+                }
+
+                world greeter {
+                  import names;
+
+                  export greet: func(id: u32, excited: option<bool>) -> string;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "greeter")
+            .expect("failed to find world");
+        let analyzed_imports = ImportAnalyzer::new(&resolve, world).analyze();
+        let naming = Naming::default();
+        let generator = ScaffoldGenerator::new(ScaffoldConfig {
+            analyzed_imports: &analyzed_imports,
+            world,
+            resolve: &resolve,
+            option_style: OptionStyle::Pair,
+            naming: &naming,
+        });
+        let mut tokens = Tokens::<Go>::new();
+        generator.format_into(&mut tokens);
+
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Imports are stubbed with methods returning zero values
+        assert!(generated.contains("type stubGreeterNames struct{}"));
+        assert!(generated.contains("func (stubGreeterNames) Lookup("));
+        assert!(generated.contains(") (_ string, _ error) {"));
+
+        // Every export is called with zero values
+        assert!(generated.contains("func TestSmoke(t *testing.T) {"));
+        assert!(generated.contains("stubGreeterNames{},"));
+        assert!(generated.contains("t.Run(\"Greet\", func(t *testing.T) {"));
+        assert!(generated.contains("var id uint32"));
+        assert!(generated.contains("var excited bool"));
+        assert!(generated.contains("ins.Greet("));
+    }
+}
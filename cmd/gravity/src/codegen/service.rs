@@ -0,0 +1,295 @@
+use genco::prelude::*;
+use wit_bindgen_core::wit_parser::{Function, Resolve, Type, World, WorldItem};
+
+use crate::{
+    codegen::ir::AnalyzedImports,
+    go::{GoIdentifier, GoType, Naming, OptionStyle, Renames, comment, imports::CONTEXT_CONTEXT},
+};
+
+/// Configuration for service generation.
+pub struct ServiceConfig<'a> {
+    pub analyzed_imports: &'a AnalyzedImports,
+    pub world: &'a World,
+    pub resolve: &'a Resolve,
+    pub option_style: OptionStyle,
+    pub named_lists: bool,
+    /// The naming strategy for type and function names.
+    pub naming: &'a Naming,
+}
+
+/// Generator for a gRPC-style service wrapping the functions exported by a
+/// world.
+///
+/// The service has a method for every exported function, taking a context
+/// and a request struct with a field per parameter, and returning a response
+/// struct with the results and an error. This is the shape of the handlers
+/// protoc generates, so an adapter from the messages of a gRPC service only
+/// has to copy their fields.
+pub struct ServiceGenerator<'a> {
+    config: ServiceConfig<'a>,
+    renames: Renames,
+}
+
+impl<'a> ServiceGenerator<'a> {
+    /// Create a new service generator with the given config.
+    pub fn new(config: ServiceConfig<'a>) -> Self {
+        Self {
+            config,
+            renames: Renames::default(),
+        }
+    }
+
+    /// Set the Go identifiers overriding the generated ones.
+    pub fn with_renames(mut self, renames: Renames) -> Self {
+        self.renames = renames;
+        self
+    }
+
+    /// Resolves the Go type of a WIT type, as in the signatures of the
+    /// exported functions.
+    fn go_type(&self, wit_type: &Type) -> GoType {
+        self.config.option_style.go_type(crate::resolve_type_with(
+            wit_type,
+            self.config.resolve,
+            self.config.named_lists,
+            self.config.naming,
+        ))
+    }
+
+    /// Returns the fields of the request of the given function, with the
+    /// parameter of the exported function each is passed as.
+    fn request_fields(&self, func: &Function) -> Vec<(GoIdentifier, GoType)> {
+        func.params
+            .iter()
+            .flat_map(|(name, wit_type)| {
+                // Results are passed as a value and an error, which is the
+                // field suffixed with `Err`
+                let names = [
+                    GoIdentifier::public(name),
+                    GoIdentifier::public(format!("{name}-err")),
+                ];
+                names
+                    .into_iter()
+                    .zip(self.go_type(wit_type).params(name))
+                    .map(|(field, (_, typ))| (field, typ))
+            })
+            .collect()
+    }
+
+    /// Generates the request and response types of the given function, and
+    /// the method of the service calling it.
+    fn generate_method(&self, service: &GoIdentifier, func: &Function, tokens: &mut Tokens<Go>) {
+        let fn_name = &self.renames.export(self.config.naming, func);
+        let method = String::from(fn_name);
+        let request = &format!("{method}Request");
+        let response = &format!("{method}Response");
+        let fields = self.request_fields(func);
+        let args = std::iter::once(quote!(ctx))
+            .chain(fields.iter().map(|(field, _)| quote!(req.$field)))
+            .collect::<Vec<_>>();
+        let call = &quote!(ins.$fn_name($(for arg in &args join (, ) => $arg)));
+
+        // The response has a field per result, and the statements calling
+        // the function return it
+        let result = func.result.as_ref().map(|typ| self.go_type(typ));
+        let (response_fields, body): (Vec<Tokens<Go>>, Tokens<Go>) = match result {
+            None => (
+                vec![],
+                quote! {
+                    $call
+                    return &$response{}, nil
+                },
+            ),
+            Some(GoType::Error) => (
+                vec![],
+                quote! {
+                    if err := $call; err != nil {
+                        return nil, err
+                    }
+                    return &$response{}, nil
+                },
+            ),
+            Some(GoType::ValueOrError(typ)) => (
+                vec![quote!(Result $(typ.as_ref()))],
+                quote! {
+                    result, err := $call
+                    if err != nil {
+                        return nil, err
+                    }
+                    return &$response{Result: result}, nil
+                },
+            ),
+            Some(GoType::ValueOrOk(typ)) => (
+                vec![quote!(Result $(typ.as_ref())), quote!(Ok bool)],
+                quote! {
+                    result, ok := $call
+                    return &$response{Result: result, Ok: ok}, nil
+                },
+            ),
+            Some(GoType::MultiReturn(typs)) => {
+                let results = (0..typs.len())
+                    .map(|i| format!("result{i}"))
+                    .collect::<Vec<_>>();
+                (
+                    typs.iter()
+                        .enumerate()
+                        .map(|(i, typ)| quote!($(format!("F{i}")) $typ))
+                        .collect(),
+                    quote! {
+                        $(for result in &results join (, ) => $result) := $call
+                        return &$response{$(for (i, result) in results.iter().enumerate() join (, ) => $(format!("F{i}")): $result)}, nil
+                    },
+                )
+            }
+            Some(typ) => (
+                vec![quote!(Result $(&typ))],
+                quote! {
+                    result := $call
+                    return &$response{Result: result}, nil
+                },
+            ),
+        };
+
+        quote_in! { *tokens =>
+            $['\n']
+            $(comment([
+                format!("{request} is the request of {}.{method}, with a field for each", String::from(service)),
+                format!("parameter of the exported {} function.", func.name),
+            ]))
+            $(if fields.is_empty() {
+                type $request struct{}
+            } else {
+                type $request struct {
+                    $(for (field, typ) in &fields join ($['\r']) => $field $typ)
+                }
+            })
+            $['\n']
+            $(comment([format!("{response} is the response of {}.{method}.", String::from(service))]))
+            $(if response_fields.is_empty() {
+                type $response struct{}
+            } else {
+                type $response struct {
+                    $(for field in &response_fields join ($['\r']) => $field)
+                }
+            })
+            $['\n']
+            $(comment([format!("{method} calls {} on a new instance with the fields of req.", func.name)]))
+            func (s *$service) $fn_name(ctx $CONTEXT_CONTEXT, req *$request) (*$response, error) {
+                ins, err := s.factory.Instantiate(ctx)
+                if err != nil {
+                    return nil, err
+                }
+                defer ins.Close(ctx)
+                $body
+            }
+        }
+    }
+}
+
+impl FormatInto<Go> for ServiceGenerator<'_> {
+    fn format_into(self, tokens: &mut Tokens<Go>) {
+        let factory = &self.config.analyzed_imports.factory_name;
+        let world = &self.config.world.name;
+        let service = &GoIdentifier::public(format!("{world}-service"));
+        let constructor = &GoIdentifier::public(format!("new-{world}-service"));
+        quote_in! { *tokens =>
+            $['\n']
+            $(comment([
+                format!("{} serves the functions exported by the {world} world in the", String::from(service)),
+                "style of a gRPC service: each method takes a request with the arguments of".to_string(),
+                "a function, and returns a response with its results. Every call is made".to_string(),
+                "on a new instance, so the service can be called concurrently.".to_string(),
+            ]))
+            type $service struct {
+                factory *$factory
+            }
+            $['\n']
+            $(comment([format!(
+                "{} returns a service calling the functions of instances of factory.",
+                String::from(constructor)
+            )]))
+            func $constructor(factory *$factory) *$service {
+                return &$service{factory: factory}
+            }
+        }
+        for item in self.config.world.exports.values() {
+            if let WorldItem::Function(func) = item {
+                self.generate_method(service, func, tokens);
+            }
+        }
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use genco::prelude::*;
+    use wit_bindgen_core::wit_parser::Resolve;
+
+    use crate::{
+        codegen::imports::ImportAnalyzer,
+        go::{Naming, OptionStyle},
+    };
+
+    use super::{ServiceConfig, ServiceGenerator};
+
+    #[test]
+    fn test_generate_service() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "shop.wit",
+                r#"
+                package arcjet:shop;
+
+                world shop {
+                  export price: func(sku: string, quantity: u32) -> result<u64, string>;
+                  export stock: func(sku: string) -> option<u32>;
+                  export split: func(total: u64) -> tuple<u64, u64>;
+                  export clear: func();
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "shop")
+            .expect("failed to find world");
+        let analyzed_imports = ImportAnalyzer::new(&resolve, world).analyze();
+        let config = ServiceConfig {
+            analyzed_imports: &analyzed_imports,
+            world,
+            resolve: &resolve,
+            option_style: OptionStyle::Pair,
+            named_lists: false,
+            naming: &Naming::default(),
+        };
+        let mut tokens = Tokens::<Go>::new();
+        ServiceGenerator::new(config).format_into(&mut tokens);
+        let generated = tokens.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        assert!(generated.contains("type ShopService struct {\n\tfactory *ShopFactory\n}"));
+        assert!(generated.contains("func NewShopService(factory *ShopFactory) *ShopService {"));
+
+        // Every method calls the function on a new instance
+        assert!(generated.contains(
+            "func (s *ShopService) Price(ctx context.Context, req *PriceRequest) (*PriceResponse, error) {"
+        ));
+        assert!(generated.contains("ins, err := s.factory.Instantiate(ctx)"));
+        assert!(generated.contains("defer ins.Close(ctx)"));
+
+        // Parameters are fields of the request, and results of the response
+        assert!(
+            generated.contains("type PriceRequest struct {\n\tSku string\n\tQuantity uint32\n}")
+        );
+        assert!(generated.contains("result, err := ins.Price(ctx, req.Sku, req.Quantity)"));
+        assert!(generated.contains("return &PriceResponse{Result: result}, nil"));
+        assert!(generated.contains("type StockResponse struct {\n\tResult uint32\n\tOk bool\n}"));
+        assert!(generated.contains("return &StockResponse{Result: result, Ok: ok}, nil"));
+        assert!(generated.contains("result0, result1 := ins.Split(ctx, req.Total)"));
+        assert!(generated.contains("return &SplitResponse{F0: result0, F1: result1}, nil"));
+        assert!(generated.contains("type ClearRequest struct{}"));
+        assert!(generated.contains("ins.Clear(ctx)\n\treturn &ClearResponse{}, nil"));
+    }
+}
@@ -1,7 +1,20 @@
+use std::io::Write;
+
+use flate2::{Compression, write::GzEncoder};
 use genco::prelude::*;
 
 use crate::go::{GoIdentifier, embed};
 
+/// Compresses WebAssembly bytes with gzip, for the bindings to decompress
+/// when the first factory is created.
+pub fn compress_wasm(wasm: &[u8]) -> Vec<u8> {
+    let mut encoder = GzEncoder::new(Vec::new(), Compression::best());
+    encoder
+        .write_all(wasm)
+        .expect("writing to a Vec can't fail");
+    encoder.finish().expect("writing to a Vec can't fail")
+}
+
 /// The WebAssembly data for a world, either inline or embedded using go:embed.
 pub enum WasmData<'a> {
     /// The WebAssembly file is inlined as a byte array.
@@ -54,13 +67,29 @@ impl FormatInto<Go> for Wasm<'_> {
 
 #[cfg(test)]
 mod tests {
+    use std::io::Read;
+
+    use flate2::read::GzDecoder;
     use genco::{prelude::*, tokens::Tokens};
 
     use crate::{
-        codegen::wasm::{Wasm, WasmData},
+        codegen::wasm::{Wasm, WasmData, compress_wasm},
         go::GoIdentifier,
     };
 
+    #[test]
+    fn test_compress_wasm() {
+        let wasm = b"\0asm\x01\0\0\0".repeat(64);
+        let compressed = compress_wasm(&wasm);
+        assert!(compressed.len() < wasm.len());
+
+        let mut decompressed = Vec::new();
+        GzDecoder::new(compressed.as_slice())
+            .read_to_end(&mut decompressed)
+            .unwrap();
+        assert_eq!(decompressed, wasm);
+    }
+
     #[test]
     fn test_inline_wasm() {
         let var = GoIdentifier::private("wasm");
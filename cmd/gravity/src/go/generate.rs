@@ -0,0 +1,115 @@
+/// Returns a `//go:generate` directive running gravity with the given
+/// arguments, so `go generate` regenerates the output the same way.
+///
+/// `--out-test` and its path are left out, as the scaffold is meant to be
+/// edited and regenerating it would overwrite the edits.
+///
+/// Arguments `go generate` would split or unquote are quoted as Go strings,
+/// and `$` is escaped as `$DOLLAR` so it isn't expanded as a variable.
+pub fn generate_directive<I>(args: I) -> String
+where
+    I: IntoIterator,
+    I::Item: AsRef<str>,
+{
+    let mut directive = String::from("//go:generate gravity");
+    let mut args = args.into_iter();
+    while let Some(arg) = args.next() {
+        if arg.as_ref() == "--out-test" {
+            args.next();
+            continue;
+        }
+        if arg.as_ref().starts_with("--out-test=") {
+            continue;
+        }
+        directive.push(' ');
+        directive.push_str(&quote_arg(&escape_dollars(arg.as_ref())));
+    }
+    directive
+}
+
+/// Escapes every `$` of an argument as `$DOLLAR`, which `go generate` expands
+/// back to `$`. It's braced when followed by a character which would otherwise
+/// be read as part of the variable name.
+fn escape_dollars(arg: &str) -> String {
+    let mut escaped = String::new();
+    let mut chars = arg.chars().peekable();
+    while let Some(c) = chars.next() {
+        if c != '$' {
+            escaped.push(c);
+        } else if chars
+            .peek()
+            .is_some_and(|c| c.is_alphanumeric() || *c == '_')
+        {
+            escaped.push_str("${DOLLAR}");
+        } else {
+            escaped.push_str("$DOLLAR");
+        }
+    }
+    escaped
+}
+
+/// Quotes an argument of a `//go:generate` directive if it's empty, or
+/// contains whitespace or quotes.
+fn quote_arg(arg: &str) -> String {
+    if !arg.is_empty() && !arg.contains(|c: char| c.is_whitespace() || c == '"') {
+        return arg.to_string();
+    }
+    let mut quoted = String::from('"');
+    for c in arg.chars() {
+        match c {
+            '"' => quoted.push_str("\\\""),
+            '\\' => quoted.push_str("\\\\"),
+            '\n' => quoted.push_str("\\n"),
+            '\r' => quoted.push_str("\\r"),
+            '\t' => quoted.push_str("\\t"),
+            c => quoted.push(c),
+        }
+    }
+    quoted.push('"');
+    quoted
+}
+
+#[cfg(test)]
+mod tests {
+    use crate::go::generate_directive;
+
+    #[test]
+    fn test_generate_directive() {
+        assert_eq!(
+            generate_directive(["--world", "basic", "-o", "basic.go", "basic.wasm"]),
+            "//go:generate gravity --world basic -o basic.go basic.wasm"
+        );
+        assert_eq!(generate_directive([] as [&str; 0]), "//go:generate gravity");
+    }
+
+    #[test]
+    fn test_generate_directive_out_test() {
+        assert_eq!(
+            generate_directive([
+                "--out-test",
+                "basic_test.go",
+                "-o",
+                "basic.go",
+                "--out-test=other_test.go",
+                "basic.wasm",
+            ]),
+            "//go:generate gravity -o basic.go basic.wasm"
+        );
+    }
+
+    #[test]
+    fn test_generate_directive_quoted() {
+        assert_eq!(
+            generate_directive(["-o", "my bindings/basic.go", "", r#"a"b\c"#]),
+            r#"//go:generate gravity -o "my bindings/basic.go" "" "a\"b\\c""#
+        );
+    }
+
+    #[test]
+    fn test_generate_directive_dollars() {
+        assert_eq!(
+            generate_directive(["-o", "$GOFILE", "a$", "$ b", "$-"]),
+            r#"//go:generate gravity -o ${DOLLAR}GOFILE a$DOLLAR "$DOLLAR b" $DOLLAR-"#
+        );
+    }
+}
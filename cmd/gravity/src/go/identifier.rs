@@ -58,6 +58,91 @@ impl GoIdentifier {
     }
 }
 
+/// The initialisms kept intact by `FieldCase::Initialisms` by default, taken
+/// from the Go linters.
+pub const DEFAULT_INITIALISMS: &[&str] = &[
+    "ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP", "HTTPS", "ID", "IP",
+    "JSON", "LHS", "QPS", "RAM", "RHS", "RPC", "SLA", "SMTP", "SQL", "SSH", "TCP", "TLS", "TTL",
+    "UDP", "UI", "UID", "UUID", "URI", "URL", "UTF8", "VM", "XML", "XMPP", "XSRF", "XSS",
+];
+
+/// A naming strategy for the Go identifiers generated from WIT names.
+#[derive(Debug, Clone, Default)]
+pub enum FieldCase {
+    /// Names are exported in PascalCase, e.g. `HttpStatus`.
+    #[default]
+    Pascal,
+    /// Names are exported in PascalCase, but the given initialisms are kept
+    /// in upper case, e.g. `HTTPStatus`.
+    Initialisms(Vec<String>),
+}
+
+impl FieldCase {
+    /// Returns the given WIT name with its words which are initialisms in
+    /// upper case, e.g. `user-ID`, which is exported as `UserID`.
+    ///
+    /// Names are kept as is with `FieldCase::Pascal`.
+    pub fn name(&self, name: &str) -> String {
+        match self {
+            FieldCase::Pascal => name.to_string(),
+            FieldCase::Initialisms(initialisms) => name
+                .split('-')
+                .map(|word| {
+                    let upper = word.to_uppercase();
+                    if initialisms.contains(&upper) {
+                        upper
+                    } else {
+                        word.to_string()
+                    }
+                })
+                .collect::<Vec<_>>()
+                .join("-"),
+        }
+    }
+
+    /// Returns the Go identifier of a struct field with the given WIT name.
+    pub fn field(&self, name: &str) -> GoIdentifier {
+        GoIdentifier::public(self.name(name))
+    }
+}
+
+/// The naming strategies for the fields of generated structs, and for the
+/// types and functions generated from WIT names.
+#[derive(Debug, Clone)]
+pub struct Naming {
+    /// The strategy for the fields of generated structs.
+    pub fields: FieldCase,
+    /// The strategy for types and functions.
+    pub names: FieldCase,
+}
+
+impl Default for Naming {
+    /// Fields are in PascalCase, while types and functions keep the default
+    /// initialisms intact, e.g. `UserID`.
+    fn default() -> Self {
+        Self {
+            fields: FieldCase::Pascal,
+            names: FieldCase::Initialisms(
+                DEFAULT_INITIALISMS.iter().map(|s| s.to_string()).collect(),
+            ),
+        }
+    }
+}
+
+impl Naming {
+    /// Returns the given WIT name of a type or function with its words which
+    /// are initialisms in upper case, following `names`.
+    pub fn name(&self, name: &str) -> String {
+        self.names.name(name)
+    }
+
+    /// Returns the Go identifier of a struct field with the given WIT name,
+    /// following `fields`.
+    pub fn field(&self, name: &str) -> GoIdentifier {
+        self.fields.field(name)
+    }
+}
+
 impl From<GoIdentifier> for String {
     fn from(value: GoIdentifier) -> Self {
         (&value).into()
@@ -109,7 +194,7 @@ mod tests {
 
     use genco::{prelude::*, tokens::Tokens};
 
-    use crate::go::GoIdentifier;
+    use crate::go::{DEFAULT_INITIALISMS, FieldCase, GoIdentifier, Naming};
 
     #[test]
     fn test_public_identifier() {
@@ -134,4 +219,38 @@ mod tests {
         (&id).format_into(&mut tokens);
         assert_eq!(tokens.to_string().unwrap(), "helloWorld");
     }
+
+    #[test]
+    fn test_field_case() {
+        let pascal = FieldCase::Pascal;
+        assert_eq!(String::from(pascal.field("http-status")), "HttpStatus");
+        assert_eq!(String::from(pascal.field("id")), "Id");
+
+        let initialisms =
+            FieldCase::Initialisms(DEFAULT_INITIALISMS.iter().map(|s| s.to_string()).collect());
+        assert_eq!(String::from(initialisms.field("http-status")), "HTTPStatus");
+        assert_eq!(String::from(initialisms.field("id")), "ID");
+        assert_eq!(String::from(initialisms.field("user-id")), "UserID");
+        assert_eq!(String::from(initialisms.field("identity")), "Identity");
+
+        // Types and functions are named alike
+        assert_eq!(initialisms.name("user-id"), "user-ID");
+        assert_eq!(initialisms.name("next-id"), "next-ID");
+        assert_eq!(FieldCase::Pascal.name("user-id"), "user-id");
+    }
+
+    #[test]
+    fn test_naming() {
+        // Types and functions keep initialisms by default, but fields don't
+        let naming = Naming::default();
+        assert_eq!(naming.name("user-id"), "user-ID");
+        assert_eq!(String::from(naming.field("user-id")), "UserId");
+
+        let naming = Naming {
+            fields: FieldCase::Initialisms(vec!["ID".to_string()]),
+            names: FieldCase::Pascal,
+        };
+        assert_eq!(naming.name("user-id"), "user-id");
+        assert_eq!(String::from(naming.field("user-id")), "UserID");
+    }
 }
@@ -9,17 +9,101 @@ impl FormatInto<Go> for GoImport {
     }
 }
 
+pub static BYTES_CLONE: GoImport = GoImport("bytes", "Clone");
+pub static BYTES_NEW_READER: GoImport = GoImport("bytes", "NewReader");
+pub static GZIP_NEW_READER: GoImport = GoImport("compress/gzip", "NewReader");
 pub static CONTEXT_CONTEXT: GoImport = GoImport("context", "Context");
+pub static CONTEXT_BACKGROUND: GoImport = GoImport("context", "Background");
+pub static SHA256_SUM256: GoImport = GoImport("crypto/sha256", "Sum256");
+pub static BINARY_LITTLE_ENDIAN: GoImport = GoImport("encoding/binary", "LittleEndian");
+pub static HEX_ENCODE_TO_STRING: GoImport = GoImport("encoding/hex", "EncodeToString");
 pub static ERRORS_NEW: GoImport = GoImport("errors", "New");
+pub static FMT_ERRORF: GoImport = GoImport("fmt", "Errorf");
 pub static FMT_PRINTF: GoImport = GoImport("fmt", "Printf");
+pub static FMT_PRINTLN: GoImport = GoImport("fmt", "Println");
+pub static FMT_SPRINTF: GoImport = GoImport("fmt", "Sprintf");
+pub static IO_READ_ALL: GoImport = GoImport("io", "ReadAll");
+pub static IO_READER: GoImport = GoImport("io", "Reader");
+pub static IO_WRITER: GoImport = GoImport("io", "Writer");
+pub static IO_WRITE_STRING: GoImport = GoImport("io", "WriteString");
+pub static ITER_SEQ2: GoImport = GoImport("iter", "Seq2");
+pub static LOG_FATAL: GoImport = GoImport("log", "Fatal");
+pub static SLOG_LOGGER: GoImport = GoImport("log/slog", "Logger");
+pub static MATH_FLOAT32_BITS: GoImport = GoImport("math", "Float32bits");
+pub static MATH_FLOAT32_FROM_BITS: GoImport = GoImport("math", "Float32frombits");
+pub static MATH_FLOAT64_BITS: GoImport = GoImport("math", "Float64bits");
+pub static MATH_FLOAT64_FROM_BITS: GoImport = GoImport("math", "Float64frombits");
+pub static SLICES_EQUAL: GoImport = GoImport("slices", "Equal");
+pub static SLICES_EQUAL_FUNC: GoImport = GoImport("slices", "EqualFunc");
+pub static RUNTIME_ADD_CLEANUP: GoImport = GoImport("runtime", "AddCleanup");
+pub static RUNTIME_CLEANUP: GoImport = GoImport("runtime", "Cleanup");
+pub static SLICES_GROW: GoImport = GoImport("slices", "Grow");
+pub static STRINGS_CONTAINS: GoImport = GoImport("strings", "Contains");
+pub static SYNC_ATOMIC_BOOL: GoImport = GoImport("sync/atomic", "Bool");
+pub static SYNC_MUTEX: GoImport = GoImport("sync", "Mutex");
+pub static SYNC_ONCE_VALUES: GoImport = GoImport("sync", "OnceValues");
+pub static TIME_TIME: GoImport = GoImport("time", "Time");
+pub static TESTING_B: GoImport = GoImport("testing", "B");
+pub static TESTING_T: GoImport = GoImport("testing", "T");
+pub static UNSAFE_SLICE_DATA: GoImport = GoImport("unsafe", "SliceData");
+pub static UNSAFE_STRING: GoImport = GoImport("unsafe", "String");
 pub static WAZERO_RUNTIME: GoImport = GoImport("github.com/tetratelabs/wazero", "Runtime");
-pub static WAZERO_NEW_RUNTIME: GoImport = GoImport("github.com/tetratelabs/wazero", "NewRuntime");
+pub static WAZERO_NEW_RUNTIME_WITH_CONFIG: GoImport =
+    GoImport("github.com/tetratelabs/wazero", "NewRuntimeWithConfig");
+pub static WAZERO_RUNTIME_CONFIG: GoImport =
+    GoImport("github.com/tetratelabs/wazero", "RuntimeConfig");
+pub static WAZERO_NEW_RUNTIME_CONFIG: GoImport =
+    GoImport("github.com/tetratelabs/wazero", "NewRuntimeConfig");
+pub static WAZERO_NEW_RUNTIME_CONFIG_INTERPRETER: GoImport = GoImport(
+    "github.com/tetratelabs/wazero",
+    "NewRuntimeConfigInterpreter",
+);
+pub static WAZERO_NEW_RUNTIME_CONFIG_COMPILER: GoImport =
+    GoImport("github.com/tetratelabs/wazero", "NewRuntimeConfigCompiler");
 pub static WAZERO_NEW_MODULE_CONFIG: GoImport =
     GoImport("github.com/tetratelabs/wazero", "NewModuleConfig");
+pub static WAZERO_MODULE_CONFIG: GoImport =
+    GoImport("github.com/tetratelabs/wazero", "ModuleConfig");
 pub static WAZERO_COMPILED_MODULE: GoImport =
     GoImport("github.com/tetratelabs/wazero", "CompiledModule");
+pub static WAZERO_COMPILATION_CACHE: GoImport =
+    GoImport("github.com/tetratelabs/wazero", "CompilationCache");
+pub static WAZERO_SYS_CLOCK_RESOLUTION: GoImport =
+    GoImport("github.com/tetratelabs/wazero/sys", "ClockResolution");
+pub static WASI_SNAPSHOT_PREVIEW1_INSTANTIATE: GoImport = GoImport(
+    "github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1",
+    "Instantiate",
+);
+pub static WASI_SNAPSHOT_PREVIEW1_MODULE_NAME: GoImport = GoImport(
+    "github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1",
+    "ModuleName",
+);
+pub static WAZERO_EXPERIMENTAL_WITH_MEMORY_ALLOCATOR: GoImport = GoImport(
+    "github.com/tetratelabs/wazero/experimental",
+    "WithMemoryAllocator",
+);
+pub static WAZERO_EXPERIMENTAL_MEMORY_ALLOCATOR_FUNC: GoImport = GoImport(
+    "github.com/tetratelabs/wazero/experimental",
+    "MemoryAllocatorFunc",
+);
+pub static WAZERO_EXPERIMENTAL_LINEAR_MEMORY: GoImport =
+    GoImport("github.com/tetratelabs/wazero/experimental", "LinearMemory");
 pub static WAZERO_API_MODULE: GoImport = GoImport("github.com/tetratelabs/wazero/api", "Module");
 pub static WAZERO_API_MEMORY: GoImport = GoImport("github.com/tetratelabs/wazero/api", "Memory");
+pub static WAZERO_API_FUNCTION: GoImport =
+    GoImport("github.com/tetratelabs/wazero/api", "Function");
+pub static WAZERO_API_GO_MODULE_FUNC: GoImport =
+    GoImport("github.com/tetratelabs/wazero/api", "GoModuleFunc");
+pub static WAZERO_API_VALUE_TYPE: GoImport =
+    GoImport("github.com/tetratelabs/wazero/api", "ValueType");
+pub static WAZERO_API_VALUE_TYPE_I32: GoImport =
+    GoImport("github.com/tetratelabs/wazero/api", "ValueTypeI32");
+pub static WAZERO_API_VALUE_TYPE_I64: GoImport =
+    GoImport("github.com/tetratelabs/wazero/api", "ValueTypeI64");
+pub static WAZERO_API_VALUE_TYPE_F32: GoImport =
+    GoImport("github.com/tetratelabs/wazero/api", "ValueTypeF32");
+pub static WAZERO_API_VALUE_TYPE_F64: GoImport =
+    GoImport("github.com/tetratelabs/wazero/api", "ValueTypeF64");
 pub static WAZERO_API_ENCODE_U32: GoImport =
     GoImport("github.com/tetratelabs/wazero/api", "EncodeU32");
 pub static WAZERO_API_DECODE_U32: GoImport =
@@ -2,16 +2,20 @@
 
 mod comment;
 mod embed;
+mod generate;
 #[path = "./type.rs"]
 mod go_type;
 mod identifier;
 pub mod imports;
 mod operand;
+mod renames;
 mod result;
 
 pub use comment::*;
 pub use embed::*;
+pub use generate::*;
 pub use go_type::*;
 pub use identifier::*;
 pub use operand::*;
+pub use renames::*;
 pub use result::*;
@@ -15,6 +15,8 @@ pub enum Operand {
     SingleValue(String),
     /// A tuple of two values (for multi-value returns)
     MultiValue((String, String)),
+    /// The values of a WIT tuple, returned as multiple values
+    Tuple(Vec<String>),
 }
 
 impl Operand {
@@ -30,6 +32,7 @@ impl Operand {
             Operand::Literal(s) => s.clone(),
             Operand::SingleValue(s) => s.clone(),
             Operand::MultiValue((s1, _)) => s1.clone(),
+            Operand::Tuple(values) => values.first().cloned().unwrap_or_default(),
         }
     }
 }
@@ -46,6 +49,15 @@ impl FormatInto<Go> for &Operand {
                 tokens.space();
                 tokens.append(ItemStr::from(val2));
             }
+            Operand::Tuple(values) => {
+                for (i, val) in values.iter().enumerate() {
+                    if i > 0 {
+                        tokens.append(static_literal(","));
+                        tokens.space();
+                    }
+                    tokens.append(ItemStr::from(val));
+                }
+            }
         }
     }
 }
@@ -0,0 +1,332 @@
+use std::collections::{BTreeMap, BTreeSet};
+
+use wit_bindgen_core::wit_parser::{
+    Function, Resolve, TypeDefKind, TypeId, TypeOwner, World, WorldItem, WorldKey,
+};
+
+use crate::go::{GoIdentifier, Naming};
+
+/// The exported methods every generated instance has.
+pub const INSTANCE_METHODS: &[&str] = &["Close", "MemorySize", "MemoryStats", "Reset"];
+
+/// Go identifiers overriding the ones generated for WIT items.
+///
+/// Renames are keyed by the dotted WIT path of the item they name:
+/// - `func` for a function of the world, and `iface.func` for a function of an
+///   imported interface.
+/// - `record.field` for a field of a record of the world, and
+///   `iface.record.field` for a field of a record of an interface.
+#[derive(Debug, Clone, Default)]
+pub struct Renames(BTreeMap<String, String>);
+
+impl Renames {
+    /// Parses renames from a JSON object mapping WIT paths to Go identifiers,
+    /// e.g. `{"logger.debug": "Trace"}`.
+    pub fn parse(source: &str) -> Result<Self, String> {
+        let renames = serde_json::from_str::<BTreeMap<String, String>>(source)
+            .map_err(|err| err.to_string())?;
+        if let Some((path, name)) = renames.iter().find(|(_, name)| !is_identifier(name)) {
+            return Err(format!("`{path}`: invalid Go identifier: {name:?}"));
+        }
+        Ok(Self(renames))
+    }
+
+    /// Returns the Go identifier of a function, in the interface with the
+    /// given name if it isn't a function of the world, named with
+    /// `naming` unless it is renamed.
+    pub fn function(
+        &self,
+        naming: &Naming,
+        interface: Option<&str>,
+        func: &Function,
+    ) -> GoIdentifier {
+        let path = match interface {
+            Some(interface) => format!("{interface}.{}", func.name),
+            None => func.name.clone(),
+        };
+        match self.0.get(&path) {
+            Some(name) => GoIdentifier::public(name),
+            None => GoIdentifier::public(naming.name(&func.name)),
+        }
+    }
+
+    /// Returns the Go identifier of an exported function, which names its
+    /// method on the instance.
+    ///
+    /// Functions named after a method every instance has, e.g. `close`, are
+    /// prefixed with `Call`, e.g. `CallClose`, unless they are renamed.
+    pub fn export(&self, naming: &Naming, func: &Function) -> GoIdentifier {
+        let name = self.function(naming, None, func);
+        if self.0.contains_key(&func.name)
+            || !INSTANCE_METHODS.contains(&String::from(&name).as_str())
+        {
+            return name;
+        }
+        GoIdentifier::public(format!("call-{}", naming.name(&func.name)))
+    }
+
+    /// Returns the Go identifier of a field of the given record, named with
+    /// `naming` unless it is renamed.
+    pub fn field(
+        &self,
+        naming: &Naming,
+        resolve: &Resolve,
+        record: TypeId,
+        field: &str,
+    ) -> GoIdentifier {
+        match self
+            .0
+            .get(&format!("{}.{field}", type_path(resolve, record)))
+        {
+            Some(name) => GoIdentifier::public(name),
+            None => naming.field(field),
+        }
+    }
+
+    /// Checks that every renamed path names a function or record field of the
+    /// world, and that no two functions or fields end up with the same name.
+    pub fn check(&self, naming: &Naming, resolve: &Resolve, world: &World) -> Result<(), String> {
+        let mut known = BTreeSet::new();
+        let mut scopes = Vec::new();
+        let mut records = Vec::new();
+        for (key, item) in &world.imports {
+            match item {
+                WorldItem::Interface { id, .. } => {
+                    let interface = &resolve.interfaces[*id];
+                    let interface_name = match key {
+                        WorldKey::Name(name) => name,
+                        WorldKey::Interface(_) => {
+                            interface.name.as_ref().expect("interface missing name")
+                        }
+                    };
+                    let functions = interface
+                        .functions
+                        .values()
+                        .map(|func| {
+                            let path = format!("{interface_name}.{}", func.name);
+                            known.insert(path.clone());
+                            (
+                                path,
+                                self.function(naming, Some(interface_name.as_str()), func),
+                            )
+                        })
+                        .collect::<Vec<_>>();
+                    scopes.push(functions);
+                    records.extend(interface.types.values().copied());
+                }
+                WorldItem::Type(id) => records.push(*id),
+                WorldItem::Function(_) => {}
+            }
+        }
+        let functions = world
+            .imports
+            .values()
+            .filter_map(|item| match item {
+                WorldItem::Function(func) => {
+                    known.insert(func.name.clone());
+                    Some((func.name.clone(), self.function(naming, None, func)))
+                }
+                _ => None,
+            })
+            .collect::<Vec<_>>();
+        scopes.push(functions);
+        let mut functions = Vec::new();
+        for item in world.exports.values() {
+            let WorldItem::Function(func) = item else {
+                continue;
+            };
+            known.insert(func.name.clone());
+            let name = self.export(naming, func);
+            if INSTANCE_METHODS.contains(&String::from(&name).as_str()) {
+                return Err(format!(
+                    "`{}` is named {}, which is a method of every instance",
+                    func.name,
+                    String::from(name)
+                ));
+            }
+            functions.push((func.name.clone(), name));
+        }
+        scopes.push(functions);
+        for id in records {
+            let TypeDefKind::Record(record) = &resolve.types[id].kind else {
+                continue;
+            };
+            let path = type_path(resolve, id);
+            let fields = record
+                .fields
+                .iter()
+                .map(|field| {
+                    let field_path = format!("{path}.{}", field.name);
+                    known.insert(field_path.clone());
+                    (field_path, self.field(naming, resolve, id, &field.name))
+                })
+                .collect::<Vec<_>>();
+            scopes.push(fields);
+        }
+
+        if let Some(path) = self.0.keys().find(|path| !known.contains(*path)) {
+            return Err(format!(
+                "`{path}`: no function or record field with this path"
+            ));
+        }
+        for scope in scopes {
+            let mut names = BTreeMap::new();
+            for (path, name) in scope {
+                let name = String::from(name);
+                if let Some(other) = names.insert(name.clone(), path.clone()) {
+                    return Err(format!("`{other}` and `{path}` are both named {name}"));
+                }
+            }
+        }
+        Ok(())
+    }
+}
+
+/// Returns the dotted WIT path of a named type, prefixed with the name of its
+/// interface if it has one.
+fn type_path(resolve: &Resolve, id: TypeId) -> String {
+    let typ = &resolve.types[id];
+    let name = typ.name.as_deref().unwrap_or_default();
+    match typ.owner {
+        TypeOwner::Interface(interface) => match &resolve.interfaces[interface].name {
+            Some(interface) => format!("{interface}.{name}"),
+            None => name.to_string(),
+        },
+        _ => name.to_string(),
+    }
+}
+
+/// Returns whether the given name is a Go identifier gravity can emit as is.
+///
+/// Underscores are not allowed, as they separate words in WIT names.
+fn is_identifier(name: &str) -> bool {
+    let mut chars = name.chars();
+    chars.next().is_some_and(|c| c.is_ascii_alphabetic())
+        && chars.all(|c| c.is_ascii_alphanumeric())
+}
+
+#[cfg(test)]
+mod tests {
+    use wit_bindgen_core::wit_parser::{Resolve, SizeAlign};
+
+    use crate::{
+        codegen::Bindings,
+        go::{Naming, Renames},
+    };
+
+    const WIT: &str = r#"
+        package arcjet:renames;
+
+        interface logger {
+          record entry {
+            msg: string,
+            level: u32,
+          }
+
+          debug: func(entry: entry);
+          info: func(msg: string);
+        }
+
+        world renames {
+          import logger;
+
+          export run: func(name: string) -> string;
+        }
+    "#;
+
+    #[test]
+    fn test_parse() {
+        let renames = Renames::parse(r#" { "a.b": "First", "c" : "Second" } "#).unwrap();
+        assert_eq!(renames.0.len(), 2);
+        assert_eq!(renames.0["a.b"], "First");
+        assert!(Renames::parse("{}").unwrap().0.is_empty());
+
+        assert!(Renames::parse(r#"{"a": "not-go"}"#).is_err());
+        assert!(Renames::parse(r#"{"a": "A""#).is_err());
+        assert!(Renames::parse(r#"["a"]"#).is_err());
+    }
+
+    #[test]
+    fn test_check() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str("renames.wit", WIT)
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "renames")
+            .expect("failed to find world");
+        let check = |source: &str| {
+            Renames::parse(source)
+                .unwrap()
+                .check(&Naming::default(), &resolve, world)
+        };
+
+        assert!(
+            check(r#"{"logger.debug": "Trace", "logger.entry.msg": "Message", "run": "Start"}"#)
+                .is_ok()
+        );
+
+        // Paths must name a function or a field
+        let err = check(r#"{"logger.warn": "Warn"}"#).unwrap_err();
+        assert!(err.contains("`logger.warn`"), "{err}");
+        assert!(check(r#"{"logger.entry": "Record"}"#).is_err());
+
+        // Renames must not collide with each other, or generated names
+        let err = check(r#"{"logger.debug": "Info"}"#).unwrap_err();
+        assert!(err.contains("`logger.debug`"), "{err}");
+        assert!(err.contains("`logger.info`"), "{err}");
+        assert!(check(r#"{"logger.entry.msg": "Level"}"#).is_err());
+
+        // Exports can't be renamed after the methods of the instance
+        let err = check(r#"{"run": "Close"}"#).unwrap_err();
+        assert!(err.contains("`run`"), "{err}");
+    }
+
+    #[test]
+    fn test_generate() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str("renames.wit", WIT)
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "renames")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+        let mut bindings = Bindings::new(&resolve, world, &sizes);
+        bindings.set_renames(
+            Renames::parse(
+                r#"{
+                  "logger.debug": "Trace",
+                  "logger.entry.msg": "Message",
+                  "run": "Start"
+                }"#,
+            )
+            .unwrap(),
+        );
+        bindings.generate();
+
+        let generated = bindings.out.to_string().unwrap();
+        println!("Generated: {}", generated);
+
+        // Functions are renamed in Go, but keep their WIT names in the Wasm
+        assert!(generated.contains("Trace(\n"));
+        assert!(generated.contains("Info(\n"));
+        assert!(generated.contains("recoverHostPanic(logger, \"Trace\")"));
+        assert!(generated.contains("logger.Trace(ctx, "));
+        assert!(generated.contains("Export(\"debug\")"));
+        assert!(generated.contains("func (i *RenamesInstance) Start("));
+        assert!(generated.contains("func StartArgSize("));
+        assert!(generated.contains("ExportedFunction(\"run\")"));
+
+        // Renamed fields are used in the struct and when lifting it
+        assert!(generated.contains("Message string"));
+        assert!(generated.contains("Level uint32"));
+        assert!(generated.contains("Message: "));
+        assert!(!generated.contains("Msg"));
+    }
+}
@@ -39,7 +39,11 @@ impl FormatInto<Go> for GoResult {
 impl FormatInto<Go> for &GoResult {
     fn format_into(self, tokens: &mut Tokens<Go>) {
         match &self {
-            GoResult::Anon(typ @ GoType::ValueOrError(_) | typ @ GoType::ValueOrOk(_)) => {
+            GoResult::Anon(
+                typ @ GoType::ValueOrError(_)
+                | typ @ GoType::ValueOrOk(_)
+                | typ @ GoType::MultiReturn(_),
+            ) => {
                 // Be cautious here as there are `(` and `)` surrounding the type
                 tokens.append(quote!(($typ)))
             }
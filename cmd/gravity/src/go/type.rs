@@ -36,22 +36,72 @@ pub enum GoType {
     Error,
     /// Interface type (for variants/discriminated unions)
     Interface,
-    // Pointer to another type
-    // Pointer(Box<GoType>),
+    /// Pointer to another type, e.g. `*uint32` for `option<u32>` with
+    /// `OptionStyle::Pointer`
+    Pointer(Box<GoType>),
     /// Result type with Ok value
     ValueOrOk(Box<GoType>),
     /// Result type with Error value
     ValueOrError(Box<GoType>),
     /// Slice/array of another type
     Slice(Box<GoType>),
+    /// Fixed-length array of another type, e.g. `[16]uint8` for a `list<u8>`
+    /// field with a `@go-array:16` directive
+    Array(usize, Box<GoType>),
     /// Multi-return type (for functions returning arbitrary multiple values)
-    // MultiReturn(Vec<GoType>),
+    MultiReturn(Vec<GoType>),
+    /// Anonymous struct with a field per element, e.g.
+    /// `struct{ F0 uint32; F1 string }`, for tuples which can't be returned as
+    /// multiple values, such as list elements
+    Tuple(Vec<GoType>),
     /// User-defined type (records, enums, type aliases)
     UserDefined(String),
+    /// Defined type over a primitive type (e.g. `type UserId uint64` for
+    /// `type user-id = u64`)
+    Defined(String, Box<GoType>),
     /// Represents no value/void
     Nothing,
 }
 
+/// How `option<T>` is represented in Go.
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub enum OptionStyle {
+    /// Options are returned as `T, bool`, where `false` is `None`.
+    #[default]
+    Pair,
+    /// Options are pointers, where `nil` is `None`, e.g. `*uint32`. Unlike
+    /// pairs, pointers can also be record fields and list elements.
+    Pointer,
+}
+
+impl OptionStyle {
+    /// Returns the given resolved type, with its options in this style.
+    pub fn go_type(self, typ: GoType) -> GoType {
+        match (self, typ) {
+            (OptionStyle::Pair, typ) => typ,
+            (OptionStyle::Pointer, GoType::ValueOrOk(inner)) => {
+                GoType::Pointer(Box::new(self.go_type(*inner)))
+            }
+            (OptionStyle::Pointer, GoType::ValueOrError(inner)) => {
+                GoType::ValueOrError(Box::new(self.go_type(*inner)))
+            }
+            (OptionStyle::Pointer, GoType::Slice(inner)) => {
+                GoType::Slice(Box::new(self.go_type(*inner)))
+            }
+            (OptionStyle::Pointer, GoType::Pointer(inner)) => {
+                GoType::Pointer(Box::new(self.go_type(*inner)))
+            }
+            (OptionStyle::Pointer, GoType::MultiReturn(typs)) => {
+                GoType::MultiReturn(typs.into_iter().map(|typ| self.go_type(typ)).collect())
+            }
+            (OptionStyle::Pointer, GoType::Tuple(typs)) => {
+                GoType::Tuple(typs.into_iter().map(|typ| self.go_type(typ)).collect())
+            }
+            (OptionStyle::Pointer, typ) => typ,
+        }
+    }
+}
+
 impl GoType {
     /// Returns true if this type needs post-return cleanup (cabi_post_* function)
     ///
@@ -88,7 +138,7 @@ impl GoType {
             | GoType::Float64 => false,
 
             // String and slices allocate memory and need cleanup
-            GoType::String | GoType::Slice(_) => true,
+            GoType::String | GoType::Slice(_) | GoType::Array(..) => true,
 
             // Complex types need cleanup if their inner types do
             GoType::ValueOrOk(inner) => inner.needs_cleanup(),
@@ -115,13 +165,37 @@ impl GoType {
             // 3. Using a different representation that carries this information
             GoType::UserDefined(_) => true,
 
+            // Defined types need cleanup exactly when their underlying type does
+            GoType::Defined(_, underlying) => underlying.needs_cleanup(),
+
             // Error is actually Result<None, String> - strings need cleanup!
             GoType::Error => true,
 
+            // Multiple values need cleanup if any of them do
+            GoType::MultiReturn(typs) | GoType::Tuple(typs) => {
+                typs.iter().any(GoType::needs_cleanup)
+            }
+
             // Nothing represents no value, so no cleanup needed
             GoType::Nothing => false,
-            // TODO - figure out if a pointer needs cleanup, once implemented.
-            // GoType::Pointer(_) => todo!()
+
+            // Pointers need cleanup if the value they point to does
+            GoType::Pointer(inner) => inner.needs_cleanup(),
+        }
+    }
+
+    /// Returns the Go parameters a function parameter of this type is passed
+    /// as. Options in the pair style are passed as their value, and results as
+    /// their value and an error named after the parameter, like they're
+    /// returned.
+    pub fn params(self, name: &str) -> Vec<(GoIdentifier, GoType)> {
+        match self {
+            GoType::ValueOrOk(typ) => vec![(GoIdentifier::local(name), *typ)],
+            GoType::ValueOrError(typ) => vec![
+                (GoIdentifier::local(name), *typ),
+                (GoIdentifier::local(format!("{name}-err")), GoType::Error),
+            ],
+            typ => vec![(GoIdentifier::local(name), typ)],
         }
     }
 }
@@ -159,14 +233,32 @@ impl FormatInto<Go> for &GoType {
                 tokens.append(static_literal("[]"));
                 typ.as_ref().format_into(tokens);
             }
-            // GoType::MultiReturn(typs) => {
-            //     tokens.append(quote!($(for typ in typs join (, ) => $typ)))
-            // }
-            // GoType::Pointer(typ) => {
-            //     tokens.append(static_literal("*"));
-            //     typ.as_ref().format_into(tokens);
-            // }
-            GoType::UserDefined(name) => {
+            GoType::Array(len, typ) => {
+                tokens.append(format!("[{len}]"));
+                typ.as_ref().format_into(tokens);
+            }
+            GoType::MultiReturn(typs) => {
+                tokens.append(quote!($(for typ in typs join (, ) => $typ)))
+            }
+            GoType::Tuple(typs) => {
+                tokens.append(static_literal("struct{"));
+                for (i, typ) in typs.iter().enumerate() {
+                    if i > 0 {
+                        tokens.append(static_literal(";"));
+                    }
+                    tokens.space();
+                    tokens.append(format!("F{i}"));
+                    tokens.space();
+                    typ.format_into(tokens);
+                }
+                tokens.space();
+                tokens.append(static_literal("}"));
+            }
+            GoType::Pointer(typ) => {
+                tokens.append(static_literal("*"));
+                typ.as_ref().format_into(tokens);
+            }
+            GoType::UserDefined(name) | GoType::Defined(name, _) => {
                 let id = GoIdentifier::public(name);
                 id.format_into(tokens)
             }
@@ -185,7 +277,7 @@ impl FormatInto<Go> for GoType {
 mod tests {
     use genco::{prelude::*, tokens::Tokens};
 
-    use crate::go::GoType;
+    use crate::go::{GoType, OptionStyle};
 
     #[test]
     fn test_basic_types() {
@@ -227,6 +319,57 @@ mod tests {
         assert_eq!(tokens.to_string().unwrap(), "uint32, bool");
     }
 
+    #[test]
+    fn test_pointer() {
+        let typ = GoType::Pointer(Box::new(GoType::Uint32));
+        let mut tokens = Tokens::<Go>::new();
+        (&typ).format_into(&mut tokens);
+        assert_eq!(tokens.to_string().unwrap(), "*uint32");
+    }
+
+    #[test]
+    fn test_array() {
+        let typ = GoType::Array(16, Box::new(GoType::Uint8));
+        let mut tokens = Tokens::<Go>::new();
+        (&typ).format_into(&mut tokens);
+        assert_eq!(tokens.to_string().unwrap(), "[16]uint8");
+    }
+
+    #[test]
+    fn test_option_style() {
+        let typ = GoType::Slice(Box::new(GoType::ValueOrOk(Box::new(GoType::String))));
+        assert_eq!(OptionStyle::Pair.go_type(typ.clone()), typ);
+        assert_eq!(
+            OptionStyle::Pointer.go_type(typ),
+            GoType::Slice(Box::new(GoType::Pointer(Box::new(GoType::String))))
+        );
+    }
+
+    #[test]
+    fn test_multi_return() {
+        let typ = GoType::MultiReturn(vec![GoType::Uint32, GoType::String]);
+        let mut tokens = Tokens::<Go>::new();
+        (&typ).format_into(&mut tokens);
+        assert_eq!(tokens.to_string().unwrap(), "uint32, string");
+        assert!(typ.needs_cleanup());
+    }
+
+    #[test]
+    fn test_tuple() {
+        let typ = GoType::Slice(Box::new(GoType::Tuple(vec![
+            GoType::Uint32,
+            GoType::String,
+        ])));
+        let mut tokens = Tokens::<Go>::new();
+        (&typ).format_into(&mut tokens);
+        assert_eq!(
+            tokens.to_string().unwrap(),
+            "[]struct{ F0 uint32; F1 string }"
+        );
+        assert!(GoType::Tuple(vec![GoType::String]).needs_cleanup());
+        assert!(!GoType::Tuple(vec![GoType::Uint32]).needs_cleanup());
+    }
+
     #[test]
     fn test_value_or_error() {
         let typ = GoType::ValueOrError(Box::new(GoType::String));
@@ -235,6 +378,39 @@ mod tests {
         assert_eq!(tokens.to_string().unwrap(), "string, error");
     }
 
+    #[test]
+    fn test_params() {
+        let params = |typ: GoType, name: &str| {
+            typ.params(name)
+                .into_iter()
+                .map(|(name, typ)| (String::from(&name), typ))
+                .collect::<Vec<_>>()
+        };
+        assert_eq!(
+            params(GoType::ValueOrError(Box::new(GoType::Uint32)), "outcome"),
+            [
+                ("outcome".to_string(), GoType::Uint32),
+                ("outcomeErr".to_string(), GoType::Error),
+            ]
+        );
+        assert_eq!(
+            params(GoType::ValueOrOk(Box::new(GoType::String)), "name"),
+            [("name".to_string(), GoType::String)]
+        );
+    }
+
+    #[test]
+    fn test_defined() {
+        let typ = GoType::Defined("user-id".into(), Box::new(GoType::Uint64));
+        let mut tokens = Tokens::<Go>::new();
+        (&typ).format_into(&mut tokens);
+        assert_eq!(tokens.to_string().unwrap(), "UserId");
+        assert!(!typ.needs_cleanup());
+
+        let typ = GoType::Defined("name".into(), Box::new(GoType::String));
+        assert!(typ.needs_cleanup());
+    }
+
     #[test]
     fn test_slice() {
         let typ = GoType::Slice(Box::new(GoType::Int32));
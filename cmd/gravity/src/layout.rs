@@ -0,0 +1,241 @@
+use std::fmt::Write;
+
+use wit_bindgen_core::{
+    abi::{AbiVariant, WasmType},
+    wit_parser::{Function, Resolve, SizeAlign, Type, TypeDefKind, TypeOwner, World, WorldItem},
+};
+
+/// Describes the computed ABI layout of a world as JSON.
+///
+/// This includes the size, alignment and field offsets of every named type,
+/// and the flattened core Wasm signature of every function imported or
+/// exported by the world. Sizes and offsets are given for wasm32.
+pub fn layout_json(resolve: &Resolve, world: &World, sizes: &SizeAlign) -> String {
+    let mut out = String::from("{\n  \"types\": [");
+    let mut first = true;
+    for (id, def) in resolve.types.iter() {
+        let Some(name) = &def.name else {
+            continue;
+        };
+        // Resources and handles don't have a layout of their own.
+        if matches!(
+            def.kind,
+            TypeDefKind::Resource | TypeDefKind::Handle(_) | TypeDefKind::Unknown
+        ) {
+            continue;
+        }
+        let typ = Type::Id(id);
+        out.push_str(if first { "\n" } else { ",\n" });
+        first = false;
+        write!(
+            out,
+            "    {{\"name\": {}, \"owner\": {}, \"size\": {}, \"align\": {}",
+            quoted(name),
+            owner(resolve, def.owner),
+            sizes.size(&typ).size_wasm32(),
+            sizes.align(&typ).align_wasm32(),
+        )
+        .unwrap();
+        if let TypeDefKind::Record(record) = &def.kind {
+            let offsets = sizes.field_offsets(record.fields.iter().map(|field| &field.ty));
+            let fields = record
+                .fields
+                .iter()
+                .zip(offsets)
+                .map(|(field, (offset, _))| {
+                    format!(
+                        "{{\"name\": {}, \"offset\": {}}}",
+                        quoted(&field.name),
+                        offset.size_wasm32()
+                    )
+                })
+                .collect::<Vec<_>>();
+            write!(out, ", \"fields\": [{}]", fields.join(", ")).unwrap();
+        }
+        out.push('}');
+    }
+    out.push_str(if first { "],\n" } else { "\n  ],\n" });
+
+    out.push_str("  \"functions\": [");
+    let mut first = true;
+    let items = world
+        .imports
+        .values()
+        .map(|item| (AbiVariant::GuestImport, item))
+        .chain(
+            world
+                .exports
+                .values()
+                .map(|item| (AbiVariant::GuestExport, item)),
+        );
+    for (variant, item) in items {
+        let (interface, functions): (Option<&str>, Vec<&Function>) = match item {
+            WorldItem::Function(func) => (None, vec![func]),
+            WorldItem::Interface { id, .. } => {
+                let interface = &resolve.interfaces[*id];
+                (
+                    interface.name.as_deref(),
+                    interface.functions.values().collect(),
+                )
+            }
+            WorldItem::Type(_) => continue,
+        };
+        for func in functions {
+            let sig = resolve.wasm_signature(variant, func);
+            out.push_str(if first { "\n" } else { ",\n" });
+            first = false;
+            write!(
+                out,
+                "    {{\"name\": {}, \"interface\": {}, \"direction\": {}, \"params\": [{}], \"results\": [{}], \"indirect_params\": {}, \"retptr\": {}}}",
+                quoted(&func.name),
+                interface.map(quoted).unwrap_or_else(|| "null".to_string()),
+                quoted(match variant {
+                    AbiVariant::GuestImport => "import",
+                    _ => "export",
+                }),
+                wasm_types(&sig.params),
+                wasm_types(&sig.results),
+                sig.indirect_params,
+                sig.retptr,
+            )
+            .unwrap();
+        }
+    }
+    out.push_str(if first { "]\n}" } else { "\n  ]\n}" });
+    out
+}
+
+/// The name of the world or interface that owns a type, as JSON.
+fn owner(resolve: &Resolve, owner: TypeOwner) -> String {
+    match owner {
+        TypeOwner::World(id) => quoted(&resolve.worlds[id].name),
+        TypeOwner::Interface(id) => resolve.interfaces[id]
+            .name
+            .as_deref()
+            .map(quoted)
+            .unwrap_or_else(|| "null".to_string()),
+        TypeOwner::None => "null".to_string(),
+    }
+}
+
+fn wasm_types(types: &[WasmType]) -> String {
+    types
+        .iter()
+        .map(|typ| {
+            quoted(match typ {
+                WasmType::I32 => "i32",
+                WasmType::I64 => "i64",
+                WasmType::F32 => "f32",
+                WasmType::F64 => "f64",
+                WasmType::Pointer => "pointer",
+                WasmType::PointerOrI64 => "pointer-or-i64",
+                WasmType::Length => "length",
+            })
+        })
+        .collect::<Vec<_>>()
+        .join(", ")
+}
+
+/// Quotes a string as JSON.
+fn quoted(s: &str) -> String {
+    let mut out = String::with_capacity(s.len() + 2);
+    out.push('"');
+    for c in s.chars() {
+        match c {
+            '"' => out.push_str("\\\""),
+            '\\' => out.push_str("\\\\"),
+            c if c.is_control() => write!(out, "\\u{:04x}", c as u32).unwrap(),
+            c => out.push(c),
+        }
+    }
+    out.push('"');
+    out
+}
+
+#[cfg(test)]
+mod tests {
+    use wit_bindgen_core::wit_parser::{Resolve, SizeAlign};
+
+    use super::layout_json;
+
+    #[test]
+    fn test_layout_record_field_offsets() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "layout.wit",
+                r#"
+                package arcjet:layout;
+
+                world layout {
+                  record entry {
+                    flag: bool,
+                    id: u64,
+                    name: string,
+                  }
+
+                  export get: func(id: u64) -> entry;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "layout")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+
+        let json = layout_json(&resolve, world, &sizes);
+
+        assert!(json.contains(
+            r#"{"name": "entry", "owner": "layout", "size": 24, "align": 8, "fields": [{"name": "flag", "offset": 0}, {"name": "id", "offset": 8}, {"name": "name", "offset": 16}]}"#
+        ));
+        assert!(json.contains(
+            r#"{"name": "get", "interface": null, "direction": "export", "params": ["i64"], "results": ["pointer"], "indirect_params": false, "retptr": true}"#
+        ));
+    }
+
+    #[test]
+    fn test_layout_16_bit_alignment() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "packing.wit",
+                r#"
+                package arcjet:packing;
+
+                world packing {
+                  record mixed {
+                    small: u8,
+                    medium: u16,
+                    large: u32,
+                  }
+
+                  export mix: func() -> mixed;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "packing")
+            .expect("failed to find world");
+        let mut sizes = SizeAlign::default();
+        sizes.fill(&resolve);
+
+        let json = layout_json(&resolve, world, &sizes);
+
+        // `u16` fields are aligned to 2 bytes, after the `u8` and before the `u32`
+        assert!(json.contains(
+            r#"{"name": "mixed", "owner": "packing", "size": 8, "align": 4, "fields": [{"name": "small", "offset": 0}, {"name": "medium", "offset": 2}, {"name": "large", "offset": 4}]}"#
+        ));
+    }
+
+    #[test]
+    fn test_quoted() {
+        assert_eq!(super::quoted("a\"b\\c\n"), r#""a\"b\\c\u000a""#);
+    }
+}
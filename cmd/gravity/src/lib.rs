@@ -1,10 +1,13 @@
 pub mod codegen;
 pub mod go;
+pub mod layout;
+pub mod manifest;
+pub mod strict;
 
-use crate::go::GoType;
+use crate::go::{GoType, Naming};
 use wit_bindgen_core::{
     abi::WasmType,
-    wit_parser::{Resolve, Result_, Type, TypeDef, TypeDefKind},
+    wit_parser::{Field, Function, Resolve, Result_, Type, TypeDef, TypeDefKind},
 };
 
 // Temporary re-export while we migrate.
@@ -23,6 +26,24 @@ pub fn resolve_wasm_type(typ: &WasmType) -> GoType {
     }
 }
 
+/// Resolves a Wasm type to the Go type used for it in a wazero host function
+/// signature.
+///
+/// wazero derives the Wasm signature of a host function from its Go parameter
+/// and result types, so these must match the core Wasm types exactly.
+pub fn resolve_host_wasm_type(typ: &WasmType) -> GoType {
+    match typ {
+        WasmType::I32 => GoType::Uint32,
+        WasmType::I64 => GoType::Uint64,
+        WasmType::F32 => GoType::Float32,
+        WasmType::F64 => GoType::Float64,
+        // TODO(#58): Support additional ArchitectureSize
+        WasmType::Pointer => GoType::Uint32,
+        WasmType::PointerOrI64 => GoType::Uint64,
+        WasmType::Length => GoType::Uint32,
+    }
+}
+
 /// Resolves a WIT type to a Go type.
 ///
 /// # Panics
@@ -33,6 +54,23 @@ pub fn resolve_wasm_type(typ: &WasmType) -> GoType {
 /// - The type is still unimplemented.
 /// - The type does not have a name when it is expected to have one (enums, records, type aliases).
 pub fn resolve_type(typ: &Type, resolve: &Resolve) -> GoType {
+    resolve_type_with(typ, resolve, false, &Naming::default())
+}
+
+/// Resolves a WIT type to a Go type like `resolve_type`, except that named
+/// lists, e.g. `type rows = list<row>`, are defined types such as `Rows` when
+/// `named_lists` is set, rather than inlined as `[]Row`, and the names of
+/// types follow `naming`, e.g. `UserID` with initialisms.
+///
+/// # Panics
+///
+/// This function panics in the same cases as `resolve_type`.
+pub fn resolve_type_with(
+    typ: &Type,
+    resolve: &Resolve,
+    named_lists: bool,
+    naming: &Naming,
+) -> GoType {
     match typ {
         // Basic types.
         Type::Bool => GoType::Bool,
@@ -60,28 +98,50 @@ pub fn resolve_type(typ: &Type, resolve: &Resolve) -> GoType {
                 .get(*id)
                 .expect("failed to find type definition");
             match kind {
-                TypeDefKind::Record(_) => {
-                    GoType::UserDefined(name.clone().expect("expected record to have a name"))
-                }
+                TypeDefKind::Record(_) => GoType::UserDefined(
+                    naming.name(name.as_ref().expect("expected record to have a name")),
+                ),
                 TypeDefKind::Resource => todo!("TODO(#5): implement resources"),
                 TypeDefKind::Handle(_) => todo!("TODO(#5): implement resources"),
-                TypeDefKind::Flags(_) => todo!("TODO(#4): implement flag conversion"),
-                TypeDefKind::Tuple(_) => todo!("TODO(#4): implement tuple conversion"),
+                TypeDefKind::Flags(_) => GoType::UserDefined(
+                    naming.name(name.as_ref().expect("expected flags to have a name")),
+                ),
+                // Tuples are returned as multiple values from functions, and
+                // are structs in lists.
+                TypeDefKind::Tuple(tuple) => GoType::MultiReturn(
+                    tuple
+                        .types
+                        .iter()
+                        .map(|typ| resolve_type_with(typ, resolve, named_lists, naming))
+                        .collect(),
+                ),
                 // Variants are handled as an empty interfaces in type signatures; however, that
                 // means they require runtime type reflection
                 TypeDefKind::Variant(_) => GoType::Interface,
-                TypeDefKind::Enum(_) => {
-                    GoType::UserDefined(name.clone().expect("expected enum to have a name"))
-                }
+                TypeDefKind::Enum(_) => GoType::UserDefined(
+                    naming.name(name.as_ref().expect("expected enum to have a name")),
+                ),
+                // Tuples in options are structs as well, so `Some` is one value.
                 TypeDefKind::Option(value) => {
-                    GoType::ValueOrOk(Box::new(resolve_type(value, resolve)))
+                    GoType::ValueOrOk(Box::new(
+                        match resolve_type_with(value, resolve, named_lists, naming) {
+                            GoType::MultiReturn(typs) => GoType::Tuple(typs),
+                            typ => typ,
+                        },
+                    ))
                 }
 
-                // Various results, including specialised ones.
+                // Various results, including specialised ones. Tuples are
+                // structs in results too, returned along with the error.
                 TypeDefKind::Result(Result_ {
                     ok: Some(ok),
                     err: Some(Type::String),
-                }) => GoType::ValueOrError(Box::new(resolve_type(ok, resolve))),
+                }) => GoType::ValueOrError(Box::new(
+                    match resolve_type_with(ok, resolve, named_lists, naming) {
+                        GoType::MultiReturn(typs) => GoType::Tuple(typs),
+                        typ => typ,
+                    },
+                )),
                 TypeDefKind::Result(Result_ {
                     ok: Some(_),
                     err: Some(_),
@@ -91,7 +151,7 @@ pub fn resolve_type(typ: &Type, resolve: &Resolve) -> GoType {
                 TypeDefKind::Result(Result_ {
                     ok: Some(ok),
                     err: None,
-                }) => resolve_type(ok, resolve),
+                }) => resolve_type_with(ok, resolve, named_lists, naming),
                 TypeDefKind::Result(Result_ {
                     ok: None,
                     err: Some(Type::String),
@@ -105,11 +165,41 @@ pub fn resolve_type(typ: &Type, resolve: &Resolve) -> GoType {
                     err: None,
                 }) => GoType::Nothing,
 
-                TypeDefKind::List(inner) => GoType::Slice(Box::new(resolve_type(inner, resolve))),
+                // Tuples in lists are structs, since they can't be multiple values there.
+                TypeDefKind::List(inner) => {
+                    let slice = GoType::Slice(Box::new(
+                        match resolve_type_with(inner, resolve, named_lists, naming) {
+                            GoType::MultiReturn(typs) => GoType::Tuple(typs),
+                            typ => typ,
+                        },
+                    ));
+                    // Named lists are defined types, if asked for.
+                    match name {
+                        Some(name) if named_lists => {
+                            GoType::Defined(naming.name(name), Box::new(slice))
+                        }
+                        _ => slice,
+                    }
+                }
                 TypeDefKind::Future(_) => todo!("TODO(#4): implement future conversion"),
                 TypeDefKind::Stream(_) => todo!("TODO(#4): implement stream conversion"),
-                TypeDefKind::Type(_) => {
-                    GoType::UserDefined(name.clone().expect("expected type alias to have a name"))
+                TypeDefKind::Type(inner) => {
+                    let name =
+                        naming.name(name.as_ref().expect("expected type alias to have a name"));
+                    match inner {
+                        // References to other types keep their own name, but
+                        // stay defined types when they refer to one.
+                        Type::Id(_) => {
+                            match resolve_type_with(inner, resolve, named_lists, naming) {
+                                GoType::Defined(_, underlying) => GoType::Defined(name, underlying),
+                                _ => GoType::UserDefined(name),
+                            }
+                        }
+                        _ => GoType::Defined(
+                            name,
+                            Box::new(resolve_type_with(inner, resolve, named_lists, naming)),
+                        ),
+                    }
                 }
                 TypeDefKind::FixedSizeList(_, _) => {
                     todo!("TODO(#4): implement fixed size list conversion")
@@ -119,3 +209,61 @@ pub fn resolve_type(typ: &Type, resolve: &Resolve) -> GoType {
         }
     }
 }
+
+/// Returns the length of the Go array a `list<u8>` record field is lifted
+/// into instead of a slice, set with a `@go-array:N` line in its docs, e.g.
+/// `/// @go-array:16` for a UUID.
+///
+/// # Panics
+///
+/// This function panics if the length isn't a number, or the field isn't a
+/// `list<u8>`.
+pub fn field_array_len(field: &Field, resolve: &Resolve) -> Option<usize> {
+    let docs = field.docs.contents.as_deref()?;
+    let len = docs
+        .lines()
+        .find_map(|line| line.trim().strip_prefix("@go-array:"))?;
+    let len = len
+        .trim()
+        .parse()
+        .unwrap_or_else(|_| panic!("invalid `@go-array` length of field {}: {len}", field.name));
+    if resolve_type(&field.ty, resolve) != GoType::Slice(Box::new(GoType::Uint8)) {
+        panic!(
+            "`@go-array` only applies to `list<u8>` fields, not to field {}",
+            field.name
+        );
+    }
+    Some(len)
+}
+
+/// Returns the names of the `string` parameters of a function which must not
+/// be empty, set with a `@go-validate:nonempty` line in its docs naming the
+/// parameter, e.g. `/// @go-validate:nonempty name`.
+///
+/// # Panics
+///
+/// This function panics if the parameter doesn't exist, or isn't a `string`.
+pub fn nonempty_params<'a>(func: &'a Function, resolve: &Resolve) -> Vec<&'a str> {
+    let Some(docs) = func.docs.contents.as_deref() else {
+        return Vec::new();
+    };
+    docs.lines()
+        .filter_map(|line| line.trim().strip_prefix("@go-validate:nonempty"))
+        .map(|name| {
+            let name = name.trim();
+            let Some((name, typ)) = func.params.iter().find(|(param, _)| param == name) else {
+                panic!(
+                    "`@go-validate:nonempty` names no parameter of function {}: {name}",
+                    func.name
+                );
+            };
+            if resolve_type(typ, resolve) != GoType::String {
+                panic!(
+                    "`@go-validate:nonempty` only applies to `string` parameters, not to parameter {name} of function {}",
+                    func.name
+                );
+            }
+            name.as_str()
+        })
+        .collect()
+}
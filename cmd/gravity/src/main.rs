@@ -1,10 +1,21 @@
-use std::{fs, path::Path, process::ExitCode};
+use std::{
+    fs,
+    io::{self, Read},
+    path::Path,
+    process::ExitCode,
+};
 
-use clap::{Arg, ArgAction, Command};
+use clap::{Arg, ArgAction, ArgMatches, Command};
 use genco::lang::{Go, go};
-use wit_bindgen_core::wit_parser::SizeAlign;
+use wit_bindgen_core::wit_parser::{Resolve, SizeAlign};
 
-use arcjet_gravity::codegen::{Bindings, WasmData};
+use arcjet_gravity::{
+    codegen::{Bindings, WasmData, compress_wasm},
+    go::{DEFAULT_INITIALISMS, FieldCase, Naming, OptionStyle, Renames, generate_directive},
+    layout::layout_json,
+    manifest,
+    strict::unsupported_features,
+};
 
 // `wit_component::decode` uses `root` as an arbitrary name for the primary
 // world name, see
@@ -14,47 +25,250 @@ pub const PRIMARY_WORLD_NAME: &str = "root";
 
 fn main() -> Result<ExitCode, ()> {
     let cmd = Command::new("gravity")
-        .arg(
-            Arg::new("world")
-                .short('w')
-                .long("world")
-                .help("generate host bindings for the specified world")
-                .default_value(PRIMARY_WORLD_NAME),
+        .args_conflicts_with_subcommands(true)
+        .subcommand_negates_reqs(true)
+        .subcommand(
+            Command::new("layout")
+                .about("print the computed ABI layout of a world as JSON")
+                .arg(
+                    Arg::new("wit-file")
+                        .long("wit-file")
+                        .help("the WIT file or directory to process, or `-` to read WIT from stdin")
+                        .required(true),
+                )
+                .arg(
+                    Arg::new("world")
+                        .short('w')
+                        .long("world")
+                        .help("print the layout of the specified world")
+                        .required(true),
+                ),
         )
-        .arg(
-            Arg::new("inline-wasm")
-                .long("inline-wasm")
-                .help("include the WebAssembly file as hex bytes in the output code")
-                .action(ArgAction::SetTrue),
+        .subcommand(
+            Command::new("verify")
+                .about("check that the files generated with the given arguments are up to date, printing a diff of the first one which isn't")
+                .args(generate_args()),
         )
-        .arg(
-            Arg::new("file")
-                .help("the WebAssembly file to process")
-                .required(true),
-        )
-        .arg(
-            Arg::new("output")
-                .help("the file path where output generated code should be output")
-                .short('o')
-                .long("output"),
-        );
+        .args(generate_args());
 
     let matches = cmd.get_matches();
+    Ok(match matches.subcommand() {
+        Some(("layout", matches)) => layout(matches),
+        Some(("verify", matches)) => run(matches, true),
+        _ => run(&matches, false),
+    })
+}
+
+/// Returns the arguments of generating bindings, shared by `verify`.
+fn generate_args() -> Vec<Arg> {
+    vec![
+        Arg::new("world")
+            .short('w')
+            .long("world")
+            .help("generate host bindings for the specified world")
+            .default_value(PRIMARY_WORLD_NAME),
+        Arg::new("inline-wasm")
+            .long("inline-wasm")
+            .help("include the WebAssembly file as hex bytes in the output code")
+            .action(ArgAction::SetTrue),
+        Arg::new("compress-wasm")
+            .long("compress-wasm")
+            .help("compress the WebAssembly file with gzip, which the bindings decompress once when the first factory is created")
+            .action(ArgAction::SetTrue),
+        Arg::new("field-case")
+            .long("field-case")
+            .help("the naming strategy for record fields")
+            .value_parser(["pascal", "initialisms"])
+            .default_value("pascal"),
+        Arg::new("name-case")
+            .long("name-case")
+            .help("the naming strategy for types and functions")
+            .value_parser(["pascal", "initialisms"])
+            .default_value("initialisms"),
+        Arg::new("initialisms")
+            .long("initialisms")
+            .help("the initialisms kept in upper case with `--field-case initialisms` or `--name-case initialisms`")
+            .value_delimiter(',')
+            .default_values(DEFAULT_INITIALISMS.iter().copied()),
+        Arg::new("rename-map")
+            .long("rename-map")
+            .help("a JSON file mapping the WIT paths of functions and record fields, e.g. `iface.func` or `iface.record.field`, to the Go identifiers to generate for them. The file must hold a single object whose values are all strings")
+            .value_name("PATH"),
+        Arg::new("option-style")
+            .long("option-style")
+            .help("how `option<T>` is represented: `pair` returns `T, bool`, and `pointer` uses `*T`, which is `nil` for `none`, in records and signatures too")
+            .value_parser(["pair", "pointer"])
+            .default_value("pair"),
+        Arg::new("named-lists")
+            .long("named-lists")
+            .help("generate a defined type for each named list, e.g. `type Rows []Row` for `type rows = list<row>`, used in signatures instead of the inlined slice")
+            .action(ArgAction::SetTrue),
+        Arg::new("byte-views")
+            .long("byte-views")
+            .help("pass `list<u8>` parameters to host functions as views into guest memory, which are only valid until the host function returns, instead of copies")
+            .action(ArgAction::SetTrue),
+        Arg::new("manual-cleanup")
+            .long("manual-cleanup")
+            .help("return `string` and `list<u8>` results of exported functions as views into guest memory, along with a `cleanup` function the caller must call once done with them, instead of copies")
+            .action(ArgAction::SetTrue),
+        Arg::new("tinygo")
+            .long("tinygo")
+            .help("register host functions without the reflection TinyGo doesn't fully support. Building the bindings with TinyGo isn't verified, and other constructs they use may still be rejected by it")
+            .action(ArgAction::SetTrue),
+        Arg::new("with-binary")
+            .long("with-binary")
+            .help("generate `MarshalBinary` and `UnmarshalBinary` methods encoding records in their Canonical ABI layout, for records of numbers, strings, flags, lists and other such records")
+            .action(ArgAction::SetTrue),
+        Arg::new("with-grpc-adapter")
+            .long("with-grpc-adapter")
+            .help("also generate a gRPC-style service in the bindings, with a method for every exported function taking a context and a request struct of its arguments, and returning a response struct of its results")
+            .conflicts_with("manual-cleanup")
+            .action(ArgAction::SetTrue),
+        Arg::new("strict")
+            .long("strict")
+            .help("check the world for WIT features gravity doesn't support before generating, and fail listing all of them instead of panicking on the first one")
+            .action(ArgAction::SetTrue),
+        Arg::new("out-test")
+            .long("out-test")
+            .help("also generate a skeleton test for the world at the given path, to start from")
+            .value_name("PATH"),
+        Arg::new("with-benchmarks")
+            .long("with-benchmarks")
+            .help("also generate a benchmark of every exported function, next to the output")
+            .requires("output")
+            .action(ArgAction::SetTrue),
+        Arg::new("with-examples")
+            .long("with-examples")
+            .help("also generate a testable example of every exported function, next to the output")
+            .requires("output")
+            .action(ArgAction::SetTrue),
+        Arg::new("emit-generate-directive")
+            .long("emit-generate-directive")
+            .help("write the command used to generate the output as a `//go:generate` directive at its top, so `go generate` regenerates it. Paths are kept as given, so run gravity from the directory of the output")
+            .action(ArgAction::SetTrue),
+        Arg::new("manifest")
+            .long("manifest")
+            .help("a TOML file with an `[[entry]]` table for each world to generate bindings for, setting its `world`, its `wasm` file and the `output` path relative to the manifest, to generate all of them in one invocation with the other options. An entry may also set the `wit` file or directory to read the world from, instead of the WIT embedded in its `wasm` file")
+            .value_name("PATH")
+            .conflicts_with_all([
+                "file",
+                "output",
+                "world",
+                "out-test",
+                "with-benchmarks",
+                "with-examples",
+                "emit-generate-directive",
+            ]),
+        Arg::new("file")
+            .help("the WebAssembly file to process, or `-` to read it from stdin")
+            .required_unless_present("manifest"),
+        Arg::new("output")
+            .help("the file path where output generated code should be output")
+            .short('o')
+            .long("output"),
+    ]
+}
+
+/// Generates the bindings of the world or manifest given in `matches`, or
+/// checks that they're up to date when verifying.
+fn run(matches: &ArgMatches, verify: bool) -> ExitCode {
+    if let Some(path) = matches.get_one::<String>("manifest") {
+        return generate_manifest(matches, path, verify);
+    }
+
     let selected_world = matches
         .get_one::<String>("world")
         .expect("should have a world");
     let file = matches
         .get_one::<String>("file")
         .expect("should have a file");
+    let output = matches.get_one::<String>("output").map(String::as_str);
+    generate(matches, selected_world, None, file, output, verify)
+}
+
+/// Generates the bindings of every entry of a manifest with the options of
+/// the command, stopping at the first failing.
+fn generate_manifest(matches: &ArgMatches, path: &str, verify: bool) -> ExitCode {
+    let Ok(source) = fs::read_to_string(path) else {
+        eprintln!("unable to read manifest: {path}");
+        return ExitCode::FAILURE;
+    };
+    let dir = Path::new(path).parent().unwrap_or(Path::new(""));
+    let entries = match manifest::parse(&source, dir) {
+        Ok(entries) => entries,
+        Err(err) => {
+            eprintln!("invalid manifest: {path}: {err}");
+            return ExitCode::FAILURE;
+        }
+    };
+    for entry in entries {
+        let status = generate(
+            matches,
+            &entry.world,
+            entry.wit.as_deref(),
+            &entry.wasm.to_string_lossy(),
+            Some(entry.output.to_string_lossy().as_ref()),
+            verify,
+        );
+        if status != ExitCode::SUCCESS {
+            return status;
+        }
+    }
+    ExitCode::SUCCESS
+}
+
+/// Generates the bindings of a world of the given WebAssembly file, writing
+/// them to `output`, or to stdout without one. The world is read from `wit`
+/// if given, or else from the WIT embedded in the file. When verifying, the
+/// files are compared with the ones already at their paths instead.
+fn generate(
+    matches: &ArgMatches,
+    selected_world: &str,
+    wit: Option<&Path>,
+    file: &str,
+    output: Option<&str>,
+    verify: bool,
+) -> ExitCode {
+    if verify && output.is_none() {
+        eprintln!("verify requires the output the bindings were generated to");
+        return ExitCode::FAILURE;
+    }
     let inline_wasm = matches.get_flag("inline-wasm");
-    let output = matches.get_one::<String>("output");
+    let with_benchmarks = matches.get_flag("with-benchmarks");
+    let with_examples = matches.get_flag("with-examples");
+    let field_case = |arg: &str| match matches.get_one::<String>(arg).map(String::as_str) {
+        Some("initialisms") => FieldCase::Initialisms(
+            matches
+                .get_many::<String>("initialisms")
+                .expect("should have initialisms")
+                .map(|initialism| initialism.to_uppercase())
+                .collect(),
+        ),
+        _ => FieldCase::Pascal,
+    };
+    let naming = Naming {
+        fields: field_case("field-case"),
+        names: field_case("name-case"),
+    };
+    let option_style = match matches
+        .get_one::<String>("option-style")
+        .map(String::as_str)
+    {
+        Some("pointer") => OptionStyle::Pointer,
+        _ => OptionStyle::Pair,
+    };
+    let emit_generate_directive = matches.get_flag("emit-generate-directive");
+    if emit_generate_directive && file == "-" {
+        eprintln!("--emit-generate-directive requires reading the Wasm from a file, not stdin");
+        return ExitCode::FAILURE;
+    }
 
     // Load the file specified as the `file` arg to clap
-    let wasm = match fs::read(file) {
+    let wasm = match read_input(file) {
         Ok(wasm) => wasm,
         Err(_) => {
             eprintln!("unable to read file: {file}");
-            return Ok(ExitCode::FAILURE);
+            return ExitCode::FAILURE;
         }
     };
 
@@ -62,22 +276,82 @@ fn main() -> Result<ExitCode, ()> {
         // If the Wasm doesn't have a custom section, None will be returned so we need to use the original
         .map(|(module, bindgen)| (module.unwrap_or(wasm), bindgen))
         .expect("file should be a valid WebAssembly module");
+    let resolve = match wit {
+        Some(wit) => {
+            let mut resolve = Resolve::new();
+            if let Err(err) = resolve.push_path(wit) {
+                eprintln!("unable to parse WIT: {}: {err}", wit.display());
+                return ExitCode::FAILURE;
+            }
+            resolve
+        }
+        None => bindgen.resolve,
+    };
 
-    let wasm_file = &format!("{}.wasm", selected_world.replace('-', "_"));
+    let compress = matches.get_flag("compress-wasm");
+    let module = if compress {
+        compress_wasm(&module)
+    } else {
+        module
+    };
+    let wasm_file = &format!(
+        "{}.wasm{}",
+        selected_world.replace('-', "_"),
+        if compress { ".gz" } else { "" }
+    );
 
-    let Some((_, world)) = bindgen
-        .resolve
+    let Some((_, world)) = resolve
         .worlds
         .iter()
         .find(|(_, world)| world.name == *selected_world)
     else {
         eprintln!("unable to find world: {selected_world}");
-        return Ok(ExitCode::FAILURE);
+        return ExitCode::FAILURE;
+    };
+
+    if matches.get_flag("strict") {
+        let unsupported = unsupported_features(&resolve, world);
+        if !unsupported.is_empty() {
+            eprintln!("unsupported WIT features in world {selected_world}:");
+            for feature in unsupported {
+                eprintln!("  {feature}");
+            }
+            return ExitCode::FAILURE;
+        }
+    }
+
+    let renames = match matches.get_one::<String>("rename-map") {
+        Some(path) => {
+            let Ok(source) = fs::read_to_string(path) else {
+                eprintln!("unable to read rename map: {path}");
+                return ExitCode::FAILURE;
+            };
+            let renames = Renames::parse(&source)
+                .and_then(|renames| renames.check(&naming, &resolve, world).map(|_| renames));
+            match renames {
+                Ok(renames) => renames,
+                Err(err) => {
+                    eprintln!("invalid rename map: {path}: {err}");
+                    return ExitCode::FAILURE;
+                }
+            }
+        }
+        None => Renames::default(),
     };
 
     let mut sizes = SizeAlign::default();
-    sizes.fill(&bindgen.resolve);
-    let mut bindings = Bindings::new(&bindgen.resolve, world, &sizes);
+    sizes.fill(&resolve);
+    let mut bindings = Bindings::new(&resolve, world, &sizes);
+    bindings.set_naming(naming);
+    bindings.set_renames(renames);
+    bindings.set_option_style(option_style);
+    bindings.set_named_lists(matches.get_flag("named-lists"));
+    bindings.set_byte_views(matches.get_flag("byte-views"));
+    bindings.set_manual_cleanup(matches.get_flag("manual-cleanup"));
+    bindings.set_tinygo(matches.get_flag("tinygo"));
+    bindings.set_binary(matches.get_flag("with-binary"));
+    bindings.set_grpc_adapter(matches.get_flag("with-grpc-adapter"));
+    bindings.set_compressed_wasm(compress);
 
     bindings.include_wasm(if inline_wasm {
         WasmData::Inline(&module)
@@ -88,10 +362,66 @@ fn main() -> Result<ExitCode, ()> {
     bindings.generate();
 
     let header = "// Code generated by arcjet-gravity; DO NOT EDIT.\n\n".to_string();
-    let mut w = genco::fmt::FmtWriter::new(header);
+    // The directive is the command generating the output, without `verify`
+    let mut w = genco::fmt::FmtWriter::new(if emit_generate_directive {
+        format!(
+            "{header}{}\n\n",
+            generate_directive(std::env::args().skip(if verify { 2 } else { 1 }))
+        )
+    } else {
+        header.clone()
+    });
     let fmt = genco::fmt::Config::from_lang::<Go>().with_indentation(genco::fmt::Indentation::Tab);
     let config = go::Config::default().with_package(selected_world.replace('-', "_"));
 
+    if with_benchmarks {
+        let outpath = output.expect("benchmarks require an output");
+        let mut w = genco::fmt::FmtWriter::new(header.clone());
+        bindings
+            .generate_benchmarks()
+            .format_file(&mut w.as_formatter(&fmt), &config)
+            .unwrap();
+        let stem = Path::new(outpath)
+            .file_stem()
+            .map(|stem| stem.to_string_lossy())
+            .unwrap_or_default();
+        let bench_outpath = Path::new(outpath).with_file_name(format!("{stem}_bench_test.go"));
+        if !emit(&bench_outpath, w.into_inner().as_bytes(), verify) {
+            return ExitCode::FAILURE;
+        }
+    }
+
+    if with_examples {
+        let outpath = output.expect("examples require an output");
+        let mut w = genco::fmt::FmtWriter::new(header.clone());
+        bindings
+            .generate_examples()
+            .format_file(&mut w.as_formatter(&fmt), &config)
+            .unwrap();
+        let stem = Path::new(outpath)
+            .file_stem()
+            .map(|stem| stem.to_string_lossy())
+            .unwrap_or_default();
+        let example_outpath = Path::new(outpath).with_file_name(format!("{stem}_example_test.go"));
+        if !emit(&example_outpath, w.into_inner().as_bytes(), verify) {
+            return ExitCode::FAILURE;
+        }
+    }
+
+    // The scaffold is meant to be edited, so it isn't marked as generated, nor
+    // verified
+    if let Some(test_outpath) = matches.get_one::<String>("out-test").filter(|_| !verify) {
+        let mut w = genco::fmt::FmtWriter::new(String::new());
+        bindings
+            .generate_scaffold()
+            .format_file(&mut w.as_formatter(&fmt), &config)
+            .unwrap();
+        if fs::write(test_outpath, w.into_inner()).is_err() {
+            eprintln!("failed to create file: {test_outpath}");
+            return ExitCode::FAILURE;
+        }
+    }
+
     // TODO(#16): Don't use the internal bindings.out field
     bindings
         .out
@@ -102,25 +432,132 @@ fn main() -> Result<ExitCode, ()> {
         Some(outpath) => {
             if !inline_wasm {
                 let wasm_outpath = Path::new(outpath).with_file_name(wasm_file);
-                match fs::write(&wasm_outpath, module) {
-                    Ok(_) => (),
-                    Err(_) => {
-                        eprintln!("failed to create file: {}", wasm_outpath.to_string_lossy());
-                        return Ok(ExitCode::FAILURE);
-                    }
+                if !emit(&wasm_outpath, &module, verify) {
+                    return ExitCode::FAILURE;
                 }
             }
-            match fs::write(outpath, w.into_inner()) {
-                Ok(_) => Ok(ExitCode::SUCCESS),
-                Err(_) => {
-                    eprintln!("failed to create file: {outpath}");
-                    Ok(ExitCode::FAILURE)
-                }
+            if emit(Path::new(outpath), w.into_inner().as_bytes(), verify) {
+                ExitCode::SUCCESS
+            } else {
+                ExitCode::FAILURE
             }
         }
         None => {
             println!("{}", w.into_inner());
-            Ok(ExitCode::SUCCESS)
+            ExitCode::SUCCESS
+        }
+    }
+}
+
+/// Writes a generated file to `path`, or checks that the file already there
+/// has the same contents when verifying, printing a diff if it doesn't.
+/// Returns whether it succeeded.
+fn emit(path: &Path, contents: &[u8], verify: bool) -> bool {
+    let name = path.to_string_lossy();
+    if !verify {
+        if fs::write(path, contents).is_err() {
+            eprintln!("failed to create file: {name}");
+            return false;
         }
+        return true;
+    }
+    let Ok(existing) = fs::read(path) else {
+        eprintln!("missing generated file: {name}");
+        return false;
+    };
+    if existing == contents {
+        return true;
+    }
+    eprintln!("generated file is out of date: {name}");
+    // Only text files are diffed, the Wasm is simply reported as different
+    if let (Ok(old), Ok(new)) = (
+        std::str::from_utf8(&existing),
+        std::str::from_utf8(contents),
+    ) {
+        print!("--- {name}\n+++ {name}\n{}", diff(old, new));
+    }
+    false
+}
+
+/// Returns a diff of the lines of two texts, as a single hunk spanning the
+/// lines between their common prefix and suffix.
+fn diff(old: &str, new: &str) -> String {
+    let old = old.lines().collect::<Vec<_>>();
+    let new = new.lines().collect::<Vec<_>>();
+    let prefix = old.iter().zip(&new).take_while(|(a, b)| a == b).count();
+    let suffix = old[prefix..]
+        .iter()
+        .rev()
+        .zip(new[prefix..].iter().rev())
+        .take_while(|(a, b)| a == b)
+        .count();
+    let removed = &old[prefix..old.len() - suffix];
+    let added = &new[prefix..new.len() - suffix];
+    let mut hunk = format!(
+        "@@ -{},{} +{},{} @@\n",
+        prefix + 1,
+        removed.len(),
+        prefix + 1,
+        added.len()
+    );
+    for line in removed {
+        hunk.push_str(&format!("-{line}\n"));
+    }
+    for line in added {
+        hunk.push_str(&format!("+{line}\n"));
+    }
+    hunk
+}
+
+/// Prints the computed ABI layout of a world in a WIT file as JSON.
+fn layout(matches: &ArgMatches) -> ExitCode {
+    let wit_file = matches
+        .get_one::<String>("wit-file")
+        .expect("should have a WIT file");
+    let selected_world = matches
+        .get_one::<String>("world")
+        .expect("should have a world");
+
+    let mut resolve = Resolve::new();
+    let parsed = if wit_file == "-" {
+        let source = match read_input(wit_file).map(String::from_utf8) {
+            Ok(Ok(source)) => source,
+            _ => {
+                eprintln!("unable to read WIT from stdin");
+                return ExitCode::FAILURE;
+            }
+        };
+        resolve.push_str("<stdin>", &source).map(|_| ())
+    } else {
+        resolve.push_path(wit_file).map(|_| ())
+    };
+    if let Err(err) = parsed {
+        eprintln!("unable to parse WIT: {wit_file}: {err}");
+        return ExitCode::FAILURE;
+    }
+
+    let Some((_, world)) = resolve
+        .worlds
+        .iter()
+        .find(|(_, world)| world.name == *selected_world)
+    else {
+        eprintln!("unable to find world: {selected_world}");
+        return ExitCode::FAILURE;
+    };
+
+    let mut sizes = SizeAlign::default();
+    sizes.fill(&resolve);
+    println!("{}", layout_json(&resolve, world, &sizes));
+    ExitCode::SUCCESS
+}
+
+/// Reads the contents of the given file, or of stdin if it is `-`.
+fn read_input(path: &str) -> io::Result<Vec<u8>> {
+    if path == "-" {
+        let mut buf = Vec::new();
+        io::stdin().read_to_end(&mut buf)?;
+        Ok(buf)
+    } else {
+        fs::read(path)
     }
 }
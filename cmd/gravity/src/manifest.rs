@@ -0,0 +1,111 @@
+use std::path::{Path, PathBuf};
+
+use serde::Deserialize;
+
+/// The bindings of a world to generate, an entry of a manifest.
+#[derive(Debug, Clone, PartialEq, Eq, Deserialize)]
+#[serde(deny_unknown_fields)]
+pub struct Entry {
+    /// The world to generate bindings for.
+    pub world: String,
+    /// The WIT file or directory the world is read from, instead of the WIT
+    /// embedded in the WebAssembly file.
+    pub wit: Option<PathBuf>,
+    /// The WebAssembly file the world is read from.
+    pub wasm: PathBuf,
+    /// The file the bindings are written to.
+    pub output: PathBuf,
+}
+
+/// A manifest listing the bindings to generate in one invocation.
+#[derive(Debug, Deserialize)]
+#[serde(deny_unknown_fields)]
+struct Manifest {
+    entry: Vec<Entry>,
+}
+
+/// Parses a manifest listing the bindings to generate in one invocation, a
+/// TOML document with an `[[entry]]` table per world, e.g.
+///
+/// ```toml
+/// [[entry]]
+/// world = "basic"
+/// wasm = "target/wasm32-unknown-unknown/release/example_basic.wasm"
+/// output = "examples/basic/basic.go"
+/// ```
+///
+/// Relative paths are resolved against `dir`, the directory of the manifest.
+pub fn parse(source: &str, dir: &Path) -> Result<Vec<Entry>, String> {
+    let manifest = toml::from_str::<Manifest>(source).map_err(|err| err.to_string())?;
+    if manifest.entry.is_empty() {
+        return Err("no `[[entry]]` tables".to_string());
+    }
+    Ok(manifest
+        .entry
+        .into_iter()
+        .map(|entry| Entry {
+            wit: entry.wit.map(|wit| dir.join(wit)),
+            wasm: dir.join(entry.wasm),
+            output: dir.join(entry.output),
+            ..entry
+        })
+        .collect())
+}
+
+#[cfg(test)]
+mod tests {
+    use std::path::{Path, PathBuf};
+
+    use crate::manifest::{Entry, parse};
+
+    #[test]
+    fn test_parse() {
+        let entries = parse(
+            r#"
+            # Every guest of the service
+            [[entry]]
+            world = "basic"
+            wasm = "target/basic.wasm"
+            output = "basic/basic.go" # next to its tests
+
+            [[entry]]
+            world = 'records'
+            wit = "wit/records.wit"
+            wasm = "/opt/guests/records.wasm"
+            output = "records/records.go"
+            "#,
+            Path::new("examples"),
+        )
+        .unwrap();
+
+        assert_eq!(
+            entries,
+            [
+                Entry {
+                    world: "basic".to_string(),
+                    wit: None,
+                    wasm: PathBuf::from("examples/target/basic.wasm"),
+                    output: PathBuf::from("examples/basic/basic.go"),
+                },
+                Entry {
+                    world: "records".to_string(),
+                    wit: Some(PathBuf::from("examples/wit/records.wit")),
+                    // Absolute paths are kept as is
+                    wasm: PathBuf::from("/opt/guests/records.wasm"),
+                    output: PathBuf::from("examples/records/records.go"),
+                },
+            ]
+        );
+    }
+
+    #[test]
+    fn test_parse_errors() {
+        let parse = |source: &str| parse(source, Path::new(""));
+
+        assert_eq!(parse("entry = []").unwrap_err(), "no `[[entry]]` tables");
+        assert!(parse("").is_err());
+        assert!(parse("[[entry]]\nworld = \"basic\"\nwasm = \"basic.wasm\"").is_err());
+        assert!(parse("[[entry]]\nworld = \"basic\"\nwasm = \"basic.wasm\"\noutput = \"basic.go\"\npackage = \"basic\"").is_err());
+        assert!(parse("[[entry]]\nworld = basic").is_err());
+    }
+}
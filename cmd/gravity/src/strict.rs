@@ -0,0 +1,199 @@
+use wit_bindgen_core::wit_parser::{
+    Function, Resolve, Result_, Type, TypeDefKind, World, WorldItem, WorldKey,
+};
+
+/// Lists every construct of a world gravity can't generate bindings for, with
+/// its location in the WIT, so they can be reported at once instead of
+/// panicking in the middle of generating the bindings.
+///
+/// Locations are dotted WIT paths, e.g. `iface.func` for a function of an
+/// imported interface, followed by the parameter or result using the
+/// construct.
+pub fn unsupported_features(resolve: &Resolve, world: &World) -> Vec<String> {
+    let mut unsupported = Vec::new();
+    for (key, item) in &world.imports {
+        match item {
+            WorldItem::Interface { id, .. } => {
+                let interface = &resolve.interfaces[*id];
+                let interface_name = match key {
+                    WorldKey::Name(name) => name,
+                    WorldKey::Interface(_) => {
+                        interface.name.as_ref().expect("interface missing name")
+                    }
+                };
+                for func in interface.functions.values() {
+                    check_function(
+                        resolve,
+                        &format!("{interface_name}.{}", func.name),
+                        func,
+                        &mut unsupported,
+                    );
+                }
+            }
+            WorldItem::Function(func) => {
+                check_function(resolve, &func.name, func, &mut unsupported)
+            }
+            WorldItem::Type(_) => {}
+        }
+    }
+    for (key, item) in &world.exports {
+        match item {
+            WorldItem::Function(func) => {
+                check_function(resolve, &func.name, func, &mut unsupported)
+            }
+            WorldItem::Interface { .. } => unsupported.push(format!(
+                "`{}`: exported interfaces are not supported",
+                resolve.name_world_key(key)
+            )),
+            WorldItem::Type(_) => unsupported.push(format!(
+                "`{}`: exported types are not supported",
+                resolve.name_world_key(key)
+            )),
+        }
+    }
+    unsupported
+}
+
+/// Checks the parameters and result of a function.
+fn check_function(resolve: &Resolve, path: &str, func: &Function, unsupported: &mut Vec<String>) {
+    for (name, typ) in &func.params {
+        if let Some(reason) = unsupported_type(resolve, typ) {
+            unsupported.push(format!("`{path}` parameter `{name}`: {reason}"));
+        }
+    }
+    if let Some(reason) = func
+        .result
+        .as_ref()
+        .and_then(|typ| unsupported_type(resolve, typ))
+    {
+        unsupported.push(format!("`{path}` result: {reason}"));
+    }
+}
+
+/// Returns why a type isn't supported, if it or a type it contains isn't.
+///
+/// This mirrors the types `resolve_type` has yet to implement.
+fn unsupported_type(resolve: &Resolve, typ: &Type) -> Option<String> {
+    let id = match typ {
+        Type::Char => return Some("`char` is not supported".to_string()),
+        Type::ErrorContext => return Some("`error-context` is not supported".to_string()),
+        Type::Id(id) => *id,
+        _ => return None,
+    };
+    let typ = &resolve.types[id];
+    let named = |kind: &str| match &typ.name {
+        Some(name) => format!("{kind} `{name}`"),
+        None => kind.to_string(),
+    };
+    match &typ.kind {
+        TypeDefKind::Resource | TypeDefKind::Handle(_) => {
+            Some(format!("{} is not supported", named("resource")))
+        }
+        TypeDefKind::Future(_) => Some("`future` is not supported".to_string()),
+        TypeDefKind::Stream(_) => Some("`stream` is not supported".to_string()),
+        TypeDefKind::FixedSizeList(..) => Some("fixed-size lists are not supported".to_string()),
+        TypeDefKind::Unknown => Some("unknown types are not supported".to_string()),
+        TypeDefKind::Result(Result_ { err: Some(err), .. }) if *err != Type::String => {
+            Some("`result` with an error other than `string` is not supported".to_string())
+        }
+        TypeDefKind::Result(Result_ { ok, .. }) => {
+            ok.as_ref().and_then(|ok| unsupported_type(resolve, ok))
+        }
+        TypeDefKind::Record(record) => record.fields.iter().find_map(|field| {
+            unsupported_type(resolve, &field.ty)
+                .map(|reason| format!("{} field `{}`: {reason}", named("record"), field.name))
+        }),
+        TypeDefKind::Variant(variant) => variant.cases.iter().find_map(|case| {
+            case.ty.as_ref().and_then(|ty| {
+                unsupported_type(resolve, ty)
+                    .map(|reason| format!("{} case `{}`: {reason}", named("variant"), case.name))
+            })
+        }),
+        TypeDefKind::Tuple(tuple) => tuple
+            .types
+            .iter()
+            .find_map(|ty| unsupported_type(resolve, ty)),
+        TypeDefKind::Option(ty) | TypeDefKind::List(ty) | TypeDefKind::Type(ty) => {
+            unsupported_type(resolve, ty)
+        }
+        TypeDefKind::Flags(_) | TypeDefKind::Enum(_) => None,
+    }
+}
+
+#[cfg(test)]
+mod tests {
+    use wit_bindgen_core::wit_parser::Resolve;
+
+    use crate::strict::unsupported_features;
+
+    #[test]
+    fn test_unsupported_features() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "strict.wit",
+                r#"
+                package arcjet:strict;
+
+                interface codes {
+                  record code {
+                    id: u32,
+                    symbol: char,
+                  }
+
+                  lookup: func(id: u32) -> option<code>;
+                  parse: func(source: string) -> result<u32, u32>;
+                  log: func(msg: string);
+                }
+
+                world strict {
+                  import codes;
+
+                  export initial: func(name: string) -> char;
+                  export run: func(name: string) -> string;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "strict")
+            .expect("failed to find world");
+
+        assert_eq!(
+            unsupported_features(&resolve, world),
+            [
+                "`codes.lookup` result: record `code` field `symbol`: `char` is not supported",
+                "`codes.parse` result: `result` with an error other than `string` is not supported",
+                "`initial` result: `char` is not supported",
+            ]
+        );
+    }
+
+    #[test]
+    fn test_supported_features() {
+        let mut resolve = Resolve::new();
+        resolve
+            .push_str(
+                "supported.wit",
+                r#"
+                package arcjet:supported;
+
+                world supported {
+                  import log: func(msg: string);
+
+                  export run: func(names: list<string>) -> result<option<u32>, string>;
+                }
+                "#,
+            )
+            .expect("failed to parse WIT");
+        let (_, world) = resolve
+            .worlds
+            .iter()
+            .find(|(_, world)| world.name == "supported")
+            .expect("failed to find world");
+
+        assert!(unsupported_features(&resolve, world).is_empty());
+    }
+}
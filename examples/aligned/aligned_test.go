@@ -0,0 +1,52 @@
+package aligned
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTotal(t *testing.T) {
+	fac, err := NewAlignedFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	samples := []Sample{
+		{Weight: 1, Id: 1 << 40},
+		{Weight: 2, Id: 3},
+		{Weight: math.MaxUint32, Id: 1},
+	}
+	expected := uint64(1<<40 + 6 + math.MaxUint32)
+	if actual := ins.Total(t.Context(), samples); actual != expected {
+		t.Errorf("expected: %d, but got: %d", expected, actual)
+	}
+}
+
+func TestAligned(t *testing.T) {
+	fac, err := NewAlignedFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	// Lists of different sizes are allocated at different offsets
+	for n := range 8 {
+		ids := make([]uint64, n+1)
+		if !ins.Aligned(t.Context(), ids) {
+			t.Errorf("expected a list of %d ids to be 8-byte aligned", len(ids))
+		}
+	}
+}
@@ -0,0 +1,21 @@
+wit_bindgen::generate!({
+    world: "aligned",
+});
+
+struct AlignedWorld;
+
+export!(AlignedWorld);
+
+impl Guest for AlignedWorld {
+    fn total(samples: Vec<Sample>) -> u64 {
+        samples
+            .iter()
+            .map(|sample| sample.id * u64::from(sample.weight))
+            .sum()
+    }
+
+    fn aligned(ids: Vec<u64>) -> bool {
+        // The list is used in place, so this is the pointer the host allocated
+        ids.as_ptr().align_offset(align_of::<u64>()) == 0
+    }
+}
@@ -0,0 +1,47 @@
+package allocs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAllocFailure(t *testing.T) {
+	fac, err := NewAllocsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	ins.FailAllocations(t.Context(), true)
+
+	if _, err := ins.Length(t.Context(), "hello"); !errors.Is(err, ErrGuestAllocFailed) {
+		t.Errorf("expected: %v, but got: %v", ErrGuestAllocFailed, err)
+	}
+	if _, err := ins.Sum(t.Context(), []uint32{1, 2, 3}); !errors.Is(err, ErrGuestAllocFailed) {
+		t.Errorf("expected: %v, but got: %v", ErrGuestAllocFailed, err)
+	}
+
+	// The instance is still usable once the guest can allocate again
+	ins.FailAllocations(t.Context(), false)
+
+	length, err := ins.Length(t.Context(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if length != 5 {
+		t.Errorf("expected: %d, but got: %d", 5, length)
+	}
+	sum, err := ins.Sum(t.Context(), []uint32{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != 6 {
+		t.Errorf("expected: %d, but got: %d", 6, sum)
+	}
+}
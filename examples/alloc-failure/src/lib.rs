@@ -0,0 +1,52 @@
+use std::{
+    alloc::{self, Layout},
+    ptr,
+    sync::atomic::{AtomicBool, Ordering},
+};
+
+wit_bindgen::generate!({
+    world: "allocs",
+});
+
+static FAIL_ALLOCATIONS: AtomicBool = AtomicBool::new(false);
+
+/// The canonical ABI realloc function, which returns a null pointer instead of
+/// trapping when allocations are configured to fail.
+#[unsafe(no_mangle)]
+pub unsafe extern "C" fn cabi_realloc(
+    old_ptr: *mut u8,
+    old_len: usize,
+    align: usize,
+    new_len: usize,
+) -> *mut u8 {
+    if FAIL_ALLOCATIONS.load(Ordering::SeqCst) {
+        return ptr::null_mut();
+    }
+    unsafe {
+        if old_len == 0 {
+            if new_len == 0 {
+                return align as *mut u8;
+            }
+            alloc::alloc(Layout::from_size_align_unchecked(new_len, align))
+        } else {
+            let layout = Layout::from_size_align_unchecked(old_len, align);
+            alloc::realloc(old_ptr, layout, new_len)
+        }
+    }
+}
+
+struct AllocsWorld;
+
+export!(AllocsWorld);
+
+impl Guest for AllocsWorld {
+    fn fail_allocations(enabled: bool) {
+        FAIL_ALLOCATIONS.store(enabled, Ordering::SeqCst);
+    }
+    fn length(s: String) -> Result<u32, String> {
+        Ok(s.len() as u32)
+    }
+    fn sum(items: Vec<u32>) -> Result<u32, String> {
+        Ok(items.iter().sum())
+    }
+}
@@ -0,0 +1,84 @@
+package arena
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestConcat(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("arena=%t", enabled), func(t *testing.T) {
+			fac, err := NewArenaFactory(t.Context(), WithArgArena(enabled))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fac.Close(t.Context())
+
+			ins, err := fac.Instantiate(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ins.Close(t.Context())
+
+			// Repeat the call so the arena is reused, and grown once the
+			// arguments no longer fit.
+			for i := range 3 {
+				b := fmt.Sprintf("%0*d", i*100, 0)
+				expected := "a" + b + "c"
+				if actual := ins.Concat(t.Context(), "a", b, "c"); actual != expected {
+					t.Errorf("expected: %q, but got: %q", expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestArgArenaReusesMemory(t *testing.T) {
+	fac, err := NewArenaFactory(t.Context(), WithArgArena(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	// The first call allocates its arguments with realloc and then grows
+	// the arena to fit them.
+	ins.Concat(t.Context(), "a", "b", "c")
+
+	before := ins.Allocations(t.Context())
+	ins.Concat(t.Context(), "a", "b", "c")
+	// Only the result is allocated by the guest.
+	if actual := ins.Allocations(t.Context()) - before; actual != 1 {
+		t.Errorf("expected 1 allocation, but got: %d", actual)
+	}
+}
+
+func BenchmarkConcat(b *testing.B) {
+	for _, enabled := range []bool{false, true} {
+		b.Run(fmt.Sprintf("arena=%t", enabled), func(b *testing.B) {
+			fac, err := NewArenaFactory(b.Context(), WithArgArena(enabled))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer fac.Close(b.Context())
+
+			ins, err := fac.Instantiate(b.Context())
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer ins.Close(b.Context())
+
+			before := ins.Allocations(b.Context())
+			for b.Loop() {
+				ins.Concat(b.Context(), "hello", ", ", "world")
+			}
+			allocations := ins.Allocations(b.Context()) - before
+			b.ReportMetric(float64(allocations)/float64(b.N), "reallocs/op")
+		})
+	}
+}
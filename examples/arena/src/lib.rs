@@ -0,0 +1,42 @@
+use std::{
+    alloc::{GlobalAlloc, Layout, System},
+    sync::atomic::{AtomicUsize, Ordering},
+};
+
+wit_bindgen::generate!({
+    world: "arena",
+});
+
+/// Counts the allocations made in the guest, so the host can check how often
+/// realloc is called for arguments.
+///
+/// Freeing is a no-op, as arguments allocated from the host's arena are not
+/// owned by the guest's allocator and must not be freed by it.
+struct CountingAllocator;
+
+static ALLOCATIONS: AtomicUsize = AtomicUsize::new(0);
+
+unsafe impl GlobalAlloc for CountingAllocator {
+    unsafe fn alloc(&self, layout: Layout) -> *mut u8 {
+        ALLOCATIONS.fetch_add(1, Ordering::SeqCst);
+        unsafe { System.alloc(layout) }
+    }
+
+    unsafe fn dealloc(&self, _ptr: *mut u8, _layout: Layout) {}
+}
+
+#[global_allocator]
+static GLOBAL: CountingAllocator = CountingAllocator;
+
+struct ArenaWorld;
+
+export!(ArenaWorld);
+
+impl Guest for ArenaWorld {
+    fn concat(a: String, b: String, c: String) -> String {
+        [a, b, c].concat()
+    }
+    fn allocations() -> u32 {
+        ALLOCATIONS.load(Ordering::SeqCst) as u32
+    }
+}
@@ -0,0 +1,63 @@
+package arrays
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIssue(t *testing.T) {
+	fac, err := NewArraysFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	id := [16]byte{0: 0xde, 1: 0xad, 15: 0xff}
+	token := ins.Issue(t.Context(), id[:], "gravity")
+	if token.Id != id {
+		t.Errorf("expected: %x, but got: %x", id, token.Id)
+	}
+	if token.Name != "gravity" {
+		t.Errorf("expected: %q, but got: %q", "gravity", token.Name)
+	}
+
+	expected := "gravity: dead00000000000000000000000000ff"
+	if actual := ins.Describe(t.Context(), token); actual != expected {
+		t.Errorf("expected: %q, but got: %q", expected, actual)
+	}
+}
+
+// TestIssueWrongLength checks that lists of another length are rejected,
+// rather than truncated or padded with zeros.
+func TestIssueWrongLength(t *testing.T) {
+	fac, err := NewArraysFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	for _, n := range []int{15, 17} {
+		func() {
+			defer func() {
+				err, ok := recover().(error)
+				if !ok || !strings.Contains(err.Error(), "expected 16 bytes for token.id") {
+					t.Errorf("%d bytes: expected a length error, but got: %v", n, err)
+				}
+			}()
+			token := ins.Issue(t.Context(), make([]byte, n), "gravity")
+			t.Errorf("%d bytes: expected a panic, but got: %v", n, token)
+		}()
+	}
+}
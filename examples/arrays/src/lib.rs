@@ -0,0 +1,22 @@
+wit_bindgen::generate!({
+    world: "arrays",
+});
+
+struct ArraysWorld;
+
+export!(ArraysWorld);
+
+impl Guest for ArraysWorld {
+    fn issue(id: Vec<u8>, name: String) -> Token {
+        Token { id, name }
+    }
+
+    fn describe(token: Token) -> String {
+        let id = token
+            .id
+            .iter()
+            .map(|b| format!("{b:02x}"))
+            .collect::<String>();
+        format!("{}: {id}", token.name)
+    }
+}
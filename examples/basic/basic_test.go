@@ -1,9 +1,17 @@
 package basic
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"log/slog"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
 )
 
 type SlogLogger struct{}
@@ -13,6 +21,10 @@ func (s SlogLogger) Info(ctx context.Context, msg string)  { slog.InfoContext(ct
 func (s SlogLogger) Warn(ctx context.Context, msg string)  { slog.WarnContext(ctx, msg) }
 func (s SlogLogger) Error(ctx context.Context, msg string) { slog.ErrorContext(ctx, msg) }
 
+// The host interface is exported, so implementations can be checked at
+// compile time.
+var _ IBasicLogger = (*SlogLogger)(nil)
+
 func TestBasic(t *testing.T) {
 	fac, err := NewBasicFactory(t.Context(), SlogLogger{})
 	if err != nil {
@@ -37,6 +49,107 @@ func TestBasic(t *testing.T) {
 	}
 }
 
+type contextKey struct{}
+
+// ContextLogger records the value of contextKey in the context of its calls.
+type ContextLogger struct {
+	SlogLogger
+	values []any
+}
+
+func (l *ContextLogger) Debug(ctx context.Context, msg string) {
+	l.values = append(l.values, ctx.Value(contextKey{}))
+}
+
+func TestContextPropagation(t *testing.T) {
+	logger := &ContextLogger{}
+	fac, err := NewBasicFactory(t.Context(), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	// The host import receives the context of the call into the guest
+	ctx := context.WithValue(t.Context(), contextKey{}, "trace-id")
+	if _, err := ins.Hello(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(logger.values) != 1 || logger.values[0] != "trace-id" {
+		t.Errorf("expected the host to see: %q, but got: %v", "trace-id", logger.values)
+	}
+}
+
+// BlockingLogger blocks in Debug until the context of the call is done,
+// recording why it was.
+type BlockingLogger struct {
+	SlogLogger
+	err error
+}
+
+func (l *BlockingLogger) Debug(ctx context.Context, msg string) {
+	<-ctx.Done()
+	l.err = ctx.Err()
+}
+
+func TestHostCancellation(t *testing.T) {
+	logger := &BlockingLogger{}
+	fac, err := NewBasicFactory(t.Context(), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	// Cancelling the call into the guest cancels the host import it's blocked in
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := ins.Hello(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the call to return promptly, but it took: %v", elapsed)
+	}
+	if !errors.Is(logger.err, context.Canceled) {
+		t.Errorf("expected the host to see: %v, but got: %v", context.Canceled, logger.err)
+	}
+}
+
+func TestBasicInterpreter(t *testing.T) {
+	fac, err := NewBasicFactory(t.Context(), SlogLogger{}, WithInterpreter())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	message, err := ins.Hello(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "Hello, world!"
+	if message != want {
+		t.Errorf("wanted: %s, but got: %s", want, message)
+	}
+}
+
 func TestNoPrimitiveCleanup(t *testing.T) {
 	fac, err := NewBasicFactory(t.Context(), SlogLogger{})
 	if err != nil {
@@ -105,3 +218,126 @@ func TestResultPrimitiveCleanup(t *testing.T) {
 		t.Errorf("expected: %t, but got: %t", expected, actual)
 	}
 }
+
+// MockInstance is a hand-written mock of the instance, for testing code
+// depending on one without instantiating the module.
+type MockInstance struct {
+	message string
+}
+
+func (m *MockInstance) Close(ctx context.Context) error { return nil }
+func (m *MockInstance) MemorySize() uint64              { return 0 }
+func (m *MockInstance) MemoryStats() MemoryStats        { return MemoryStats{} }
+func (m *MockInstance) Reset(ctx context.Context) error { return nil }
+func (m *MockInstance) Hello(ctx context.Context) (string, error) {
+	return m.message, nil
+}
+func (m *MockInstance) Primitive(ctx context.Context) bool { return true }
+func (m *MockInstance) OptionalPrimitive(ctx context.Context) (bool, bool) {
+	return true, true
+}
+func (m *MockInstance) ResultPrimitive(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+var _ IBasicInstance = (*MockInstance)(nil)
+
+// shout depends on an instance through the generated interface.
+func shout(ctx context.Context, ins IBasicInstance) (string, error) {
+	message, err := ins.Hello(ctx)
+	if err != nil {
+		return "", err
+	}
+	return message + "!", nil
+}
+
+func TestMockInstance(t *testing.T) {
+	actual, err := shout(t.Context(), &MockInstance{message: "Hello, mock"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "Hello, mock!"
+	if actual != want {
+		t.Errorf("wanted: %s, but got: %s", want, actual)
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	fac, err := NewBasicFactory(t.Context(), SlogLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	// Closing again, such as in a deferred call after an explicit one, is a
+	// no-op rather than a panic
+	for range 2 {
+		if err := ins.Close(t.Context()); err != nil {
+			t.Errorf("expected closing the instance to succeed, but got: %v", err)
+		}
+	}
+	for range 2 {
+		fac.Close(t.Context())
+	}
+}
+
+// PanickingLogger panics whenever the guest logs a debug message.
+type PanickingLogger struct {
+	SlogLogger
+}
+
+func (PanickingLogger) Debug(context.Context, string) {
+	panic("logger unavailable")
+}
+
+func TestHostPanic(t *testing.T) {
+	fac, err := NewBasicFactory(t.Context(), PanickingLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	// The panic traps the guest, and the call fails rather than crashing
+	_, err = ins.Hello(t.Context())
+	if !errors.Is(err, ErrHostPanic) {
+		t.Fatalf("expected error: %v, but got: %v", ErrHostPanic, err)
+	}
+	if expected := "basic.PanickingLogger.Debug: logger unavailable"; !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected the error to contain: %q, but got: %q", expected, err.Error())
+	}
+}
+
+// BenchmarkCall calls an exported function through pooled instances, which
+// look the function up once and reuse it on later calls.
+func BenchmarkCall(b *testing.B) {
+	fac, err := NewBasicFactory(b.Context(), SlogLogger{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer fac.Close(b.Context())
+
+	for b.Loop() {
+		err := fac.Call(b.Context(), func(ins *BasicInstance) error {
+			if !ins.Primitive(b.Context()) {
+				return errors.New("expected true")
+			}
+			return nil
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+wit_bindgen::generate!({
+    world: "stats",
+});
+
+struct StatsWorld;
+
+export!(StatsWorld);
+
+impl Guest for StatsWorld {
+    fn stats() -> Stats {
+        // Negative and large values check that every field is read at its
+        // offset and with its width and sign.
+        Stats {
+            name: "gravity".to_string(),
+            tiny: u8::MAX,
+            signed_tiny: i8::MIN,
+            small: u16::MAX,
+            signed_small: i16::MIN,
+            count: -42,
+            total: u64::MAX,
+            delta: i64::MIN,
+            ratio: 0.5,
+            mean: -4.2,
+        }
+    }
+}
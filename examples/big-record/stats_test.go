@@ -0,0 +1,36 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	fac, err := NewStatsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	expected := Stats{
+		Name:        "gravity",
+		Tiny:        math.MaxUint8,
+		SignedTiny:  math.MinInt8,
+		Small:       math.MaxUint16,
+		SignedSmall: math.MinInt16,
+		Count:       -42,
+		Total:       math.MaxUint64,
+		Delta:       math.MinInt64,
+		Ratio:       0.5,
+		Mean:        -4.2,
+	}
+	if actual := ins.Stats(t.Context()); actual != expected {
+		t.Errorf("expected: %+v, but got: %+v", expected, actual)
+	}
+}
@@ -0,0 +1,15 @@
+use arcjet::views::bytes;
+
+wit_bindgen::generate!({
+    world: "views",
+});
+
+struct ViewsWorld;
+
+export!(ViewsWorld);
+
+impl Guest for ViewsWorld {
+    fn checksum(data: Vec<u8>) -> u32 {
+        bytes::sum(&data)
+    }
+}
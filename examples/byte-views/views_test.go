@@ -0,0 +1,48 @@
+package views
+
+import (
+	"context"
+	"testing"
+)
+
+// Bytes sums the bytes by reading them directly from guest memory.
+type Bytes struct{}
+
+func (Bytes) Sum(_ context.Context, data []uint8) uint32 {
+	var sum uint32
+	for _, b := range data {
+		sum += uint32(b)
+	}
+	return sum
+}
+
+func TestChecksum(t *testing.T) {
+	fac, err := NewViewsFactory(t.Context(), Bytes{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	tests := []struct {
+		name     string
+		data     []uint8
+		expected uint32
+	}{
+		{"empty", nil, 0},
+		{"bytes", []uint8{1, 2, 3}, 6},
+		{"large", make([]uint8, 1<<16), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := ins.Checksum(t.Context(), tt.data); actual != tt.expected {
+				t.Errorf("expected: %d, but got: %d", tt.expected, actual)
+			}
+		})
+	}
+}
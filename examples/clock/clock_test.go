@@ -0,0 +1,56 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWASIClock(t *testing.T) {
+	fixed := time.Date(2024, time.March, 1, 12, 30, 0, 500, time.UTC)
+	fac, err := NewClockFactory(t.Context(), WithWASIClock(fixed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	if actual := ins.Now(t.Context()); actual != uint64(fixed.UnixNano()) {
+		t.Errorf("expected the guest to see: %d, but got: %d", fixed.UnixNano(), actual)
+	}
+}
+
+// zeroReader is a random source which only reads zeros, and never runs out.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestWASIRandSource(t *testing.T) {
+	fac, err := NewClockFactory(t.Context(), WithWASIRandSource(zeroReader{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	// Every instance keys its hasher from the same bytes, so it hashes the
+	// same value alike
+	hashes := make([]uint64, 2)
+	for i := range hashes {
+		ins, err := fac.Instantiate(t.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes[i] = ins.Hash(t.Context(), "gravity")
+		ins.Close(t.Context())
+	}
+	if hashes[0] != hashes[1] {
+		t.Errorf("expected the hashes to be equal, but got: %d and %d", hashes[0], hashes[1])
+	}
+}
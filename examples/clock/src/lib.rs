@@ -0,0 +1,26 @@
+use std::{
+    collections::hash_map::RandomState,
+    hash::BuildHasher,
+    time::{SystemTime, UNIX_EPOCH},
+};
+
+wit_bindgen::generate!({
+    world: "clock",
+});
+
+struct ClockWorld;
+
+export!(ClockWorld);
+
+impl Guest for ClockWorld {
+    fn now() -> u64 {
+        SystemTime::now()
+            .duration_since(UNIX_EPOCH)
+            .expect("clock is before the Unix epoch")
+            .as_nanos() as u64
+    }
+
+    fn hash(value: String) -> u64 {
+        RandomState::new().hash_one(value)
+    }
+}
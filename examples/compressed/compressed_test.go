@@ -0,0 +1,69 @@
+package basic
+
+import (
+	"context"
+	"testing"
+)
+
+// Logger records the messages the guest logs.
+type Logger struct {
+	messages []string
+}
+
+func (l *Logger) Debug(ctx context.Context, msg string) { l.messages = append(l.messages, msg) }
+func (l *Logger) Info(ctx context.Context, msg string)  { l.messages = append(l.messages, msg) }
+func (l *Logger) Warn(ctx context.Context, msg string)  { l.messages = append(l.messages, msg) }
+func (l *Logger) Error(ctx context.Context, msg string) { l.messages = append(l.messages, msg) }
+
+// TestCompressed runs the basic example with bindings generated with
+// --compress-wasm, which embed the module compressed with gzip.
+func TestCompressed(t *testing.T) {
+	fac, err := NewBasicFactory(t.Context(), &Logger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	message, err := ins.Hello(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "Hello, world!"; message != expected {
+		t.Errorf("expected: %q, but got: %q", expected, message)
+	}
+	if !ins.Primitive(t.Context()) {
+		t.Error("expected primitive to be true")
+	}
+}
+
+func TestDecompressedOnce(t *testing.T) {
+	first, err := decompressWasm()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) <= len(wasmFileBasic) {
+		t.Errorf("expected the module to be larger than its %d compressed bytes, but got %d", len(wasmFileBasic), len(first))
+	}
+
+	// Later factories reuse the decompressed module
+	for range 2 {
+		fac, err := NewBasicFactory(t.Context(), &Logger{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fac.Close(t.Context())
+	}
+	second, err := decompressWasm()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &first[0] != &second[0] {
+		t.Error("expected the module to be decompressed once")
+	}
+}
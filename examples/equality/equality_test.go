@@ -0,0 +1,72 @@
+package equality
+
+import (
+	"bytes"
+	"encoding"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	fac, err := NewEqualityFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	expected := Path{
+		Name:   "home",
+		Start:  Point{X: 0, Y: 0},
+		Points: []Point{{X: 1, Y: -1}, {X: 2, Y: -2}, {X: 3, Y: -3}},
+		Tags:   []string{"walk", "home"},
+	}
+	actual := ins.Walk(t.Context(), "home", 3)
+	if !actual.Equal(expected) {
+		t.Errorf("expected: %+v, but got: %+v", expected, actual)
+	}
+
+	// Differences in slices of nested records are detected too
+	actual.Points[1].Y = 2
+	if actual.Equal(expected) {
+		t.Errorf("expected %+v not to equal %+v", actual, expected)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	expected := Path{
+		Name:   "home",
+		Start:  Point{X: 1, Y: -1},
+		Points: []Point{{X: 2, Y: -2}, {X: 3, Y: -3}},
+		Tags:   []string{"walk", "", "home"},
+	}
+	var marshaler encoding.BinaryMarshaler = expected
+	data, err := marshaler.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual Path
+	var unmarshaler encoding.BinaryUnmarshaler = &actual
+	if err := unmarshaler.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !actual.Equal(expected) {
+		t.Errorf("expected: %+v, but got: %+v", expected, actual)
+	}
+
+	// The fields are laid out like in guest memory
+	start := []byte{1, 0, 0, 0, 0xff, 0xff, 0xff, 0xff}
+	if !bytes.Equal(data[8:16], start) {
+		t.Errorf("expected start: %x, but got: %x", start, data[8:16])
+	}
+
+	// Strings and lists pointing past the data are rejected
+	if err := actual.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Error("expected an error for truncated data")
+	}
+}
@@ -0,0 +1,19 @@
+wit_bindgen::generate!({
+    world: "equality",
+});
+
+struct EqualityWorld;
+
+export!(EqualityWorld);
+
+impl Guest for EqualityWorld {
+    fn walk(name: String, steps: u32) -> Path {
+        let points = (1..=steps as i32).map(|i| Point { x: i, y: -i }).collect();
+        Path {
+            tags: vec!["walk".to_string(), name.clone()],
+            name,
+            start: Point { x: 0, y: 0 },
+            points,
+        }
+    }
+}
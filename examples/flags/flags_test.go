@@ -0,0 +1,120 @@
+package flags
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+	fac, err := NewFlagsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	tests := map[string]struct {
+		perms Perms
+		count uint32
+	}{
+		"none":       {perms: 0, count: 0},
+		"low":        {perms: PermsF0 | PermsF31, count: 2},
+		"bit 33":     {perms: PermsF33, count: 1},
+		"both words": {perms: PermsF1 | PermsF33 | PermsF39, count: 3},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual := ins.Echo(t.Context(), test.perms)
+			if actual != test.perms {
+				t.Errorf("expected: %#x, but got: %#x", test.perms, actual)
+			}
+			count := ins.Count(t.Context(), test.perms)
+			if count != test.count {
+				t.Errorf("expected %d flags, but got: %d", test.count, count)
+			}
+		})
+	}
+}
+
+func TestListOfFlags(t *testing.T) {
+	fac, err := NewFlagsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	perms := []Perms{PermsF0, PermsF1 | PermsF33, PermsF39}
+	expected := []Perms{PermsF39, PermsF1 | PermsF33, PermsF0}
+	actual := ins.Reverse(t.Context(), perms)
+	if !slices.Equal(actual, expected) {
+		t.Errorf("expected: %#x, but got: %#x", expected, actual)
+	}
+}
+
+func TestListOfEnums(t *testing.T) {
+	fac, err := NewFlagsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	colors := []Color{Red, Green, Blue}
+	expected := []Color{Green, Blue, Red}
+	actual := ins.Rotate(t.Context(), colors)
+	if !slices.Equal(actual, expected) {
+		t.Errorf("expected: %v, but got: %v", expected, actual)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	fac, err := NewFlagsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	swatch := Swatch{Name: "sky", Color: Blue, Perms: PermsF0 | PermsF39}
+	if err := swatch.Validate(); err != nil {
+		t.Fatalf("expected a valid swatch, but got: %v", err)
+	}
+	const expected = "sky (blue, 2 perms)"
+	if actual := ins.Label(t.Context(), swatch); actual != expected {
+		t.Errorf("expected: %q, but got: %q", expected, actual)
+	}
+
+	tests := map[string]Swatch{
+		"out of range enum": {Name: "sky", Color: color(3)},
+		"nil enum":          {Name: "sky"},
+		"unknown flag":      {Name: "sky", Color: Red, Perms: PermsF39 << 1},
+	}
+	for name, swatch := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := swatch.Validate(); err == nil {
+				t.Errorf("expected %+v to be invalid", swatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+wit_bindgen::generate!({
+    world: "flags",
+});
+
+struct FlagsWorld;
+
+export!(FlagsWorld);
+
+impl Guest for FlagsWorld {
+    fn echo(perms: Perms) -> Perms {
+        perms
+    }
+
+    fn count(perms: Perms) -> u32 {
+        perms.bits().count_ones()
+    }
+
+    fn reverse(mut perms: Vec<Perms>) -> Vec<Perms> {
+        perms.reverse();
+        perms
+    }
+
+    fn rotate(colors: Vec<Color>) -> Vec<Color> {
+        colors
+            .into_iter()
+            .map(|color| match color {
+                Color::Red => Color::Green,
+                Color::Green => Color::Blue,
+                Color::Blue => Color::Red,
+            })
+            .collect()
+    }
+
+    fn label(swatch: Swatch) -> String {
+        let color = match swatch.color {
+            Color::Red => "red",
+            Color::Green => "green",
+            Color::Blue => "blue",
+        };
+        format!("{} ({color}, {} perms)", swatch.name, swatch.perms.bits().count_ones())
+    }
+}
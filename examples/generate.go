@@ -3,7 +3,61 @@ package examples
 //go:generate cargo build -p example-basic --target wasm32-unknown-unknown --release
 //go:generate cargo build -p example-iface-method-returns-string --target wasm32-unknown-unknown --release
 //go:generate cargo build -p example-instructions --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-iface-method-integers --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-streaming --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-type-aliases --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-lists --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-arena --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-alloc-failure --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-byte-views --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-reset --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-tuples --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-results --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-recursion --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-manual-cleanup --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-multi-import --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-many-params --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-big-record --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-packing --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-equality --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-aligned --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-options --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-memory-limit --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-flags --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-start --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-arrays --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-lookups --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-validation --target wasm32-unknown-unknown --release
+//go:generate cargo build -p example-clock --target wasm32-wasip1 --release
 
-//go:generate cargo run --bin gravity -- --world basic --output ./basic/basic.go ../target/wasm32-unknown-unknown/release/example_basic.wasm
+//go:generate cargo run --bin gravity -- --world basic --output ./basic/basic.go --out-test ./basic/basic_smoke_test.go ../target/wasm32-unknown-unknown/release/example_basic.wasm
 //go:generate cargo run --bin gravity -- --world example --output ./iface-method-returns-string/example.go ../target/wasm32-unknown-unknown/release/example_iface_method_returns_string.wasm
 //go:generate cargo run --bin gravity -- --world instructions --output ./instructions/bindings.go ../target/wasm32-unknown-unknown/release/example_instructions.wasm
+//go:generate cargo run --bin gravity -- --world integers --output ./iface-method-integers/bindings.go ../target/wasm32-unknown-unknown/release/example_iface_method_integers.wasm
+//go:generate cargo run --bin gravity -- --world streaming --output ./streaming/bindings.go ../target/wasm32-unknown-unknown/release/example_streaming.wasm
+//go:generate cargo run --bin gravity -- --world aliases --output ./type-aliases/bindings.go ../target/wasm32-unknown-unknown/release/example_type_aliases.wasm
+//go:generate cargo run --bin gravity -- --world lists --output ./lists/bindings.go --with-grpc-adapter --with-benchmarks ../target/wasm32-unknown-unknown/release/example_lists.wasm
+//go:generate cargo run --bin gravity -- --world arena --output ./arena/bindings.go ../target/wasm32-unknown-unknown/release/example_arena.wasm
+//go:generate cargo run --bin gravity -- --world allocs --output ./alloc-failure/bindings.go ../target/wasm32-unknown-unknown/release/example_alloc_failure.wasm
+//go:generate cargo run --bin gravity -- --world views --output ./byte-views/bindings.go --byte-views ../target/wasm32-unknown-unknown/release/example_byte_views.wasm
+//go:generate cargo run --bin gravity -- --world reset --output ./reset/bindings.go ../target/wasm32-unknown-unknown/release/example_reset.wasm
+//go:generate cargo run --bin gravity -- --world tuples --output ./tuples/bindings.go ../target/wasm32-unknown-unknown/release/example_tuples.wasm
+//go:generate cargo run --bin gravity -- --world results --output ./results/bindings.go ../target/wasm32-unknown-unknown/release/example_results.wasm
+//go:generate cargo run --bin gravity -- --world recursion --output ./recursion/bindings.go ../target/wasm32-unknown-unknown/release/example_recursion.wasm
+//go:generate cargo run --bin gravity -- --world cleanup --output ./manual-cleanup/bindings.go --manual-cleanup ../target/wasm32-unknown-unknown/release/example_manual_cleanup.wasm
+//go:generate cargo run --bin gravity -- --world logs --output ./multi-import/bindings.go --with-examples ../target/wasm32-unknown-unknown/release/example_multi_import.wasm
+//go:generate cargo run --bin gravity -- --world params --output ./many-params/bindings.go ../target/wasm32-unknown-unknown/release/example_many_params.wasm
+//go:generate cargo run --bin gravity -- --world stats --output ./big-record/bindings.go ../target/wasm32-unknown-unknown/release/example_big_record.wasm
+//go:generate cargo run --bin gravity -- --world packing --output ./packing/bindings.go ../target/wasm32-unknown-unknown/release/example_packing.wasm
+//go:generate cargo run --bin gravity -- --world basic --output ./tinygo/bindings.go --tinygo ../target/wasm32-unknown-unknown/release/example_basic.wasm
+//go:generate cargo run --bin gravity -- --world equality --output ./equality/bindings.go --with-binary ../target/wasm32-unknown-unknown/release/example_equality.wasm
+//go:generate cargo run --bin gravity -- --world aligned --output ./aligned/bindings.go ../target/wasm32-unknown-unknown/release/example_aligned.wasm
+//go:generate cargo run --bin gravity -- --world options --output ./options/bindings.go --option-style pointer ../target/wasm32-unknown-unknown/release/example_options.wasm
+//go:generate cargo run --bin gravity -- --world limits --output ./memory-limit/bindings.go ../target/wasm32-unknown-unknown/release/example_memory_limit.wasm
+//go:generate cargo run --bin gravity -- --world flags --output ./flags/bindings.go ../target/wasm32-unknown-unknown/release/example_flags.wasm
+//go:generate cargo run --bin gravity -- --world start --output ./start/bindings.go ../target/wasm32-unknown-unknown/release/example_start.wasm
+//go:generate cargo run --bin gravity -- --world arrays --output ./arrays/bindings.go ../target/wasm32-unknown-unknown/release/example_arrays.wasm
+//go:generate cargo run --bin gravity -- --world lookups --output ./lookups/bindings.go --named-lists ../target/wasm32-unknown-unknown/release/example_lookups.wasm
+//go:generate cargo run --bin gravity -- --world greetings --output ./validation/bindings.go ../target/wasm32-unknown-unknown/release/example_validation.wasm
+//go:generate cargo run --bin gravity -- --world clock --output ./clock/bindings.go ../target/wasm32-wasip1/release/example_clock.wasm
+//go:generate cargo run --bin gravity -- --world basic --output ./compressed/bindings.go --compress-wasm ../target/wasm32-unknown-unknown/release/example_basic.wasm
@@ -0,0 +1,90 @@
+package integers
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type Numbers struct{}
+
+func (Numbers) AddU32(_ context.Context, a uint32, b uint32) uint32 { return a + b }
+func (Numbers) NegateS32(_ context.Context, val int32) int32        { return -val }
+func (Numbers) ScaleS64(_ context.Context, val int64, factor uint8) int64 {
+	return val * int64(factor)
+}
+
+// MismatchedNumbers uses signed integers where the WIT declares unsigned ones.
+type MismatchedNumbers struct{ Numbers }
+
+func (MismatchedNumbers) AddU32(_ context.Context, a int32, b int32) int32 { return a + b }
+
+func TestMismatchedImplementation(t *testing.T) {
+	iface := reflect.TypeFor[IIntegersNumbers]()
+
+	if !reflect.TypeFor[Numbers]().Implements(iface) {
+		t.Errorf("expected %s to implement %s", reflect.TypeFor[Numbers](), iface)
+	}
+	if reflect.TypeFor[MismatchedNumbers]().Implements(iface) {
+		t.Errorf("expected %s not to implement %s", reflect.TypeFor[MismatchedNumbers](), iface)
+	}
+}
+
+func TestSum(t *testing.T) {
+	fac, err := NewIntegersFactory(t.Context(), Numbers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	const expected = math.MaxUint32
+	if actual := ins.Sum(t.Context(), math.MaxUint32-1, 1); actual != expected {
+		t.Errorf("expected: %d, but got: %d", uint32(expected), actual)
+	}
+}
+
+func TestNegate(t *testing.T) {
+	fac, err := NewIntegersFactory(t.Context(), Numbers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	for _, expected := range []int32{math.MinInt32 + 1, -1, 0, 1, math.MaxInt32} {
+		if actual := ins.Negate(t.Context(), -expected); actual != expected {
+			t.Errorf("expected: %d, but got: %d", expected, actual)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	fac, err := NewIntegersFactory(t.Context(), Numbers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	const expected = -3 * math.MaxUint8
+	if actual := ins.Scale(t.Context(), -3, math.MaxUint8); actual != expected {
+		t.Errorf("expected: %d, but got: %d", int64(expected), actual)
+	}
+}
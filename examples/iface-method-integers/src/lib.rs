@@ -0,0 +1,21 @@
+use arcjet::integers::numbers;
+
+wit_bindgen::generate!({
+    world: "integers",
+});
+
+struct IntegersWorld;
+
+export!(IntegersWorld);
+
+impl Guest for IntegersWorld {
+    fn sum(a: u32, b: u32) -> u32 {
+        numbers::add_u32(a, b)
+    }
+    fn negate(val: i32) -> i32 {
+        numbers::negate_s32(val)
+    }
+    fn scale(val: i64, factor: u8) -> i64 {
+        numbers::scale_s64(val, factor)
+    }
+}
@@ -44,3 +44,55 @@ func TestBasic(t *testing.T) {
 		t.Errorf("wanted: %s, but got: %s", wantPutsMsg, r.msg)
 	}
 }
+
+// countingMemory counts the reads and writes of the bindings.
+type countingMemory struct {
+	Memory
+	reads, writes *int
+}
+
+func (m countingMemory) Read(offset, byteCount uint32) ([]byte, bool) {
+	*m.reads++
+	return m.Memory.Read(offset, byteCount)
+}
+
+func (m countingMemory) Write(offset uint32, v []byte) bool {
+	*m.writes++
+	return m.Memory.Write(offset, v)
+}
+
+func TestMemory(t *testing.T) {
+	var reads, writes int
+	fac, err := NewExampleFactory(t.Context(), &Runtime{}, WithMemory(func(memory Memory) Memory {
+		return countingMemory{Memory: memory, reads: &reads, writes: &writes}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	message, err := ins.Hello(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "Hello, world!"
+	if message != want {
+		t.Errorf("wanted: %s, but got: %s", want, message)
+	}
+
+	// os and arch each write a string, along with its pointer and length.
+	// puts reads its message, and hello reads the discriminant, pointer,
+	// length and bytes of its result.
+	if writes != 6 {
+		t.Errorf("expected 6 writes, but got: %d", writes)
+	}
+	if reads != 5 {
+		t.Errorf("expected 5 reads, but got: %d", reads)
+	}
+}
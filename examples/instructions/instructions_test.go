@@ -1,10 +1,13 @@
 package instructions
 
 import (
+	"bytes"
 	"fmt"
 	"iter"
+	"log/slog"
 	"math"
 	"math/rand/v2"
+	"strings"
 	"testing"
 )
 
@@ -222,3 +225,33 @@ func Test_F64Roundtrip(t *testing.T) {
 		})
 	}
 }
+
+func TestCallLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	fac, err := NewInstructionsFactory(t.Context(), WithCallLogging(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	if actual := ins.U32Roundtrip(t.Context(), 42); actual != 42 {
+		t.Fatalf("expected: 42, but got: %d", actual)
+	}
+	for _, want := range []string{
+		`msg="guest call"`,
+		"function=u32-roundtrip",
+		"args=2a00000000000000",
+		"results=[42]",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected log to contain %q, but got: %q", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package lists
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	fac, err := NewListsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	tests := []struct {
+		name     string
+		items    []string
+		expected uint32
+	}{
+		{"nil", nil, 0},
+		{"empty", []string{}, 0},
+		{"non-empty", []string{"a", "b"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := ins.Count(t.Context(), tt.items); actual != tt.expected {
+				t.Errorf("expected: %d, but got: %d", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestJoinNil(t *testing.T) {
+	fac, err := NewListsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	if actual := ins.Join(t.Context(), nil); actual != "" {
+		t.Errorf("expected an empty string, but got: %q", actual)
+	}
+	// The instance must still be usable after lowering a nil slice
+	const expected = "a,b,c"
+	if actual := ins.Join(t.Context(), []string{"a", "b", "c"}); actual != expected {
+		t.Errorf("expected: %q, but got: %q", expected, actual)
+	}
+}
+
+// emptyReadMemory fails reads of zero bytes, which lifting empty values must
+// not make.
+type emptyReadMemory struct {
+	Memory
+}
+
+func (m emptyReadMemory) Read(offset, byteCount uint32) ([]byte, bool) {
+	if byteCount == 0 {
+		return nil, false
+	}
+	return m.Memory.Read(offset, byteCount)
+}
+
+func TestLiftEmpty(t *testing.T) {
+	fac, err := NewListsFactory(t.Context(), WithMemory(func(memory Memory) Memory {
+		return emptyReadMemory{Memory: memory}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	if actual := ins.Join(t.Context(), []string{}); actual != "" {
+		t.Errorf("expected an empty string, but got: %q", actual)
+	}
+	if actual := ins.Split(t.Context(), ""); len(actual) != 0 {
+		t.Errorf("expected an empty list, but got: %q", actual)
+	}
+	// Empty strings are lifted from within a list too
+	expected := []string{"a", "", "b"}
+	if actual := ins.Split(t.Context(), "a,,b"); !slices.Equal(actual, expected) {
+		t.Errorf("expected: %q, but got: %q", expected, actual)
+	}
+}
+
+// writtenMemory counts the bytes written to guest memory.
+type writtenMemory struct {
+	Memory
+	written *uint64
+}
+
+func (m writtenMemory) Write(offset uint32, v []byte) bool {
+	*m.written += uint64(len(v))
+	return m.Memory.Write(offset, v)
+}
+
+func TestCountArgSize(t *testing.T) {
+	var written uint64
+	fac, err := NewListsFactory(t.Context(), WithMemory(func(memory Memory) Memory {
+		return writtenMemory{Memory: memory, written: &written}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	// Every allocated byte is written when lowering a list of strings, so the
+	// estimate matches the bytes written: 8 per element, plus the strings
+	items := []string{"gravity", "", "wasm"}
+	expected := CountArgSize(items)
+	if expected != 3*8+7+4 {
+		t.Errorf("expected an estimate of: %d, but got: %d", 3*8+7+4, expected)
+	}
+	ins.Count(t.Context(), items)
+	if written != expected {
+		t.Errorf("expected %d bytes to be allocated, but got: %d", expected, written)
+	}
+}
+
+// pbJoinRequest and pbJoinReply stand in for the messages protoc generates for
+// a gRPC service joining strings.
+type pbJoinRequest struct {
+	Parts []string
+}
+
+type pbJoinReply struct {
+	Joined string
+}
+
+// joinServer implements the gRPC service by adapting its messages to the
+// generated ListsService.
+type joinServer struct {
+	service *ListsService
+}
+
+func (s joinServer) Join(ctx context.Context, req *pbJoinRequest) (*pbJoinReply, error) {
+	resp, err := s.service.Join(ctx, &JoinRequest{Items: req.Parts})
+	if err != nil {
+		return nil, err
+	}
+	return &pbJoinReply{Joined: resp.Result}, nil
+}
+
+func TestService(t *testing.T) {
+	fac, err := NewListsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	server := joinServer{service: NewListsService(fac)}
+	reply, err := server.Join(t.Context(), &pbJoinRequest{Parts: []string{"a", "b", "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = "a,b,c"
+	if reply.Joined != expected {
+		t.Errorf("expected: %q, but got: %q", expected, reply.Joined)
+	}
+
+	resp, err := NewListsService(fac).Count(t.Context(), &CountRequest{Items: []string{"a", "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Result != 2 {
+		t.Errorf("expected: 2, but got: %d", resp.Result)
+	}
+}
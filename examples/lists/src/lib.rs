@@ -0,0 +1,22 @@
+wit_bindgen::generate!({
+    world: "lists",
+});
+
+struct ListsWorld;
+
+export!(ListsWorld);
+
+impl Guest for ListsWorld {
+    fn count(items: Vec<String>) -> u32 {
+        items.len() as u32
+    }
+    fn join(items: Vec<String>) -> String {
+        items.join(",")
+    }
+    fn split(joined: String) -> Vec<String> {
+        if joined.is_empty() {
+            return Vec::new();
+        }
+        joined.split(',').map(String::from).collect()
+    }
+}
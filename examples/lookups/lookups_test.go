@@ -0,0 +1,95 @@
+package lookups
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// Store is an in-memory store of entries.
+type Store map[string]Entry
+
+func (s Store) Lookup(_ context.Context, key string) (Entry, bool) {
+	entry, ok := s[key]
+	return entry, ok
+}
+
+func (s Store) Top(_ context.Context, count uint32) Entries {
+	entries := slices.SortedFunc(maps.Values(s), func(a, b Entry) int {
+		return cmp.Compare(b.Hits, a.Hits)
+	})
+	return entries[:min(int(count), len(entries))]
+}
+
+var _ ILookupsStore = Store(nil)
+
+func TestDescribe(t *testing.T) {
+	store := Store{"home": {Key: "home", Hits: 42}}
+	fac, err := NewLookupsFactory(t.Context(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	tests := []struct {
+		name     string
+		key      string
+		expected string
+	}{
+		{name: "some", key: "home", expected: "home: 42 hits"},
+		{name: "none", key: "about", expected: "about: missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := ins.Describe(t.Context(), tt.key)
+			if actual != tt.expected {
+				t.Errorf("expected: %q, but got: %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	store := Store{
+		"home":    {Key: "home", Hits: 42},
+		"about":   {Key: "about", Hits: 7},
+		"contact": {Key: "contact", Hits: 19},
+	}
+	fac, err := NewLookupsFactory(t.Context(), store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	tests := []struct {
+		name     string
+		count    uint32
+		expected string
+	}{
+		{name: "all", count: 3, expected: "home: 42, contact: 19, about: 7"},
+		{name: "some", count: 2, expected: "home: 42, contact: 19"},
+		{name: "none", count: 0, expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := ins.Leaderboard(t.Context(), tt.count)
+			if actual != tt.expected {
+				t.Errorf("expected: %q, but got: %q", tt.expected, actual)
+			}
+		})
+	}
+}
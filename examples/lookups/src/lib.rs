@@ -0,0 +1,24 @@
+wit_bindgen::generate!({
+    world: "lookups",
+});
+
+struct LookupsWorld;
+
+export!(LookupsWorld);
+
+impl Guest for LookupsWorld {
+    fn describe(key: String) -> String {
+        match store::lookup(&key) {
+            Some(entry) => format!("{}: {} hits", entry.key, entry.hits),
+            None => format!("{key}: missing"),
+        }
+    }
+
+    fn leaderboard(count: u32) -> String {
+        store::top(count)
+            .iter()
+            .map(|entry| format!("{}: {}", entry.key, entry.hits))
+            .collect::<Vec<_>>()
+            .join(", ")
+    }
+}
@@ -0,0 +1,45 @@
+package cleanup
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestManualCleanup(t *testing.T) {
+	fac, err := NewCleanupFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	greeting, cleanup := ins.Greet(t.Context(), "world")
+	// Views must be copied to outlive the cleanup
+	copied := strings.Clone(greeting)
+	if greeting != "Hello, world!" {
+		t.Errorf("expected: %q, but got: %q", "Hello, world!", greeting)
+	}
+	cleanup()
+	if copied != "Hello, world!" {
+		t.Errorf("expected: %q, but got: %q", "Hello, world!", copied)
+	}
+
+	// The guest memory is reused once the views are cleaned up
+	for range 1000 {
+		data, cleanup := ins.Fill(t.Context(), 256)
+		if len(data) != 256 {
+			t.Fatalf("expected 256 bytes, but got: %d", len(data))
+		}
+		for i, b := range data {
+			if b != uint8(i) {
+				t.Fatalf("expected byte %d to be %d, but got: %d", i, uint8(i), b)
+			}
+		}
+		cleanup()
+	}
+}
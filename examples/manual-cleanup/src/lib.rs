@@ -0,0 +1,17 @@
+wit_bindgen::generate!({
+    world: "cleanup",
+});
+
+struct CleanupWorld;
+
+export!(CleanupWorld);
+
+impl Guest for CleanupWorld {
+    fn greet(name: String) -> String {
+        format!("Hello, {name}!")
+    }
+
+    fn fill(len: u32) -> Vec<u8> {
+        (0..len).map(|i| i as u8).collect()
+    }
+}
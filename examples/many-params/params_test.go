@@ -0,0 +1,26 @@
+package params
+
+import "testing"
+
+func TestSum(t *testing.T) {
+	fac, err := NewParamsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	// Every parameter must reach the guest, none of them passed as zero
+	actual := ins.Sum(
+		t.Context(),
+		1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20,
+	)
+	if expected := float64(210); actual != expected {
+		t.Errorf("expected: %v, but got: %v", expected, actual)
+	}
+}
@@ -0,0 +1,40 @@
+wit_bindgen::generate!({
+    world: "params",
+});
+
+struct ParamsWorld;
+
+export!(ParamsWorld);
+
+impl Guest for ParamsWorld {
+    #[allow(clippy::too_many_arguments)]
+    fn sum(
+        x0: f64,
+        x1: f64,
+        x2: f64,
+        x3: f64,
+        x4: f64,
+        x5: f64,
+        x6: f64,
+        x7: f64,
+        x8: f64,
+        x9: f64,
+        x10: f64,
+        x11: f64,
+        x12: f64,
+        x13: f64,
+        x14: f64,
+        x15: f64,
+        x16: f64,
+        x17: f64,
+        x18: f64,
+        x19: f64,
+    ) -> f64 {
+        [
+            x0, x1, x2, x3, x4, x5, x6, x7, x8, x9, x10, x11, x12, x13, x14, x15, x16, x17, x18,
+            x19,
+        ]
+        .iter()
+        .sum()
+    }
+}
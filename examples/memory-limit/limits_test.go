@@ -0,0 +1,121 @@
+package limits
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDefaultLimit(t *testing.T) {
+	fac, err := NewLimitsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	// Staying under the default limit of 256 MiB works
+	if _, err := ins.Leak(t.Context(), 128); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ins.Leak(t.Context(), 256)
+	if !errors.Is(err, ErrMemoryLimitExceeded) {
+		t.Errorf("expected: %v, but got: %v", ErrMemoryLimitExceeded, err)
+	}
+}
+
+func TestRaisedLimit(t *testing.T) {
+	fac, err := NewLimitsFactory(t.Context(), WithMaxMemoryPages(2*DefaultMaxMemoryPages))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	leaked, err := ins.Leak(t.Context(), 384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaked != 384 {
+		t.Errorf("expected 384 MiB to be allocated, but got: %d", leaked)
+	}
+}
+
+func TestLoweredLimit(t *testing.T) {
+	fac, err := NewLimitsFactory(t.Context(), WithMaxMemoryPages(512))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	// 512 pages are 32 MiB
+	_, err = ins.Leak(t.Context(), 64)
+	if !errors.Is(err, ErrMemoryLimitExceeded) {
+		t.Errorf("expected: %v, but got: %v", ErrMemoryLimitExceeded, err)
+	}
+}
+
+func TestLimitBelowMinimum(t *testing.T) {
+	fac, err := NewLimitsFactory(t.Context(), WithMaxMemoryPages(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	// The guest needs more than a single page to start
+	_, err = fac.Instantiate(t.Context())
+	if !errors.Is(err, ErrMemoryLimitExceeded) {
+		t.Errorf("expected: %v, but got: %v", ErrMemoryLimitExceeded, err)
+	}
+}
+
+func TestMemorySize(t *testing.T) {
+	fac, err := NewLimitsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	before := ins.MemorySize()
+	if before == 0 || before%65536 != 0 {
+		t.Fatalf("expected a whole number of pages in bytes, but got: %d", before)
+	}
+
+	if _, err := ins.Leak(t.Context(), 16); err != nil {
+		t.Fatal(err)
+	}
+	after := ins.MemorySize()
+	if after < before+16<<20 {
+		t.Errorf("expected at least %d bytes after leaking 16 MiB, but got: %d", before+16<<20, after)
+	}
+
+	stats := ins.MemoryStats()
+	if stats.Bytes != after || uint64(stats.Pages)*65536 != after {
+		t.Errorf("expected %d bytes, but got: %+v", after, stats)
+	}
+	if stats.Limit != DefaultMaxMemoryPages*65536 {
+		t.Errorf("expected a limit of %d bytes, but got: %d", DefaultMaxMemoryPages*65536, stats.Limit)
+	}
+}
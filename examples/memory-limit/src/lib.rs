@@ -0,0 +1,22 @@
+use std::sync::atomic::{AtomicU32, Ordering};
+
+wit_bindgen::generate!({
+    world: "limits",
+});
+
+static LEAKED: AtomicU32 = AtomicU32::new(0);
+
+struct LimitsWorld;
+
+export!(LimitsWorld);
+
+impl Guest for LimitsWorld {
+    fn leak(mib: u32) -> Result<u32, String> {
+        for _ in 0..mib {
+            // Running out of memory aborts, rather than returning an error
+            vec![1u8; 1 << 20].leak();
+            LEAKED.fetch_add(1, Ordering::SeqCst);
+        }
+        Ok(LEAKED.load(Ordering::SeqCst))
+    }
+}
@@ -0,0 +1,51 @@
+package logs
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// Recorder records the messages logged to it.
+type Recorder struct {
+	messages []string
+}
+
+func (r *Recorder) Log(_ context.Context, msg string) {
+	r.messages = append(r.messages, msg)
+}
+
+var (
+	_ ILogsAppLog   = (*Recorder)(nil)
+	_ ILogsAuditLog = (*Recorder)(nil)
+)
+
+// newExampleFactory creates the factory used by the generated examples, with
+// host implementations which don't print anything.
+func newExampleFactory(ctx context.Context) (*LogsFactory, error) {
+	return NewLogsFactory(ctx, &Recorder{}, &Recorder{})
+}
+
+func TestMultipleImports(t *testing.T) {
+	app, audit := &Recorder{}, &Recorder{}
+	fac, err := NewLogsFactory(t.Context(), app, audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	ins.Run(t.Context(), "gravity")
+
+	if expected := []string{"running"}; !slices.Equal(app.messages, expected) {
+		t.Errorf("expected app log: %q, but got: %q", expected, app.messages)
+	}
+	if expected := []string{"run by gravity"}; !slices.Equal(audit.messages, expected) {
+		t.Errorf("expected audit log: %q, but got: %q", expected, audit.messages)
+	}
+}
@@ -0,0 +1,14 @@
+wit_bindgen::generate!({
+    world: "logs",
+});
+
+struct LogsWorld;
+
+export!(LogsWorld);
+
+impl Guest for LogsWorld {
+    fn run(user: String) {
+        app_log::log("running");
+        audit_log::log(&format!("run by {user}"));
+    }
+}
@@ -0,0 +1,94 @@
+package options
+
+import "testing"
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestEcho(t *testing.T) {
+	fac, err := NewOptionsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	tests := map[string]Profile{
+		"none": {Name: "anonymous"},
+		"some": {Name: "gravity", Age: ptr[uint32](3), Nickname: ptr("grav")},
+		// Zero values are still `Some`, unlike with `T, bool` pairs
+		"some zero": {Name: "", Age: ptr[uint32](0), Nickname: ptr("")},
+	}
+	for name, expected := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual := ins.Echo(t.Context(), expected)
+			if !actual.Equal(expected) {
+				t.Errorf("expected: %+v, but got: %+v", expected, actual)
+			}
+			if (actual.Age == nil) != (expected.Age == nil) {
+				t.Errorf("expected age to be nil: %t", expected.Age == nil)
+			}
+			if (actual.Nickname == nil) != (expected.Nickname == nil) {
+				t.Errorf("expected nickname to be nil: %t", expected.Nickname == nil)
+			}
+		})
+	}
+}
+
+func TestAgeOr(t *testing.T) {
+	fac, err := NewOptionsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	if actual := ins.AgeOr(t.Context(), Profile{}, nil); actual != nil {
+		t.Errorf("expected nil, but got: %d", *actual)
+	}
+	actual := ins.AgeOr(t.Context(), Profile{}, ptr[uint32](0))
+	if actual == nil || *actual != 0 {
+		t.Errorf("expected 0, but got: %v", actual)
+	}
+	actual = ins.AgeOr(t.Context(), Profile{Age: ptr[uint32](7)}, ptr[uint32](0))
+	if actual == nil || *actual != 7 {
+		t.Errorf("expected 7, but got: %v", actual)
+	}
+}
+
+func TestBump(t *testing.T) {
+	fac, err := NewOptionsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	if actual := ins.Bump(t.Context(), nil); actual != nil {
+		t.Errorf("expected nil, but got: %+v", *actual)
+	}
+	label := struct {
+		F0 uint32
+		F1 string
+	}{F0: 1, F1: "gravity"}
+	actual := ins.Bump(t.Context(), &label)
+	if actual == nil || actual.F0 != 2 || actual.F1 != "gravity" {
+		t.Errorf("expected {2 gravity}, but got: %+v", actual)
+	}
+}
@@ -0,0 +1,21 @@
+wit_bindgen::generate!({
+    world: "options",
+});
+
+struct OptionsWorld;
+
+export!(OptionsWorld);
+
+impl Guest for OptionsWorld {
+    fn echo(profile: Profile) -> Profile {
+        profile
+    }
+
+    fn age_or(profile: Profile, fallback: Option<u32>) -> Option<u32> {
+        profile.age.or(fallback)
+    }
+
+    fn bump(label: Option<(u32, String)>) -> Option<(u32, String)> {
+        label.map(|(count, name)| (count + 1, name))
+    }
+}
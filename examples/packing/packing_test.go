@@ -0,0 +1,43 @@
+package packing
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func newInstance(t *testing.T) *PackingInstance {
+	t.Helper()
+	fac, err := NewPackingFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fac.Close(t.Context()) })
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ins.Close(t.Context()) })
+	return ins
+}
+
+func TestReverse(t *testing.T) {
+	ins := newInstance(t)
+
+	// Values using both bytes check that elements are 2 bytes apart
+	values := []uint16{1, 0x1234, math.MaxUint16}
+	expected := []uint16{math.MaxUint16, 0x1234, 1}
+	if actual := ins.Reverse(t.Context(), values); !slices.Equal(actual, expected) {
+		t.Errorf("expected: %v, but got: %v", expected, actual)
+	}
+}
+
+func TestMix(t *testing.T) {
+	ins := newInstance(t)
+
+	expected := Mixed{Small: math.MaxUint8, Medium: 0x1234, Large: math.MaxUint32}
+	if actual := ins.Mix(t.Context(), math.MaxUint8, 0x1234, math.MaxUint32); actual != expected {
+		t.Errorf("expected: %+v, but got: %+v", expected, actual)
+	}
+}
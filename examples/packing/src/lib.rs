@@ -0,0 +1,22 @@
+wit_bindgen::generate!({
+    world: "packing",
+});
+
+struct PackingWorld;
+
+export!(PackingWorld);
+
+impl Guest for PackingWorld {
+    fn reverse(mut values: Vec<u16>) -> Vec<u16> {
+        values.reverse();
+        values
+    }
+
+    fn mix(small: u8, medium: u16, large: u32) -> Mixed {
+        Mixed {
+            small,
+            medium,
+            large,
+        }
+    }
+}
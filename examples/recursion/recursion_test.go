@@ -0,0 +1,33 @@
+package recursion
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStackOverflow(t *testing.T) {
+	fac, err := NewRecursionFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	depth, err := ins.Depth(t.Context(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if depth != 100 {
+		t.Errorf("expected: 100, but got: %d", depth)
+	}
+
+	_, err = ins.Depth(t.Context(), 1<<30)
+	if !errors.Is(err, ErrStackOverflow) {
+		t.Errorf("expected: %v, but got: %v", ErrStackOverflow, err)
+	}
+}
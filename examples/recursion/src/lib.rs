@@ -0,0 +1,25 @@
+use std::hint::black_box;
+
+wit_bindgen::generate!({
+    world: "recursion",
+});
+
+struct RecursionWorld;
+
+export!(RecursionWorld);
+
+/// Recurses `n` times. The recursive call goes through an opaque function
+/// pointer, so it can't be turned into a loop.
+fn count(n: u32) -> u32 {
+    if n == 0 {
+        return 0;
+    }
+    let next: fn(u32) -> u32 = black_box(count);
+    1 + next(n - 1)
+}
+
+impl Guest for RecursionWorld {
+    fn depth(n: u32) -> Result<u32, String> {
+        Ok(count(n))
+    }
+}
@@ -0,0 +1,83 @@
+package reset
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestReset(t *testing.T) {
+	fac, err := NewResetFactory(t.Context(), WithReset(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	for _, expected := range []uint32{1, 2} {
+		if actual := ins.Increment(t.Context()); actual != expected {
+			t.Errorf("expected: %d, but got: %d", expected, actual)
+		}
+	}
+
+	if err := ins.Reset(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = 1
+	if actual := ins.Increment(t.Context()); actual != expected {
+		t.Errorf("expected the counter to be cleared, but got: %d", actual)
+	}
+}
+
+func TestResetInitialize(t *testing.T) {
+	var calls int
+	fac, err := NewResetFactory(t.Context(), WithReset(true), WithPostInstantiate(func(context.Context, api.Module) error {
+		calls++
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	if err := ins.Reset(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = 2
+	if calls != expected {
+		t.Errorf("expected the instance to be initialized %d times, but got: %d", expected, calls)
+	}
+}
+
+func TestResetUnsupported(t *testing.T) {
+	fac, err := NewResetFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	if err := ins.Reset(t.Context()); !errors.Is(err, ErrResetUnsupported) {
+		t.Errorf("expected: %v, but got: %v", ErrResetUnsupported, err)
+	}
+}
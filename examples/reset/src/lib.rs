@@ -0,0 +1,18 @@
+use std::sync::atomic::{AtomicU32, Ordering};
+
+wit_bindgen::generate!({
+    world: "reset",
+});
+
+/// State kept in guest memory, which is cleared when the instance is reset.
+static COUNTER: AtomicU32 = AtomicU32::new(0);
+
+struct ResetWorld;
+
+export!(ResetWorld);
+
+impl Guest for ResetWorld {
+    fn increment() -> u32 {
+        COUNTER.fetch_add(1, Ordering::SeqCst) + 1
+    }
+}
@@ -0,0 +1,194 @@
+package results
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestFetchAlternating alternates between the ok and err cases, so freeing the
+// payload of the wrong case corrupts the guest's allocator. Run it with -race
+// to also check that the cleanup doesn't touch memory after the call returns.
+func TestFetchAlternating(t *testing.T) {
+	fac, err := NewResultsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	wantOk := bytes.Repeat([]byte("payload"), 64)
+	wantErr := strings.Repeat("not found", 64)
+	for i := range 10_000 {
+		ok := i%2 == 0
+		data, err := ins.Fetch(t.Context(), ok)
+		if ok {
+			if err != nil {
+				t.Fatalf("call %d: unexpected error: %v", i, err)
+			}
+			if !bytes.Equal(data, wantOk) {
+				t.Fatalf("call %d: expected: %q, but got: %q", i, wantOk, data)
+			}
+			continue
+		}
+		if err == nil || err.Error() != wantErr {
+			t.Fatalf("call %d: expected error: %q, but got: %v", i, wantErr, err)
+		}
+		if data != nil {
+			t.Fatalf("call %d: expected no data, but got: %q", i, data)
+		}
+	}
+}
+
+func TestFetchErrorWriter(t *testing.T) {
+	var buf bytes.Buffer
+	fac, err := NewResultsFactory(t.Context(), WithErrorWriter(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	if _, err := ins.Fetch(t.Context(), true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected nothing written, but got: %q", buf.String())
+	}
+
+	want := strings.Repeat("not found", 64)
+	_, err = ins.Fetch(t.Context(), false)
+	if err == nil || err.Error() != want {
+		t.Fatalf("expected error: %q, but got: %v", want, err)
+	}
+	if buf.String() != want+"\n" {
+		t.Errorf("expected written: %q, but got: %q", want+"\n", buf.String())
+	}
+}
+
+func TestHandle(t *testing.T) {
+	fac, err := NewResultsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	tests := []struct {
+		name     string
+		value    uint32
+		err      error
+		expected string
+	}{
+		{name: "ok", value: 42, expected: "ok: 42"},
+		{name: "err", err: errors.New("boom"), expected: "err: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := ins.Handle(t.Context(), tt.value, tt.err)
+			if actual != tt.expected {
+				t.Errorf("expected: %q, but got: %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCallConcurrent(t *testing.T) {
+	fac, err := NewResultsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	want := bytes.Repeat([]byte("payload"), 64)
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- fac.Call(t.Context(), func(ins *ResultsInstance) error {
+				data, err := ins.Fetch(t.Context(), true)
+				if err != nil {
+					return err
+				}
+				if !bytes.Equal(data, want) {
+					return fmt.Errorf("expected: %q, but got: %q", want, data)
+				}
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCallError(t *testing.T) {
+	fac, err := NewResultsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	err = fac.Call(t.Context(), func(ins *ResultsInstance) error {
+		_, err := ins.Fetch(t.Context(), false)
+		return err
+	})
+	if want := strings.Repeat("not found", 64); err == nil || err.Error() != want {
+		t.Fatalf("expected error: %q, but got: %v", want, err)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	fac, err := NewResultsFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	entry, err := ins.Split(t.Context(), "42:answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.F0 != 42 || entry.F1 != "answer" {
+		t.Errorf("expected: {42 answer}, but got: %v", entry)
+	}
+
+	const wantErr = "missing `:` in entry: answer"
+	entry, err = ins.Split(t.Context(), "answer")
+	if err == nil || err.Error() != wantErr {
+		t.Errorf("expected error: %q, but got: %v", wantErr, err)
+	}
+	if entry.F0 != 0 || entry.F1 != "" {
+		t.Errorf("expected a zero entry, but got: %v", entry)
+	}
+}
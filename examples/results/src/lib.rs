@@ -0,0 +1,32 @@
+wit_bindgen::generate!({
+    world: "results",
+});
+
+struct ResultsWorld;
+
+export!(ResultsWorld);
+
+impl Guest for ResultsWorld {
+    fn fetch(ok: bool) -> Result<Vec<u8>, String> {
+        if ok {
+            Ok(b"payload".repeat(64))
+        } else {
+            Err("not found".repeat(64))
+        }
+    }
+
+    fn handle(outcome: Result<u32, String>) -> String {
+        match outcome {
+            Ok(value) => format!("ok: {value}"),
+            Err(err) => format!("err: {err}"),
+        }
+    }
+
+    fn split(entry: String) -> Result<(u32, String), String> {
+        let (id, name) = entry
+            .split_once(':')
+            .ok_or_else(|| format!("missing `:` in entry: {entry}"))?;
+        let id = id.parse().map_err(|_| format!("invalid id: {id}"))?;
+        Ok((id, name.to_string()))
+    }
+}
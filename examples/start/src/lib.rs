@@ -0,0 +1,39 @@
+use std::sync::atomic::{AtomicU32, Ordering};
+
+wit_bindgen::generate!({
+    world: "start",
+});
+
+static STARTS: AtomicU32 = AtomicU32::new(0);
+
+/// Exported as a global holding its address in memory, for the host to set
+/// before calling any export.
+#[unsafe(no_mangle)]
+pub static SEED: AtomicU32 = AtomicU32::new(0);
+
+/// Exported like the entry point of a command, which the host is expected to
+/// call when instantiating the module.
+#[unsafe(no_mangle)]
+pub extern "C" fn _start() {
+    STARTS.fetch_add(1, Ordering::SeqCst);
+}
+
+/// An alternative entry point, which is only called when the host asks for it.
+#[unsafe(no_mangle)]
+pub extern "C" fn custom_start() {
+    STARTS.fetch_add(10, Ordering::SeqCst);
+}
+
+struct StartWorld;
+
+export!(StartWorld);
+
+impl Guest for StartWorld {
+    fn starts() -> u32 {
+        STARTS.load(Ordering::SeqCst)
+    }
+
+    fn seed() -> u32 {
+        SEED.load(Ordering::SeqCst)
+    }
+}
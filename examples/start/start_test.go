@@ -0,0 +1,100 @@
+package start
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+func TestStart(t *testing.T) {
+	tests := map[string]struct {
+		opts     []FactoryOption
+		expected uint32
+	}{
+		// The module exports `_start`, like a command
+		"detected": {expected: 1},
+		"custom":   {opts: []FactoryOption{WithStartFunction("custom_start")}, expected: 10},
+		// Customizing the module config keeps the detected start function
+		"module config": {
+			opts: []FactoryOption{WithModuleConfig(func(config wazero.ModuleConfig) wazero.ModuleConfig {
+				return config.WithEnv("GRAVITY", "1")
+			})},
+			expected: 1,
+		},
+		"env": {opts: []FactoryOption{WithEnv("GRAVITY", "1"), WithEnv("MODE", "test")}, expected: 1},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fac, err := NewStartFactory(t.Context(), test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer fac.Close(t.Context())
+
+			ins, err := fac.Instantiate(t.Context())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ins.Close(t.Context())
+
+			if actual := ins.Starts(t.Context()); actual != test.expected {
+				t.Errorf("expected the start functions to add up to: %d, but got: %d", test.expected, actual)
+			}
+		})
+	}
+}
+
+// setSeed writes seed to the guest's SEED static, whose address is exported
+// as a global.
+func setSeed(seed uint32) func(context.Context, api.Module) error {
+	return func(ctx context.Context, module api.Module) error {
+		global := module.ExportedGlobal("SEED")
+		if global == nil {
+			return errors.New("missing SEED global")
+		}
+		if !module.Memory().WriteUint32Le(uint32(global.Get()), seed) {
+			return errors.New("SEED is out of range")
+		}
+		return nil
+	}
+}
+
+func TestPostInstantiate(t *testing.T) {
+	fac, err := NewStartFactory(t.Context(), WithPostInstantiate(setSeed(42)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	if actual := ins.Seed(t.Context()); actual != 42 {
+		t.Errorf("expected the seed to be: 42, but got: %d", actual)
+	}
+	// The start functions ran before the hook
+	if actual := ins.Starts(t.Context()); actual != 1 {
+		t.Errorf("expected the start functions to add up to: 1, but got: %d", actual)
+	}
+}
+
+func TestPostInstantiateError(t *testing.T) {
+	expected := errors.New("failed to initialize")
+	fac, err := NewStartFactory(t.Context(), WithPostInstantiate(func(context.Context, api.Module) error {
+		return expected
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	if _, err := fac.Instantiate(t.Context()); !errors.Is(err, expected) {
+		t.Errorf("expected the hook's error, but got: %v", err)
+	}
+}
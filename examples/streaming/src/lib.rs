@@ -0,0 +1,47 @@
+use std::{
+    alloc::{GlobalAlloc, Layout, System},
+    sync::atomic::{AtomicUsize, Ordering},
+};
+
+wit_bindgen::generate!({
+    world: "streaming",
+});
+
+/// Keeps track of the bytes currently allocated by the guest, so the host can
+/// check that the memory of returned lists is cleaned up.
+struct CountingAllocator;
+
+static ALLOCATED: AtomicUsize = AtomicUsize::new(0);
+
+unsafe impl GlobalAlloc for CountingAllocator {
+    unsafe fn alloc(&self, layout: Layout) -> *mut u8 {
+        ALLOCATED.fetch_add(layout.size(), Ordering::SeqCst);
+        unsafe { System.alloc(layout) }
+    }
+
+    unsafe fn dealloc(&self, ptr: *mut u8, layout: Layout) {
+        ALLOCATED.fetch_sub(layout.size(), Ordering::SeqCst);
+        unsafe { System.dealloc(ptr, layout) }
+    }
+}
+
+#[global_allocator]
+static GLOBAL: CountingAllocator = CountingAllocator;
+
+struct StreamingWorld;
+
+export!(StreamingWorld);
+
+impl Guest for StreamingWorld {
+    fn logs(count: u32) -> Vec<LogEntry> {
+        (0..count)
+            .map(|level| LogEntry {
+                level,
+                message: format!("entry {level}"),
+            })
+            .collect()
+    }
+    fn allocated_bytes() -> u32 {
+        ALLOCATED.load(Ordering::SeqCst) as u32
+    }
+}
@@ -0,0 +1,105 @@
+package streaming
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLogs(t *testing.T) {
+	fac, err := NewStreamingFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	entries := ins.Logs(t.Context(), 3)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, but got: %d", len(entries))
+	}
+	for i, actual := range entries {
+		expected := LogEntry{Level: uint32(i), Message: fmt.Sprintf("entry %d", i)}
+		if actual != expected {
+			t.Errorf("expected: %v, but got: %v", expected, actual)
+		}
+	}
+}
+
+func TestLogsSeq(t *testing.T) {
+	fac, err := NewStreamingFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	const count = 10_000
+	var i uint32
+	for actual, err := range ins.LogsSeq(t.Context(), count) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := LogEntry{Level: i, Message: fmt.Sprintf("entry %d", i)}
+		if actual != expected {
+			t.Fatalf("expected: %v, but got: %v", expected, actual)
+		}
+		i++
+	}
+	if i != count {
+		t.Errorf("expected %d entries, but got: %d", count, i)
+	}
+}
+
+func TestLogsSeqLazy(t *testing.T) {
+	fac, err := NewStreamingFactory(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	before := ins.AllocatedBytes(t.Context())
+
+	seq := ins.LogsSeq(t.Context(), 100)
+	if actual := ins.AllocatedBytes(t.Context()); actual != before {
+		t.Errorf("expected the guest not to be called before ranging, but %d bytes are allocated", actual-before)
+	}
+
+	var seen int
+	for _, err := range seq {
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The list is read directly from guest memory while ranging
+		if actual := ins.AllocatedBytes(t.Context()); actual <= before {
+			t.Errorf("expected the list to be allocated in the guest while ranging")
+		}
+		seen++
+		if seen == 3 {
+			break
+		}
+	}
+	if seen != 3 {
+		t.Errorf("expected to stop after 3 entries, but got: %d", seen)
+	}
+
+	// Breaking early must still clean up the list in the guest
+	if actual := ins.AllocatedBytes(t.Context()); actual != before {
+		t.Errorf("expected guest memory to be cleaned up, but %d bytes are still allocated", actual-before)
+	}
+}
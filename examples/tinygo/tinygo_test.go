@@ -0,0 +1,49 @@
+package basic
+
+import (
+	"context"
+	"testing"
+)
+
+// Logger records the messages the guest logs.
+type Logger struct {
+	messages []string
+}
+
+func (l *Logger) Debug(ctx context.Context, msg string) { l.messages = append(l.messages, msg) }
+func (l *Logger) Info(ctx context.Context, msg string)  { l.messages = append(l.messages, msg) }
+func (l *Logger) Warn(ctx context.Context, msg string)  { l.messages = append(l.messages, msg) }
+func (l *Logger) Error(ctx context.Context, msg string) { l.messages = append(l.messages, msg) }
+
+// TestTinyGo runs the basic example with bindings generated with --tinygo,
+// which register host functions without reflection. It only runs with
+// `go test`, building the bindings with TinyGo isn't verified.
+func TestTinyGo(t *testing.T) {
+	// TinyGo's testing package doesn't have t.Context
+	ctx := context.Background()
+	logger := &Logger{}
+	fac, err := NewBasicFactory(ctx, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(ctx)
+
+	ins, err := fac.Instantiate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(ctx)
+
+	message, err := ins.Hello(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "Hello, world!"
+	if message != want {
+		t.Errorf("wanted: %s, but got: %s", want, message)
+	}
+	if len(logger.messages) == 0 {
+		t.Error("expected the guest to call the host logger")
+	}
+}
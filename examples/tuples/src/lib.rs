@@ -0,0 +1,28 @@
+use arcjet::tuples::pairs;
+
+wit_bindgen::generate!({
+    world: "tuples",
+});
+
+struct TuplesWorld;
+
+export!(TuplesWorld);
+
+impl Guest for TuplesWorld {
+    fn describe() -> String {
+        let (id, name) = pairs::pair();
+        format!("{id}: {name}")
+    }
+
+    fn numbered(n: u32) -> Vec<(u32, String)> {
+        (0..n).map(|i| (i, format!("item-{i}"))).collect()
+    }
+
+    fn report(user: String) {
+        pairs::log(&[
+            ("user".to_string(), user),
+            ("action".to_string(), "report".to_string()),
+            ("status".to_string(), "ok".to_string()),
+        ]);
+    }
+}
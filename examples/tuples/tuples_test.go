@@ -0,0 +1,104 @@
+package tuples
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// Pairs returns a tuple of both values to the guest, and records the fields
+// logged by it.
+type Pairs struct {
+	fields []struct {
+		F0 string
+		F1 string
+	}
+}
+
+func (*Pairs) Pair(_ context.Context) (uint32, string) {
+	return 42, "answer"
+}
+
+func (p *Pairs) Log(_ context.Context, fields []struct {
+	F0 string
+	F1 string
+}) {
+	p.fields = append(p.fields, fields...)
+}
+
+func TestDescribe(t *testing.T) {
+	fac, err := NewTuplesFactory(t.Context(), &Pairs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	expected := "42: answer"
+	if actual := ins.Describe(t.Context()); actual != expected {
+		t.Errorf("expected: %q, but got: %q", expected, actual)
+	}
+}
+
+func TestNumbered(t *testing.T) {
+	fac, err := NewTuplesFactory(t.Context(), &Pairs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	// Each tuple is read at its offset within the list's stride
+	actual := ins.Numbered(t.Context(), 100)
+	if len(actual) != 100 {
+		t.Fatalf("expected 100 tuples, but got: %d", len(actual))
+	}
+	for i, tuple := range actual {
+		if tuple.F0 != uint32(i) {
+			t.Errorf("expected tuple %d to have: %d, but got: %d", i, i, tuple.F0)
+		}
+		if expected := fmt.Sprintf("item-%d", i); tuple.F1 != expected {
+			t.Errorf("expected tuple %d to have: %q, but got: %q", i, expected, tuple.F1)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	pairs := &Pairs{}
+	fac, err := NewTuplesFactory(t.Context(), pairs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	// The guest passes every field pair to the host at once
+	ins.Report(t.Context(), "gravity")
+	expected := []struct {
+		F0 string
+		F1 string
+	}{
+		{"user", "gravity"},
+		{"action", "report"},
+		{"status", "ok"},
+	}
+	if !slices.Equal(pairs.fields, expected) {
+		t.Errorf("expected fields: %q, but got: %q", expected, pairs.fields)
+	}
+}
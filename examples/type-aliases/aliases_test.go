@@ -0,0 +1,66 @@
+package aliases
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type Users struct{}
+
+func (Users) NextID(_ context.Context, id UserID) UserID { return id + 1 }
+func (Users) NameOf(_ context.Context, id UserID) Name {
+	return Name(fmt.Sprintf("user %d", id))
+}
+
+func TestDefinedTypes(t *testing.T) {
+	if reflect.TypeFor[UserID]() == reflect.TypeFor[uint64]() {
+		t.Errorf("expected UserID to be distinct from uint64")
+	}
+	if kind := reflect.TypeFor[UserID]().Kind(); kind != reflect.Uint64 {
+		t.Errorf("expected UserID to be a uint64, but got: %s", kind)
+	}
+	if reflect.TypeFor[Name]() == reflect.TypeFor[string]() {
+		t.Errorf("expected Name to be distinct from string")
+	}
+}
+
+func TestNext(t *testing.T) {
+	fac, err := NewAliasesFactory(t.Context(), Users{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	const expected UserID = math.MaxUint64
+	if actual := ins.Next(t.Context(), math.MaxUint64-1); actual != expected {
+		t.Errorf("expected: %d, but got: %d", expected, actual)
+	}
+}
+
+func TestGreet(t *testing.T) {
+	fac, err := NewAliasesFactory(t.Context(), Users{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	const expected Name = "Hello, user 42!"
+	if actual := ins.Greet(t.Context(), 42); actual != expected {
+		t.Errorf("expected: %q, but got: %q", expected, actual)
+	}
+}
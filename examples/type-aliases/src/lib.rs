@@ -0,0 +1,18 @@
+use arcjet::aliases::users;
+
+wit_bindgen::generate!({
+    world: "aliases",
+});
+
+struct AliasesWorld;
+
+export!(AliasesWorld);
+
+impl Guest for AliasesWorld {
+    fn next(id: UserId) -> UserId {
+        users::next_id(id)
+    }
+    fn greet(id: UserId) -> Name {
+        format!("Hello, {}!", users::name_of(id))
+    }
+}
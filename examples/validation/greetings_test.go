@@ -0,0 +1,57 @@
+package greetings
+
+import "testing"
+
+func TestGreet(t *testing.T) {
+	fac := NewGreetingsFactoryMust(t.Context())
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	actual, err := ins.Greet(t.Context(), "gravity")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "Hello, gravity!"; actual != expected {
+		t.Errorf("expected: %q, but got: %q", expected, actual)
+	}
+}
+
+func TestGreetEmpty(t *testing.T) {
+	fac := NewGreetingsFactoryMust(t.Context())
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	_, err = ins.Greet(t.Context(), "")
+	if expected := "Greet: name must not be empty"; err == nil || err.Error() != expected {
+		t.Fatalf("expected error: %q, but got: %v", expected, err)
+	}
+}
+
+func TestShoutEmpty(t *testing.T) {
+	fac := NewGreetingsFactoryMust(t.Context())
+	defer fac.Close(t.Context())
+
+	ins, err := fac.Instantiate(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ins.Close(t.Context())
+
+	defer func() {
+		err, ok := recover().(error)
+		if expected := "Shout: name must not be empty"; !ok || err.Error() != expected {
+			t.Errorf("expected panic: %q, but got: %v", expected, err)
+		}
+	}()
+	ins.Shout(t.Context(), "")
+}
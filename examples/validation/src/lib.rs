@@ -0,0 +1,17 @@
+wit_bindgen::generate!({
+    world: "greetings",
+});
+
+struct GreetingsWorld;
+
+export!(GreetingsWorld);
+
+impl Guest for GreetingsWorld {
+    fn greet(name: String) -> Result<String, String> {
+        Ok(format!("Hello, {name}!"))
+    }
+
+    fn shout(name: String) -> String {
+        format!("HELLO, {}!", name.to_uppercase())
+    }
+}